@@ -48,6 +48,7 @@ func (suite *MainTestSuite) TestAdminAndUsers() {
 				GUID: userGUID,
 			},
 		}
+		suite.loginAsAdmin("admin-guid")
 		testPostDoc(suite, consts.TenantPath, customer, customerCompareFilter)
 	}
 
@@ -111,7 +112,7 @@ func (suite *MainTestSuite) TestAdminAndUsers() {
 
 	//make sure regular user can't use admin api
 	suite.login(user1)
-	testBadRequest(suite, http.MethodDelete, deleteUsersUrls, errorNotAdminUser, nil, http.StatusUnauthorized)
+	testBadRequest(suite, http.MethodDelete, deleteUsersUrls, errorNotAdminUser, nil, http.StatusForbidden)
 
 	//populate user2 again
 	suite.login(user2)
@@ -177,6 +178,7 @@ func (suite *MainTestSuite) TestAdminActiveUsers() {
 	suite.NoError(err, "can't delete clusters collection")
 
 	for i, user := range users {
+		suite.loginAsAdmin("admin-guid")
 		testPostDoc(suite, consts.TenantPath, user, customerCompareFilter)
 		suite.login(user.GUID)
 		for _, cluster := range clusters[i] {
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"config-service/db"
+	"config-service/types"
+	"config-service/utils/auth"
+	"config-service/utils/consts"
+	"net/http"
+	"time"
+
+	"github.com/armosec/armoapi-go/armotypes"
+)
+
+func (suite *MainTestSuite) TestAudit() {
+	const customerGUID = "audit-customer"
+	from := time.Now().UTC().Add(-time.Minute).Format(time.RFC3339)
+	suite.login(customerGUID)
+	cluster := &types.Cluster{PortalBase: armotypes.PortalBase{Name: "audit-cluster", Attributes: map[string]interface{}{"env": "dev"}}}
+	cluster = testPostDoc(suite, consts.ClusterPath, cluster, newClusterCompareFilter)
+	updated := testGetDoc(suite, consts.ClusterPath+"/"+cluster.GUID, cluster, newClusterCompareFilter)
+	updated.Attributes["env"] = "prod"
+	w := suite.doRequest(http.MethodPut, consts.ClusterPath, updated)
+	suite.Equal(http.StatusOK, w.Code, w.Body.String())
+	testDeleteDocByGUID(suite, consts.ClusterPath, updated, newClusterCompareFilter)
+	//failed requests are not recorded
+	testBadRequest(suite, http.MethodDelete, consts.ClusterPath+"/"+cluster.GUID, errorDocumentNotFound, nil, http.StatusNotFound)
+
+	getAudit := func(query string) *db.AggResult[db.AuditRecord] {
+		w := suite.doRequest(http.MethodGet, consts.AuditPath+"?"+query, nil)
+		suite.Equal(http.StatusOK, w.Code, w.Body.String())
+		page, err := decodeResponse[*db.AggResult[db.AuditRecord]](w)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+		return page
+	}
+	page := getAudit(consts.CollectionParam + "=" + consts.ClustersCollection + "&" + consts.FromDateParam + "=" + from)
+	suite.Equal(3, page.Metadata.Total)
+	suite.Len(page.Results, 3)
+	//latest first
+	for i, method := range []string{http.MethodDelete, http.MethodPut, http.MethodPost} {
+		record := page.Results[i]
+		suite.Equal(method, record.Method)
+		suite.Equal(customerGUID, record.CustomerGUID)
+		suite.Equal(customerGUID, record.ActorGUID)
+		suite.False(record.Admin)
+		suite.Equal([]string{cluster.GUID}, record.TargetGUIDs)
+		suite.Len(record.Changes, 1)
+	}
+	suite.Equal([]db.FieldDiff{{Field: "attributes.env", Before: "dev", After: "prod"}}, page.Results[1].Changes[0].Diff)
+
+	//filters
+	suite.Equal(3, getAudit(consts.TargetGUIDParam+"="+cluster.GUID).Metadata.Total)
+	suite.Equal(0, getAudit(consts.ActorParam+"=other-customer&"+consts.FromDateParam+"="+from).Metadata.Total)
+	suite.Equal(0, getAudit(consts.CollectionParam+"="+consts.ClustersCollection+"&"+consts.ToDateParam+"="+from).Metadata.Total)
+	page = getAudit(consts.CollectionParam + "=" + consts.ClustersCollection + "&" + consts.LimitParam + "=2")
+	suite.Len(page.Results, 2)
+	suite.Equal(2, page.Metadata.NextSkip)
+	testBadRequest(suite, http.MethodGet, consts.AuditPath+"?"+consts.FromDateParam+"=yesterday", `{"error":"fromDate must be in RFC3339 format"}`, nil, http.StatusBadRequest)
+
+	//customers cannot read other customers records
+	testBadRequest(suite, http.MethodGet, consts.AuditPath+"?"+consts.CustomersParam+"="+customerGUID, `{"error":"Forbidden - only admins can query the audit log of other customers"}`, nil, http.StatusForbidden)
+	suite.login("audit-other-customer")
+	suite.Equal(0, getAudit(consts.TargetGUIDParam+"="+cluster.GUID).Metadata.Total)
+
+	//admins query across customers
+	suite.loginAsAdmin("audit-admin")
+	suite.Equal(3, getAudit(consts.TargetGUIDParam+"="+cluster.GUID).Metadata.Total)
+	suite.Equal(3, getAudit(consts.TargetGUIDParam+"="+cluster.GUID+"&"+consts.CustomersParam+"="+customerGUID).Metadata.Total)
+	suite.Equal(0, getAudit(consts.TargetGUIDParam+"="+cluster.GUID+"&"+consts.CustomersParam+"=audit-other-customer").Metadata.Total)
+
+	//api keys hashes are not recorded
+	suite.login(customerGUID)
+	w = suite.doRequest(http.MethodPost, consts.APIKeyPath, &types.APIKey{PortalBase: armotypes.PortalBase{Name: "audit-key"}, Scopes: []types.APIKeyScope{{Path: consts.ClusterPath, Access: auth.ScopeAccessRead}}})
+	suite.Equal(http.StatusCreated, w.Code, w.Body.String())
+	key, err := decodeResponse[*types.APIKey](w)
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	page = getAudit(consts.TargetGUIDParam + "=" + key.GUID)
+	suite.Equal(1, page.Metadata.Total)
+	suite.Len(page.Results[0].Changes, 1)
+	suite.Contains(page.Results[0].Changes[0].Diff, db.FieldDiff{Field: "keyPrefix", After: key.KeyPrefix})
+	w = suite.doRequest(http.MethodGet, consts.AuditPath+"?"+consts.TargetGUIDParam+"="+key.GUID, nil)
+	suite.Equal(http.StatusOK, w.Code, w.Body.String())
+	suite.NotContains(w.Body.String(), consts.KeyHashField)
+}
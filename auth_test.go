@@ -0,0 +1,331 @@
+package main
+
+import (
+	"config-service/db/mongo"
+	"config-service/types"
+	"config-service/utils"
+	"config-service/utils/auth"
+	"config-service/utils/consts"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/armosec/armoapi-go/armotypes"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/go-cmp/cmp"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func (suite *MainTestSuite) TestAuthentication() {
+	//logged in user can access protected routes
+	w := suite.doRequest(http.MethodGet, consts.ClusterPath, nil)
+	suite.Equal(http.StatusOK, w.Code)
+
+	//raw customer guid cookie is rejected
+	suite.authCookie = consts.CustomerGUID + "=" + defaultUserGUID
+	testBadRequest(suite, http.MethodGet, consts.ClusterPath, errorUnauthorized(auth.ErrInvalidToken), nil, http.StatusUnauthorized)
+	//forged admin cookie is rejected
+	suite.authCookie = consts.CustomerGUID + "=" + defaultUserGUID + ";" + consts.AdminAccess
+	testBadRequest(suite, http.MethodGet, consts.AdminPath+"/activeCustomers", errorUnauthorized(auth.ErrInvalidToken), nil, http.StatusUnauthorized)
+	//token signed with another key is rejected
+	token, _ := auth.SignSessionToken(auth.NewSessionClaims(defaultUserGUID, true, time.Hour), []byte("some-other-key"))
+	suite.authCookie = consts.CustomerGUID + "=" + token
+	testBadRequest(suite, http.MethodGet, consts.ClusterPath, errorUnauthorized(auth.ErrInvalidToken), nil, http.StatusUnauthorized)
+	//expired token is rejected
+	token, _ = auth.SignSessionToken(auth.NewSessionClaims(defaultUserGUID, false, -time.Minute), auth.GetSigningKey())
+	suite.authCookie = consts.CustomerGUID + "=" + token
+	testBadRequest(suite, http.MethodGet, consts.ClusterPath, errorUnauthorized(auth.ErrExpiredToken), nil, http.StatusUnauthorized)
+	//no cookie is rejected
+	suite.authCookie = ""
+	testBadRequest(suite, http.MethodGet, consts.ClusterPath, `{"error":"Unauthorized"}`, nil, http.StatusUnauthorized)
+	//configured admin users get admin access
+	suite.login("admin-user-guid")
+	w = suite.doRequest(http.MethodGet, consts.AdminPath+"/activeCustomers", nil)
+	suite.Equal(http.StatusBadRequest, w.Code, "admin route should be reached and fail on missing query param")
+
+	//restore login
+	suite.login(defaultUserGUID)
+}
+
+func (suite *MainTestSuite) TestLogin() {
+	suite.authCookie = ""
+	//a customer guid is not a credential
+	loginDetails := map[string]interface{}{"customerGUID": defaultUserGUID, "adminAccess": true}
+	testBadRequest(suite, http.MethodPost, "/login", `{"error":"Unauthorized"}`, loginDetails, http.StatusUnauthorized)
+	suite.requestHeaders = map[string]string{"Authorization": "Bearer some-token"}
+	testBadRequest(suite, http.MethodPost, "/login", errorUnauthorized(fmt.Errorf("%w: bearer authentication is not enabled", auth.ErrInvalidToken)), loginDetails, http.StatusUnauthorized)
+
+	//enable bearer authentication with a local key set
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	keySet, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": "login-kid",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+	keySetFile := filepath.Join(suite.T().TempDir(), "jwks.json")
+	if err := os.WriteFile(keySetFile, keySet, 0600); err != nil {
+		suite.FailNow(err.Error())
+	}
+	if jwtValidator, err = auth.NewJWTValidator(utils.JWTConfig{JWKSFile: keySetFile}); err != nil {
+		suite.FailNow(err.Error())
+	}
+	defer func() { jwtValidator = nil }()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"customerGUID": "login-customer-guid", "exp": time.Now().Add(time.Hour).Unix()})
+	token.Header["kid"] = "login-kid"
+	signed, err := token.SignedString(key)
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+
+	//the session is created for the verified token claims, admin access and customer sent in the body are ignored
+	suite.requestHeaders = map[string]string{"Authorization": "Bearer " + signed}
+	w := suite.doRequest(http.MethodPost, "/login", loginDetails)
+	suite.Equal(http.StatusOK, w.Code, w.Body.String())
+	suite.requestHeaders = nil
+	cookie := w.Header().Get("Set-Cookie")
+	suite.Contains(cookie, "Secure")
+	suite.Contains(cookie, "HttpOnly")
+	suite.authCookie = cookie
+	testBadRequest(suite, http.MethodGet, consts.AdminPath+"/activeCustomers", `{"error":"Forbidden - GET is not permitted on admin routes"}`, nil, http.StatusForbidden)
+	cluster := testPostDoc(suite, consts.ClusterPath, &types.Cluster{PortalBase: armotypes.PortalBase{Name: "login-cluster"}}, newClusterCompareFilter)
+	//the document is owned by the token customer
+	suite.login("login-customer-guid")
+	testGetDoc(suite, consts.ClusterPath+"/"+cluster.GUID, cluster, newClusterCompareFilter)
+	testDeleteDocByGUID(suite, consts.ClusterPath, cluster, newClusterCompareFilter)
+
+	//restore login
+	suite.login(defaultUserGUID)
+}
+
+func errorUnauthorized(err error) string {
+	return `{"error":"Unauthorized - ` + err.Error() + `"}`
+}
+
+func (suite *MainTestSuite) TestSessions() {
+	//logout revokes the current session
+	suite.login(defaultUserGUID)
+	sessionCookie := suite.authCookie
+	w := suite.doRequest(http.MethodPost, "/logout", nil)
+	suite.Equal(http.StatusOK, w.Code)
+	suite.authCookie = sessionCookie
+	testBadRequest(suite, http.MethodGet, consts.ClusterPath, errorUnauthorized(auth.ErrRevokedSession), nil, http.StatusUnauthorized)
+	testBadRequest(suite, http.MethodPost, "/logout", errorUnauthorized(auth.ErrRevokedSession), nil, http.StatusUnauthorized)
+
+	//token without a session is rejected
+	token, _ := auth.SignSessionToken(auth.NewSessionClaims(defaultUserGUID, false, time.Hour), auth.GetSigningKey())
+	suite.authCookie = consts.CustomerGUID + "=" + token
+	testBadRequest(suite, http.MethodGet, consts.ClusterPath, errorUnauthorized(auth.ErrInvalidToken), nil, http.StatusUnauthorized)
+
+	//admin revokes all the sessions of a customer
+	suite.login(defaultUserGUID)
+	firstSession := suite.authCookie
+	suite.login(defaultUserGUID)
+	secondSession := suite.authCookie
+	w = suite.doRequest(http.MethodGet, consts.ClusterPath, nil)
+	suite.Equal(http.StatusOK, w.Code)
+	suite.loginAsAdmin("admin-guid")
+	adminSession := suite.authCookie
+	testBadRequest(suite, http.MethodDelete, consts.AdminPath+"/sessions", `{"error":"customers query param is required"}`, nil, http.StatusBadRequest)
+	w = suite.doRequest(http.MethodDelete, fmt.Sprintf("%s/sessions?%s=%s", consts.AdminPath, consts.CustomersParam, defaultUserGUID), nil)
+	suite.Equal(http.StatusOK, w.Code)
+	for _, cookie := range []string{firstSession, secondSession} {
+		suite.authCookie = cookie
+		testBadRequest(suite, http.MethodGet, consts.ClusterPath, errorUnauthorized(auth.ErrRevokedSession), nil, http.StatusUnauthorized)
+	}
+	//other customers sessions are not revoked
+	suite.authCookie = adminSession
+	w = suite.doRequest(http.MethodGet, consts.ClusterPath, nil)
+	suite.Equal(http.StatusOK, w.Code)
+
+	//restore login
+	suite.login(defaultUserGUID)
+}
+
+func (suite *MainTestSuite) TestTenantProvisioning() {
+	tenant := &types.Customer{PortalBase: armotypes.PortalBase{Name: "provisioned", GUID: "provisioned-customer-guid"}}
+	//anonymous and non admin users cannot create tenants
+	suite.authCookie = ""
+	testBadRequest(suite, http.MethodPost, consts.TenantPath, `{"error":"Unauthorized"}`, tenant, http.StatusUnauthorized)
+	suite.login(defaultUserGUID)
+	testBadRequest(suite, http.MethodPost, consts.TenantPath, `{"error":"Forbidden - tenant creation requires a provisioning token or admin credentials"}`, tenant, http.StatusForbidden)
+	//only admins can issue provisioning tokens
+	w := suite.doRequest(http.MethodPost, consts.AdminPath+"/provisioningTokens", map[string]interface{}{})
+	suite.Equal(http.StatusForbidden, w.Code)
+	suite.loginAsAdmin("admin-guid")
+	w = suite.doRequest(http.MethodPost, consts.AdminPath+"/provisioningTokens", map[string]interface{}{
+		"customerGUID": tenant.GUID,
+		"attributes":   map[string]interface{}{"plan": "trial"},
+	})
+	suite.Equal(http.StatusCreated, w.Code)
+	token, err := decodeResponse[*auth.ProvisioningToken](w)
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	suite.NotEmpty(token.Token)
+	testBadRequest(suite, http.MethodPost, consts.AdminPath+"/provisioningTokens", `{"error":"expirationTime must be in the future"}`,
+		map[string]interface{}{"expirationTime": time.Now().Add(-time.Hour).Format(time.RFC3339)}, http.StatusBadRequest)
+
+	//create the tenant with the provisioning token
+	suite.authCookie = ""
+	suite.requestHeaders = map[string]string{consts.ProvisioningTokenHeader: token.Token}
+	//the token is issued for another customer
+	otherTenant := &types.Customer{PortalBase: armotypes.PortalBase{Name: "other", GUID: "other-customer-guid"}}
+	errorTokenNotUsable := `{"error":"Unauthorized - provisioning token is used, expired or issued for another customer"}`
+	testBadRequest(suite, http.MethodPost, consts.TenantPath, errorTokenNotUsable, otherTenant, http.StatusUnauthorized)
+	newTenant := testPostDoc(suite, consts.TenantPath, tenant, customerCompareFilter, cmp.FilterPath(func(p cmp.Path) bool {
+		return p.String() == "PortalBase.Attributes"
+	}, cmp.Ignore()))
+	suite.Equal("trial", newTenant.Attributes["plan"], "preset attributes should be set")
+	//the token is single use
+	tenant.GUID = "another-provisioned-customer-guid"
+	testBadRequest(suite, http.MethodPost, consts.TenantPath, errorTokenNotUsable, tenant, http.StatusUnauthorized)
+	//forged token is rejected
+	suite.requestHeaders = map[string]string{consts.ProvisioningTokenHeader: token.Token + "x"}
+	testBadRequest(suite, http.MethodPost, consts.TenantPath, errorUnauthorized(auth.ErrInvalidToken), tenant, http.StatusUnauthorized)
+	suite.requestHeaders = nil
+
+	//tenant creation is audited
+	audit := struct {
+		ProvisioningTokenID string `bson:"provisioningTokenID"`
+	}{}
+	err = mongo.GetReadCollection(consts.TenantsAuditCollection).FindOne(context.Background(), bson.M{consts.CustomerGUIDField: newTenant.GUID}).Decode(&audit)
+	suite.NoError(err)
+	suite.Equal(token.ID, audit.ProvisioningTokenID)
+
+	//restore login
+	suite.login(defaultUserGUID)
+}
+
+func (suite *MainTestSuite) TestImpersonation() {
+	//create a cluster for the default user
+	suite.login(defaultUserGUID)
+	cluster := testPostDoc(suite, consts.ClusterPath, &types.Cluster{PortalBase: armotypes.PortalBase{Name: "impersonated-cluster"}}, newClusterCompareFilter)
+
+	//non admin users cannot act as another customer
+	suite.login("other-customer-guid")
+	suite.requestHeaders = map[string]string{consts.ActAsCustomerHeader: defaultUserGUID}
+	testBadRequest(suite, http.MethodGet, consts.ClusterPath, `{"error":"Forbidden - only admins can act as another customer"}`, nil, http.StatusForbidden)
+
+	//admin acts as the default user
+	suite.loginAsAdmin("admin-guid")
+	testGetDoc(suite, fmt.Sprintf("%s/%s", consts.ClusterPath, cluster.GUID), cluster, newClusterCompareFilter)
+	//without the header the admin sees only its own documents
+	suite.requestHeaders = nil
+	testBadRequest(suite, http.MethodGet, fmt.Sprintf("%s/%s", consts.ClusterPath, cluster.GUID), errorDocumentNotFound, nil, http.StatusNotFound)
+
+	//restore login
+	suite.login(defaultUserGUID)
+	testDeleteDocByGUID(suite, consts.ClusterPath, cluster, newClusterCompareFilter)
+}
+
+func (suite *MainTestSuite) TestRBAC() {
+	cluster := &types.Cluster{PortalBase: armotypes.PortalBase{Name: "rbac-cluster"}}
+	//viewer can read but not write
+	suite.loginWithRoles(defaultUserGUID, auth.RoleViewer)
+	w := suite.doRequest(http.MethodGet, consts.ClusterPath, nil)
+	suite.Equal(http.StatusOK, w.Code)
+	w = suite.doRequest(http.MethodGet, consts.PostureExceptionPolicyPath, nil)
+	suite.Equal(http.StatusOK, w.Code)
+	testBadRequest(suite, http.MethodPost, consts.ClusterPath, `{"error":"Forbidden - POST is not permitted on config routes"}`, cluster, http.StatusForbidden)
+	testBadRequest(suite, http.MethodGet, consts.APIKeyPath, `{"error":"Forbidden - GET is not permitted on access routes"}`, nil, http.StatusForbidden)
+	//editor can write configuration but not security policies
+	suite.loginWithRoles(defaultUserGUID, auth.RoleEditor)
+	cluster = testPostDoc(suite, consts.ClusterPath, cluster, newClusterCompareFilter)
+	testBadRequest(suite, http.MethodPost, consts.FrameworkPath, `{"error":"Forbidden - POST is not permitted on security routes"}`, &types.Framework{}, http.StatusForbidden)
+	//deleting the customer requires write access on all the route groups
+	testBadRequest(suite, http.MethodDelete, consts.CustomerPath, `{"error":"Forbidden - DELETE is not permitted on security routes"}`, nil, http.StatusForbidden)
+	//security admin can manage api keys but not configuration
+	suite.loginWithRoles(defaultUserGUID, auth.RoleSecurityAdmin)
+	w = suite.doRequest(http.MethodGet, consts.APIKeyPath, nil)
+	suite.Equal(http.StatusOK, w.Code)
+	testBadRequest(suite, http.MethodDelete, consts.ClusterPath+"/"+cluster.GUID, `{"error":"Forbidden - DELETE is not permitted on config routes"}`, nil, http.StatusForbidden)
+	testBadRequest(suite, http.MethodDelete, consts.CustomerPath, `{"error":"Forbidden - DELETE is not permitted on config routes"}`, nil, http.StatusForbidden)
+	//only platform admins can use admin APIs
+	testBadRequest(suite, http.MethodGet, consts.AdminPath+"/activeCustomers", `{"error":"Forbidden - GET is not permitted on admin routes"}`, nil, http.StatusForbidden)
+	suite.loginWithRoles(defaultUserGUID, auth.RolePlatformAdmin)
+	w = suite.doRequest(http.MethodDelete, consts.AdminPath+"/customers", nil)
+	suite.Equal(http.StatusBadRequest, w.Code, "admin route should be reached and fail on missing query param")
+
+	//restore login
+	suite.login(defaultUserGUID)
+	testDeleteDocByGUID(suite, consts.ClusterPath, cluster, newClusterCompareFilter)
+}
+
+func (suite *MainTestSuite) TestAPIKeys() {
+	//create api key with write access to clusters and read access to registry cron jobs
+	apiKey := &types.APIKey{
+		PortalBase: armotypes.PortalBase{Name: "ci-key"},
+		Scopes: []types.APIKeyScope{
+			{Path: consts.ClusterPath, Access: auth.ScopeAccessWrite},
+			{Path: consts.RegistryCronJobPath, Access: auth.ScopeAccessRead},
+		},
+	}
+	w := suite.doRequest(http.MethodPost, consts.APIKeyPath, apiKey)
+	suite.Equal(http.StatusCreated, w.Code)
+	newKey, err := decodeResponse[*types.APIKey](w)
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	suite.NotEmpty(newKey.Key, "key should be returned on creation")
+	suite.True(strings.HasPrefix(newKey.Key, newKey.KeyPrefix), "key prefix should match the key")
+	//key is not returned after creation
+	storedKey := testGetDoc(suite, fmt.Sprintf("%s/%s", consts.APIKeyPath, newKey.GUID), newKey, cmp.FilterPath(func(p cmp.Path) bool {
+		return p.String() == "Key"
+	}, cmp.Ignore()))
+	suite.Empty(storedKey.Key, "key should not be returned after creation")
+	//bad scopes
+	badScopeKey := clone(apiKey)
+	badScopeKey.Name = "bad-scope-key"
+	badScopeKey.Scopes = []types.APIKeyScope{{Path: consts.ClusterPath, Access: "admin"}}
+	w = suite.doRequest(http.MethodPost, consts.APIKeyPath, badScopeKey)
+	suite.Equal(http.StatusBadRequest, w.Code)
+
+	//use the api key instead of the cookie
+	suite.authCookie = ""
+	suite.requestHeaders = map[string]string{consts.APIKeyHeader: newKey.Key}
+	w = suite.doRequest(http.MethodGet, consts.ClusterPath, nil)
+	suite.Equal(http.StatusOK, w.Code)
+	cluster := testPostDoc(suite, consts.ClusterPath, &types.Cluster{PortalBase: armotypes.PortalBase{Name: "api-key-cluster"}}, newClusterCompareFilter)
+	w = suite.doRequest(http.MethodGet, consts.RegistryCronJobPath, nil)
+	suite.Equal(http.StatusOK, w.Code)
+	//out of scope requests are forbidden
+	errorForbidden := `{"error":"Forbidden - api key scopes do not allow this request"}`
+	testBadRequest(suite, http.MethodPost, consts.RegistryCronJobPath, errorForbidden, &types.RegistryCronJob{}, http.StatusForbidden)
+	testBadRequest(suite, http.MethodGet, consts.FrameworkPath, errorForbidden, nil, http.StatusForbidden)
+	//api keys cannot manage api keys
+	w = suite.doRequest(http.MethodGet, consts.APIKeyPath, nil)
+	suite.Equal(http.StatusForbidden, w.Code)
+	//unknown key is rejected
+	suite.requestHeaders = map[string]string{consts.APIKeyHeader: "cs_unknown"}
+	testBadRequest(suite, http.MethodGet, consts.ClusterPath, errorUnauthorized(auth.ErrInvalidToken), nil, http.StatusUnauthorized)
+
+	//revoke the key
+	suite.requestHeaders = nil
+	suite.login(defaultUserGUID)
+	testDeleteDocByGUID(suite, consts.APIKeyPath, storedKey)
+	suite.authCookie = ""
+	suite.requestHeaders = map[string]string{consts.APIKeyHeader: newKey.Key}
+	testBadRequest(suite, http.MethodGet, consts.ClusterPath, errorUnauthorized(auth.ErrInvalidToken), nil, http.StatusUnauthorized)
+	//revoked keys cannot be restored
+	suite.requestHeaders = nil
+	suite.login(defaultUserGUID)
+	testBadRequest(suite, http.MethodPost, consts.APIKeyPath+"/"+storedKey.GUID+"/restore", "404 page not found", nil, http.StatusNotFound)
+
+	//restore login
+	suite.login(defaultUserGUID)
+	testDeleteDocByGUID(suite, consts.ClusterPath, cluster, newClusterCompareFilter)
+}
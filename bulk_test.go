@@ -0,0 +1,109 @@
+package main
+
+import (
+	"config-service/db"
+	"config-service/handlers"
+	"config-service/types"
+	"config-service/utils/consts"
+	"net/http"
+)
+
+func (suite *MainTestSuite) TestBulkPut() {
+	suite.login("bulk-put-customer-guid")
+	policies, _ := loadJson[*types.PostureExceptionPolicy](posturePoliciesJson)
+	policies = policies[:2]
+	policies[0].Name = "bulk-put-policy-1"
+	policies[1].Name = "bulk-put-policy-2"
+	policies = testBulkPostDocs(suite, consts.PostureExceptionPolicyPath, policies, commonCmpFilter)
+	original := clone(policies[0])
+
+	policies[0].Attributes = map[string]interface{}{"bulk": "updated-1"}
+	policies[1].Attributes = map[string]interface{}{"bulk": "updated-2"}
+	notExist := clone(policies[0])
+	notExist.GUID = "not-exist-guid"
+	noGUID := clone(policies[1])
+	noGUID.GUID = ""
+	w := suite.doRequest(http.MethodPut, consts.PostureExceptionPolicyPath, []*types.PostureExceptionPolicy{policies[0], notExist, noGUID, policies[1]})
+	suite.Equal(http.StatusOK, w.Code, w.Body.String())
+	results, err := decodeResponseArray[handlers.BulkItemResult](w)
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	suite.Equal([]handlers.BulkItemResult{
+		{Index: 0, GUID: policies[0].GUID, Status: handlers.BulkStatusUpdated},
+		{Index: 1, GUID: notExist.GUID, Status: handlers.BulkStatusNotFound, Error: "document not found"},
+		{Index: 2, Status: handlers.BulkStatusInvalid, Error: "guid is required"},
+		{Index: 3, GUID: policies[1].GUID, Status: handlers.BulkStatusUpdated},
+	}, results)
+	for _, policy := range policies {
+		testGetDoc(suite, consts.PostureExceptionPolicyPath+"/"+policy.GUID, policy, commonCmpFilter)
+	}
+	//the updated revision is in the history
+	historyPath := consts.PostureExceptionPolicyPath + "/" + policies[0].GUID + "/history"
+	testGetDoc(suite, historyPath+"/0", original, commonCmpFilter)
+	testGetDoc(suite, historyPath+"/1", policies[0], commonCmpFilter)
+	testBadRequest(suite, http.MethodGet, historyPath+"/2", errorDocumentNotFound, nil, http.StatusNotFound)
+	//the changes of the updated documents are audited
+	w = suite.doRequest(http.MethodGet, consts.AuditPath+"?"+consts.TargetGUIDParam+"="+policies[0].GUID+"&"+consts.LimitParam+"=1", nil)
+	suite.Equal(http.StatusOK, w.Code, w.Body.String())
+	audit, err := decodeResponse[*db.AggResult[db.AuditRecord]](w)
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	suite.Len(audit.Results, 1)
+	suite.Equal(http.MethodPut, audit.Results[0].Method)
+	suite.Len(audit.Results[0].Changes, 2)
+	suite.Contains(audit.Results[0].Changes[0].Diff, db.FieldDiff{Field: "attributes.bulk", After: "updated-1"})
+
+	//bulk requests cannot have GUID in path
+	testBadRequest(suite, http.MethodPut, consts.PostureExceptionPolicyPath+"/"+policies[0].GUID, `{"error":"GUID in path is not allowed in bulk request"}`, policies, http.StatusBadRequest)
+	testBadRequest(suite, http.MethodPut, consts.PostureExceptionPolicyPath, `{"error":"no documents in request"}`, []*types.PostureExceptionPolicy{}, http.StatusBadRequest)
+
+	for _, policy := range policies {
+		testDeleteDocByGUID(suite, consts.PostureExceptionPolicyPath, policy, commonCmpFilter)
+	}
+	suite.login(defaultUserGUID)
+}
+
+func (suite *MainTestSuite) TestBulkPostModes() {
+	suite.login("bulk-post-customer-guid")
+	policies, _ := loadJson[*types.PostureExceptionPolicy](posturePoliciesJson)
+	existing := clone(policies[0])
+	existing.Name = "bulk-post-existing"
+	existing = testPostDoc(suite, consts.PostureExceptionPolicyPath, existing, commonCmpFilter)
+
+	//partial mode creates the valid documents
+	newPolicy := clone(policies[1])
+	newPolicy.Name = "bulk-post-new"
+	noName := clone(policies[2])
+	noName.Name = ""
+	w := suite.doRequest(http.MethodPost, consts.PostureExceptionPolicyPath+"?mode=partial", []*types.PostureExceptionPolicy{newPolicy, clone(newPolicy), clone(existing), noName})
+	suite.Equal(http.StatusOK, w.Code, w.Body.String())
+	results, err := decodeResponseArray[handlers.BulkItemResult](w)
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	suite.Len(results, 4)
+	suite.Equal(handlers.BulkStatusCreated, results[0].Status)
+	suite.NotEmpty(results[0].GUID)
+	suite.Equal(handlers.BulkItemResult{Index: 1, GUID: newPolicy.GUID, Status: handlers.BulkStatusInvalid, Error: "name bulk-post-new already exists"}, results[1])
+	suite.Equal(handlers.BulkItemResult{Index: 2, GUID: existing.GUID, Status: handlers.BulkStatusInvalid, Error: "name bulk-post-existing already exists"}, results[2])
+	suite.Equal(handlers.BulkItemResult{Index: 3, GUID: noName.GUID, Status: handlers.BulkStatusInvalid, Error: "name is required"}, results[3])
+	newPolicy = testGetDoc(suite, consts.PostureExceptionPolicyPath+"/"+results[0].GUID, newPolicy, commonCmpFilter)
+
+	//atomic mode creates all the documents or none of them
+	atomic1, atomic2 := clone(policies[1]), clone(policies[2])
+	atomic1.Name, atomic2.Name = "bulk-post-atomic-1", "bulk-post-atomic-2"
+	testBadRequest(suite, http.MethodPost, consts.PostureExceptionPolicyPath+"?mode=atomic", `{"error":"name bulk-post-existing already exists"}`, []*types.PostureExceptionPolicy{atomic1, clone(existing)}, http.StatusBadRequest)
+	testBadRequest(suite, http.MethodGet, consts.PostureExceptionPolicyPath+"?policyName="+atomic1.Name, errorDocumentNotFound, nil, http.StatusNotFound)
+	//the test server is standalone, atomic inserts require a replica set for transactions
+	testBadRequest(suite, http.MethodPost, consts.PostureExceptionPolicyPath+"?mode=atomic", `{"error":"atomic mode requires a replica set"}`, []*types.PostureExceptionPolicy{atomic1, atomic2}, http.StatusBadRequest)
+	testBadRequest(suite, http.MethodGet, consts.PostureExceptionPolicyPath+"?policyName="+atomic2.Name, errorDocumentNotFound, nil, http.StatusNotFound)
+
+	testBadRequest(suite, http.MethodPost, consts.PostureExceptionPolicyPath+"?mode=all", `{"error":"unsupported mode all, supported modes are: partial,atomic"}`, []*types.PostureExceptionPolicy{atomic1}, http.StatusBadRequest)
+
+	for _, policy := range []*types.PostureExceptionPolicy{existing, newPolicy} {
+		testDeleteDocByGUID(suite, consts.PostureExceptionPolicyPath, policy, commonCmpFilter)
+	}
+	suite.login(defaultUserGUID)
+}
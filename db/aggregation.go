@@ -33,9 +33,10 @@ func Init() {
 }
 
 type Metadata struct {
-	Total    int `json:"total" bson:"total"`
-	Limit    int `json:"limit" bson:"limit"`
-	NextSkip int `json:"nextSkip" bson:"nextSkip"`
+	Total      int    `json:"total" bson:"total"`
+	Limit      int    `json:"limit" bson:"limit"`
+	NextSkip   int    `json:"nextSkip" bson:"nextSkip"`
+	NextCursor string `json:"nextCursor,omitempty" bson:"nextCursor,omitempty"`
 }
 
 type AggResult[T any] struct {
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"config-service/db/mongo"
+	"config-service/utils/consts"
+	"config-service/utils/log"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	mongoDB "go.mongodb.org/mongo-driver/mongo"
+)
+
+// audit records are not owned by the customers (they have no customers field) so they are kept when the customers data is deleted
+
+// AuditRecord is the record of a request that modified documents
+type AuditRecord struct {
+	ID            string        `json:"id" bson:"_id"`
+	Time          time.Time     `json:"time" bson:"time"`
+	CustomerGUID  string        `json:"customerGUID" bson:"customerGUID"`                 //the customer of the request
+	ActorGUID     string        `json:"actorGUID" bson:"actorGUID"`                       //the authenticated customer, the admin when an admin acts as the customer
+	Admin         bool          `json:"admin" bson:"admin"`                               //true if the request was authenticated with admin access
+	Impersonation bool          `json:"impersonation" bson:"impersonation"`               //true if an admin acted as the customer
+	Method        string        `json:"method" bson:"method"`                             //the request method
+	Path          string        `json:"path" bson:"path"`                                 //the request path
+	Query         string        `json:"query,omitempty" bson:"query,omitempty"`           //the request raw query
+	Collection    string        `json:"collection,omitempty" bson:"collection,omitempty"` //the collection of the modified documents
+	Status        int           `json:"status" bson:"status"`                             //the response status code
+	TargetGUIDs   []string      `json:"targetGUIDs,omitempty" bson:"targetGUIDs,omitempty"`
+	Changes       []AuditChange `json:"changes,omitempty" bson:"changes,omitempty"`
+	TraceID       string        `json:"traceID,omitempty" bson:"traceID,omitempty"`
+}
+
+// AuditChange is the field level diff of a modified document
+type AuditChange struct {
+	GUID string      `json:"guid" bson:"guid"`
+	Diff []FieldDiff `json:"diff" bson:"diff"`
+}
+
+// FieldDiff is the change of a document field, Before is empty for added fields and After is empty for removed fields
+type FieldDiff struct {
+	Field  string      `json:"field" bson:"field"`
+	Before interface{} `json:"before,omitempty" bson:"before,omitempty"`
+	After  interface{} `json:"after,omitempty" bson:"after,omitempty"`
+}
+
+// audit records fields
+const (
+	auditTimeField        = "time"
+	auditActorField       = "actorGUID"
+	auditCollectionField  = "collection"
+	auditTargetGUIDsField = "targetGUIDs"
+)
+
+// EnsureAuditIndexes creates the indexes of the audit collection
+func EnsureAuditIndexes(c context.Context) error {
+	_, err := mongo.GetWriteCollection(consts.AuditCollection).Indexes().CreateMany(c, []mongoDB.IndexModel{
+		{Keys: bson.D{{Key: consts.CustomerGUIDField, Value: 1}, {Key: auditTimeField, Value: -1}}},
+		{Keys: bson.D{{Key: auditTimeField, Value: -1}}},
+	})
+	return err
+}
+
+// InsertAuditRecord appends the record to the audit collection
+func InsertAuditRecord(c context.Context, record AuditRecord) error {
+	_, err := mongo.GetWriteCollection(consts.AuditCollection).InsertOne(c, record)
+	return err
+}
+
+// AuditQuery filters audit records, empty fields are not filtered
+type AuditQuery struct {
+	CustomerGUIDs []string
+	ActorGUID     string
+	Collection    string
+	TargetGUID    string
+	From          *time.Time
+	To            *time.Time
+}
+
+// FindAuditRecords returns a page of the audit records matching the query, latest first
+func FindAuditRecords(c context.Context, query AuditQuery, pagination Pagination) (*AggResult[AuditRecord], error) {
+	defer log.LogNTraceEnterExit("FindAuditRecords", c)()
+	filterBuilder := NewFilterBuilder()
+	if len(query.CustomerGUIDs) > 0 {
+		filterBuilder.WithIn(consts.CustomerGUIDField, query.CustomerGUIDs)
+	}
+	if query.ActorGUID != "" {
+		filterBuilder.WithValue(auditActorField, query.ActorGUID)
+	}
+	if query.Collection != "" {
+		filterBuilder.WithValue(auditCollectionField, query.Collection)
+	}
+	if query.TargetGUID != "" {
+		filterBuilder.WithValue(auditTargetGUIDsField, query.TargetGUID)
+	}
+	timeRange := bson.D{}
+	if query.From != nil {
+		timeRange = append(timeRange, bson.E{Key: "$gte", Value: query.From.UTC()})
+	}
+	if query.To != nil {
+		timeRange = append(timeRange, bson.E{Key: "$lt", Value: query.To.UTC()})
+	}
+	if len(timeRange) > 0 {
+		filterBuilder.WithValue(auditTimeField, timeRange)
+	}
+	return findPage[AuditRecord](c, consts.AuditCollection, filterBuilder.Get(), bson.D{{Key: auditTimeField, Value: -1}, {Key: consts.IdField, Value: -1}}, pagination)
+}
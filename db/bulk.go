@@ -0,0 +1,203 @@
+package db
+
+import (
+	"config-service/db/mongo"
+	"config-service/types"
+	"config-service/utils/consts"
+	"config-service/utils/log"
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	mongoDB "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrDocumentNotFound = errors.New("document not found")
+
+var ErrTransactionsNotSupported = errors.New("atomic mode requires a replica set")
+
+// mongo error code of transactions on servers that do not support them (standalone servers)
+const illegalOperationErrorCode = 20
+
+// BulkUpdate is the update command of a document in a bulk write
+type BulkUpdate struct {
+	ID     string
+	Update bson.D
+}
+
+// BulkUpdated is a document updated by a bulk write
+type BulkUpdated[T types.DocContent] struct {
+	Before T
+	After  T
+}
+
+// BulkUpdateDocuments updates the customer documents in one unordered bulk write, each document is updated only if it is still in the read revision
+// the read revisions of the updated documents are appended to the history after the write
+// returns the updated documents and the errors of the failed updates by their index,
+// ErrDocumentNotFound for documents that do not exist and ErrUpdateConflict for documents that were modified after they were read
+func BulkUpdateDocuments[T types.DocContent](c context.Context, updates []BulkUpdate) (map[int]BulkUpdated[T], map[int]error, error) {
+	defer log.LogNTraceEnterExit("BulkUpdateDocuments", c)()
+	collection, _, err := ReadContext(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := make([]string, 0, len(updates))
+	for _, update := range updates {
+		ids = append(ids, update.ID)
+	}
+	existingDocs, err := FindForCustomer[bson.Raw](c, NewFilterBuilder().WithIn(consts.IdField, ids), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	existing := map[string]bson.Raw{}
+	for _, raw := range existingDocs {
+		id, err := idOf(raw)
+		if err != nil {
+			return nil, nil, err
+		}
+		existing[id] = raw
+	}
+	failed := map[int]error{}
+	models := []mongoDB.WriteModel{}
+	modelsIndexes := []int{}
+	readRevisions := map[int]int64{}
+	for i, update := range updates {
+		raw, ok := existing[update.ID]
+		if !ok {
+			failed[i] = ErrDocumentNotFound
+			continue
+		}
+		version, err := versionOf(raw)
+		if err != nil {
+			return nil, nil, err
+		}
+		models = append(models, mongoDB.NewUpdateOneModel().
+			SetFilter(inRevision(NewFilterBuilder().WithNotDeleteForCustomer(c).Get(), version)).
+			SetUpdate(withRevisionIncrement(update.Update)))
+		modelsIndexes = append(modelsIndexes, i)
+		readRevisions[i] = version.Revision
+	}
+	updated := map[int]BulkUpdated[T]{}
+	if len(models) == 0 {
+		return updated, failed, nil
+	}
+	expectedMatches := int64(len(models))
+	res, err := mongo.GetWriteCollection(collection).BulkWrite(c, models, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		var bulkErr mongoDB.BulkWriteException
+		if !errors.As(err, &bulkErr) || len(bulkErr.WriteErrors) == 0 {
+			return nil, nil, err
+		}
+		for _, writeErr := range bulkErr.WriteErrors {
+			failed[modelsIndexes[writeErr.Index]] = writeErr
+			expectedMatches--
+		}
+	}
+	//bulk writes report the matched count of the whole write, when some writes did not match
+	//the documents that are not in the revision following their read revision were modified by others before the write
+	conflicts := res != nil && res.MatchedCount < expectedMatches
+	//read the updated documents, bulk writes do not return them
+	updatedIDs := []string{}
+	for _, i := range modelsIndexes {
+		if _, ok := failed[i]; !ok {
+			updatedIDs = append(updatedIDs, updates[i].ID)
+		}
+	}
+	afterDocs, err := FindForCustomer[bson.Raw](c, NewFilterBuilder().WithIn(consts.IdField, updatedIDs), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	after := map[string]bson.Raw{}
+	for _, raw := range afterDocs {
+		id, err := idOf(raw)
+		if err != nil {
+			return nil, nil, err
+		}
+		after[id] = raw
+	}
+	for _, i := range modelsIndexes {
+		if _, ok := failed[i]; ok {
+			continue
+		}
+		afterRaw, ok := after[updates[i].ID]
+		if !ok {
+			//deleted after the update
+			failed[i] = ErrDocumentNotFound
+			continue
+		}
+		if conflicts {
+			if version, err := versionOf(afterRaw); err != nil {
+				return nil, nil, err
+			} else if version.Revision != readRevisions[i]+1 {
+				failed[i] = ErrUpdateConflict
+				continue
+			}
+		}
+		beforeRaw := existing[updates[i].ID]
+		var doc BulkUpdated[T]
+		if err := bson.Unmarshal(beforeRaw, &doc.Before); err != nil {
+			return nil, nil, err
+		} else if err := bson.Unmarshal(afterRaw, &doc.After); err != nil {
+			return nil, nil, err
+		}
+		saveHistory(c, collection, beforeRaw, HistoryOperationUpdate)
+		updated[i] = doc
+	}
+	return updated, failed, nil
+}
+
+// InsertDocumentsUnordered inserts the documents in one unordered insert so the failure of one document does not fail the others
+// returns the errors of the failed inserts by their index
+func InsertDocumentsUnordered[T types.DocContent](c context.Context, docs []T) (map[int]error, error) {
+	defer log.LogNTraceEnterExit("InsertDocumentsUnordered", c)()
+	collection, customerGUID, err := ReadContext(c)
+	if err != nil {
+		return nil, err
+	}
+	dbDocs := []interface{}{}
+	for i := range docs {
+		dbDocs = append(dbDocs, types.NewDocument(docs[i], customerGUID))
+	}
+	failed := map[int]error{}
+	if _, err := mongo.GetWriteCollection(collection).InsertMany(c, dbDocs, options.InsertMany().SetOrdered(false)); err != nil {
+		var bulkErr mongoDB.BulkWriteException
+		if !errors.As(err, &bulkErr) || len(bulkErr.WriteErrors) == 0 {
+			return nil, err
+		}
+		for _, writeErr := range bulkErr.WriteErrors {
+			failed[writeErr.Index] = writeErr
+		}
+	}
+	return failed, nil
+}
+
+// InsertDocumentsAtomic inserts all the documents or none of them in a transaction
+// returns ErrTransactionsNotSupported if the server does not support transactions (standalone servers)
+func InsertDocumentsAtomic[T types.DocContent](c context.Context, docs []T) ([]T, error) {
+	defer log.LogNTraceEnterExit("InsertDocumentsAtomic", c)()
+	collection, customerGUID, err := ReadContext(c)
+	if err != nil {
+		return nil, err
+	}
+	dbDocs := []interface{}{}
+	for i := range docs {
+		dbDocs = append(dbDocs, types.NewDocument(docs[i], customerGUID))
+	}
+	writeCollection := mongo.GetWriteCollection(collection)
+	session, err := writeCollection.Database().Client().StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(c)
+	_, err = session.WithTransaction(c, func(sc mongoDB.SessionContext) (interface{}, error) {
+		return writeCollection.InsertMany(sc, dbDocs)
+	})
+	var serverErr mongoDB.ServerError
+	if errors.As(err, &serverErr) && serverErr.HasErrorCode(illegalOperationErrorCode) {
+		return nil, ErrTransactionsNotSupported
+	} else if err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
@@ -108,11 +108,31 @@ func (f *FilterBuilder) WithExists(key string, value bool) *FilterBuilder {
 	return f
 }
 
+func (f *FilterBuilder) WithRegex(key string, pattern string) *FilterBuilder {
+	f.filter = append(f.filter, bson.E{Key: key, Value: bson.D{{Key: "$regex", Value: pattern}}})
+	return f
+}
+
+func (f *FilterBuilder) WithGreaterThan(key string, value interface{}) *FilterBuilder {
+	f.filter = append(f.filter, bson.E{Key: key, Value: bson.D{{Key: "$gt", Value: value}}})
+	return f
+}
+
+func (f *FilterBuilder) WithLowerThan(key string, value interface{}) *FilterBuilder {
+	f.filter = append(f.filter, bson.E{Key: key, Value: bson.D{{Key: "$lt", Value: value}}})
+	return f
+}
+
 func (f *FilterBuilder) AddNotExists(key string) *FilterBuilder {
 	f.filter = append(f.filter, bson.E{Key: key, Value: bson.D{{Key: "$exists", Value: false}}})
 	return f
 }
 
+func (f *FilterBuilder) WithTextSearch(search string) *FilterBuilder {
+	f.filter = append(f.filter, bson.E{Key: "$text", Value: bson.D{{Key: "$search", Value: search}}})
+	return f
+}
+
 func (f *FilterBuilder) WithElementMatch(element interface{}) *FilterBuilder {
 	f.filter = append(f.filter, bson.E{Key: "$elemMatch", Value: element})
 	return f
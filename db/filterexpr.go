@@ -0,0 +1,341 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Filter expressions are RSQL/FIQL style boolean expressions of comparisons, e.g.
+//
+//	name==prod* and (attributes.env==staging or attributes.team!=sec)
+//
+// operators:     ==, !=, =gt=, =ge=, =lt=, =le=, =in=, =out=  (e.g. attributes.env=in=(dev,staging))
+// logical:       and (or ;), or (or ,), not, parentheses
+// values:        unquoted or single/double quoted strings, * is a wildcard in unquoted == and != values
+//
+// values are compared as strings, =gt=, =ge=, =lt= and =le= compare lexicographically so dates
+// (e.g. 2023-01-02 or 2023-01-02T03:00:00+02:00) are converted to the RFC3339 UTC format documents times are stored in
+
+var ErrInvalidFilter = errors.New("invalid filter")
+
+const maxFilterDepth = 20
+
+var filterFieldRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)*$`)
+
+// ParseFilterExpression compiles the filter expression to a mongo filter
+// only fields in allowedFields or nested in one of them (e.g. attributes.env for attributes) can be used
+func ParseFilterExpression(expr string, allowedFields []string) (bson.D, error) {
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("%w: empty expression", ErrInvalidFilter)
+	}
+	p := &filterParser{tokens: tokens, allowedFields: allowedFields}
+	filter, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("%w: unexpected %s", ErrInvalidFilter, p.peek().value)
+	}
+	return filter, nil
+}
+
+type filterTokenKind int
+
+const (
+	tokenWord filterTokenKind = iota
+	tokenQuoted
+	tokenOperator
+	tokenOpen
+	tokenClose
+	tokenAnd
+	tokenOr
+)
+
+type filterToken struct {
+	kind  filterTokenKind
+	value string
+}
+
+func isFilterWordRune(r rune) bool {
+	return !unicode.IsSpace(r) && !strings.ContainsRune(`()';,"=!`, r)
+}
+
+func tokenizeFilter(expr string) ([]filterToken, error) {
+	tokens := []filterToken{}
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, filterToken{tokenOpen, "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, filterToken{tokenClose, ")"})
+			i++
+		case r == ';':
+			tokens = append(tokens, filterToken{tokenAnd, ";"})
+			i++
+		case r == ',':
+			tokens = append(tokens, filterToken{tokenOr, ","})
+			i++
+		case r == '\'' || r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("%w: unterminated quoted value", ErrInvalidFilter)
+			}
+			tokens = append(tokens, filterToken{tokenQuoted, string(runes[i+1 : end])})
+			i = end + 1
+		case r == '!' || r == '=':
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				tokens = append(tokens, filterToken{tokenOperator, string(runes[i : i+2])})
+				i += 2
+				continue
+			}
+			end := i + 1
+			for end < len(runes) && unicode.IsLetter(runes[end]) {
+				end++
+			}
+			if r == '!' || end == len(runes) || runes[end] != '=' {
+				return nil, fmt.Errorf("%w: invalid operator at position %d", ErrInvalidFilter, i)
+			}
+			tokens = append(tokens, filterToken{tokenOperator, string(runes[i : end+1])})
+			i = end + 1
+		default:
+			end := i
+			for end < len(runes) && isFilterWordRune(runes[end]) {
+				end++
+			}
+			word := string(runes[i:end])
+			switch strings.ToLower(word) {
+			case "and":
+				tokens = append(tokens, filterToken{tokenAnd, word})
+			case "or":
+				tokens = append(tokens, filterToken{tokenOr, word})
+			default:
+				tokens = append(tokens, filterToken{tokenWord, word})
+			}
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens        []filterToken
+	pos           int
+	allowedFields []string
+}
+
+func (p *filterParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() (filterToken, error) {
+	if p.done() {
+		return filterToken{}, fmt.Errorf("%w: unexpected end of expression", ErrInvalidFilter)
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	return token, nil
+}
+
+// parseOr parses: and-expression (or and-expression)*
+func (p *filterParser) parseOr(depth int) (bson.D, error) {
+	return p.parseList(depth, tokenOr, "$or", p.parseAnd)
+}
+
+// parseAnd parses: unary (and unary)*
+func (p *filterParser) parseAnd(depth int) (bson.D, error) {
+	return p.parseList(depth, tokenAnd, "$and", p.parseUnary)
+}
+
+func (p *filterParser) parseList(depth int, separator filterTokenKind, operator string, parseItem func(int) (bson.D, error)) (bson.D, error) {
+	first, err := parseItem(depth)
+	if err != nil {
+		return nil, err
+	}
+	items := bson.A{first}
+	for !p.done() && p.peek().kind == separator {
+		p.pos++
+		item, err := parseItem(depth)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if len(items) == 1 {
+		return first, nil
+	}
+	return bson.D{{Key: operator, Value: items}}, nil
+}
+
+// parseUnary parses: not unary | ( expression ) | comparison
+func (p *filterParser) parseUnary(depth int) (bson.D, error) {
+	if depth > maxFilterDepth {
+		return nil, fmt.Errorf("%w: expression is nested too deep", ErrInvalidFilter)
+	}
+	token, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case token.kind == tokenWord && strings.EqualFold(token.value, "not"):
+		filter, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return bson.D{{Key: "$nor", Value: bson.A{filter}}}, nil
+	case token.kind == tokenOpen:
+		filter, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if closing, err := p.next(); err != nil {
+			return nil, err
+		} else if closing.kind != tokenClose {
+			return nil, fmt.Errorf("%w: expected ) instead of %s", ErrInvalidFilter, closing.value)
+		}
+		return filter, nil
+	case token.kind == tokenWord:
+		return p.parseComparison(token.value)
+	}
+	return nil, fmt.Errorf("%w: unexpected %s", ErrInvalidFilter, token.value)
+}
+
+// parseComparison parses: field operator value
+func (p *filterParser) parseComparison(field string) (bson.D, error) {
+	if !filterFieldRegex.MatchString(field) {
+		return nil, fmt.Errorf("%w: invalid field %s", ErrInvalidFilter, field)
+	}
+	if !p.isAllowedField(field) {
+		return nil, fmt.Errorf("%w: filter by %s is not supported", ErrInvalidFilter, field)
+	}
+	operator, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if operator.kind != tokenOperator {
+		return nil, fmt.Errorf("%w: expected operator after %s", ErrInvalidFilter, field)
+	}
+	filterBuilder := NewFilterBuilder()
+	switch operator.value {
+	case "=in=", "=out=":
+		values, err := p.parseValuesList()
+		if err != nil {
+			return nil, err
+		}
+		if operator.value == "=in=" {
+			return filterBuilder.WithIn(field, values).Get(), nil
+		}
+		return filterBuilder.WithNotIn(field, values).Get(), nil
+	}
+	value, quoted, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	switch operator.value {
+	case "==":
+		if pattern, ok := wildcardPattern(value, quoted); ok {
+			return filterBuilder.WithValue(field, pattern).Get(), nil
+		}
+		return filterBuilder.WithValue(field, value).Get(), nil
+	case "!=":
+		if pattern, ok := wildcardPattern(value, quoted); ok {
+			return filterBuilder.WithValue(field, bson.D{{Key: "$not", Value: pattern}}).Get(), nil
+		}
+		return filterBuilder.WithNotEqual(field, value).Get(), nil
+	case "=gt=", "=ge=", "=lt=", "=le=":
+		mongoOperator := map[string]string{"=gt=": "$gt", "=ge=": "$gte", "=lt=": "$lt", "=le=": "$lte"}[operator.value]
+		return filterBuilder.WithValue(field, bson.D{{Key: mongoOperator, Value: NormalizeTime(value)}}).Get(), nil
+	}
+	return nil, fmt.Errorf("%w: unsupported operator %s", ErrInvalidFilter, operator.value)
+}
+
+func (p *filterParser) parseValue() (string, bool, error) {
+	token, err := p.next()
+	if err != nil {
+		return "", false, err
+	}
+	if token.kind != tokenWord && token.kind != tokenQuoted {
+		return "", false, fmt.Errorf("%w: expected value instead of %s", ErrInvalidFilter, token.value)
+	}
+	return token.value, token.kind == tokenQuoted, nil
+}
+
+// parseValuesList parses: ( value (, value)* )
+func (p *filterParser) parseValuesList() ([]string, error) {
+	if token, err := p.next(); err != nil {
+		return nil, err
+	} else if token.kind != tokenOpen {
+		return nil, fmt.Errorf("%w: expected ( instead of %s", ErrInvalidFilter, token.value)
+	}
+	values := []string{}
+	for {
+		value, _, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		token, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if token.kind == tokenClose {
+			return values, nil
+		}
+		if token.kind != tokenOr || token.value != "," {
+			return nil, fmt.Errorf("%w: expected , or ) instead of %s", ErrInvalidFilter, token.value)
+		}
+	}
+}
+
+func (p *filterParser) isAllowedField(field string) bool {
+	for _, allowed := range p.allowedFields {
+		if field == allowed || strings.HasPrefix(field, allowed+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcardPattern returns a regex matching the value with * as wildcard, returns false if the value has no wildcards
+func wildcardPattern(value string, quoted bool) (primitive.Regex, bool) {
+	if quoted || !strings.Contains(value, "*") {
+		return primitive.Regex{}, false
+	}
+	parts := strings.Split(value, "*")
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	return primitive.Regex{Pattern: "^" + strings.Join(parts, ".*") + "$"}, true
+}
+
+// NormalizeTime returns dates in the RFC3339 UTC format documents times are stored in, other values are returned as is
+func NormalizeTime(value string) string {
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC().Format(time.RFC3339)
+		}
+	}
+	return value
+}
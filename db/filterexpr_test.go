@@ -0,0 +1,76 @@
+package db
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseFilterExpression(t *testing.T) {
+	allowed := []string{"name", "attributes"}
+	tests := []struct {
+		expr    string
+		want    bson.D
+		wantErr bool
+	}{
+		{expr: "name==prod", want: bson.D{{Key: "name", Value: "prod"}}},
+		{expr: "name=='prod*'", want: bson.D{{Key: "name", Value: "prod*"}}},
+		{expr: "name==prod*", want: bson.D{{Key: "name", Value: primitive.Regex{Pattern: "^prod.*$"}}}},
+		{expr: "name!=*.io", want: bson.D{{Key: "name", Value: bson.D{{Key: "$not", Value: primitive.Regex{Pattern: `^.*\.io$`}}}}}},
+		{expr: "attributes.env!=dev", want: bson.D{{Key: "attributes.env", Value: bson.D{{Key: "$ne", Value: "dev"}}}}},
+		{expr: "attributes.env=in=(dev, \"staging env\")", want: bson.D{{Key: "attributes.env", Value: bson.D{{Key: "$in", Value: []string{"dev", "staging env"}}}}}},
+		{expr: "attributes.env=out=(dev)", want: bson.D{{Key: "attributes.env", Value: bson.D{{Key: "$nin", Value: []string{"dev"}}}}}},
+		{expr: "attributes.time=ge=2023-01-01T00:00:00Z", want: bson.D{{Key: "attributes.time", Value: bson.D{{Key: "$gte", Value: "2023-01-01T00:00:00Z"}}}}},
+		{expr: "attributes.time=lt=2023-01-02T03:00:00+02:00", want: bson.D{{Key: "attributes.time", Value: bson.D{{Key: "$lt", Value: "2023-01-02T01:00:00Z"}}}}},
+		{expr: "attributes.time=gt=2023-01-02", want: bson.D{{Key: "attributes.time", Value: bson.D{{Key: "$gt", Value: "2023-01-02T00:00:00Z"}}}}},
+		{expr: "attributes.version=gt=10", want: bson.D{{Key: "attributes.version", Value: bson.D{{Key: "$gt", Value: "10"}}}}},
+		{
+			expr: "name==prod* and (attributes.env==staging or attributes.team!=sec)",
+			want: bson.D{{Key: "$and", Value: bson.A{
+				bson.D{{Key: "name", Value: primitive.Regex{Pattern: "^prod.*$"}}},
+				bson.D{{Key: "$or", Value: bson.A{
+					bson.D{{Key: "attributes.env", Value: "staging"}},
+					bson.D{{Key: "attributes.team", Value: bson.D{{Key: "$ne", Value: "sec"}}}},
+				}}},
+			}}},
+		},
+		{
+			expr: "name==a;name==b,name==c",
+			want: bson.D{{Key: "$or", Value: bson.A{
+				bson.D{{Key: "$and", Value: bson.A{bson.D{{Key: "name", Value: "a"}}, bson.D{{Key: "name", Value: "b"}}}}},
+				bson.D{{Key: "name", Value: "c"}},
+			}}},
+		},
+		{expr: "not name==a", want: bson.D{{Key: "$nor", Value: bson.A{bson.D{{Key: "name", Value: "a"}}}}}},
+		{expr: "", wantErr: true},
+		{expr: "guid==a", wantErr: true},
+		{expr: "names==a", wantErr: true},
+		{expr: "$where==a", wantErr: true},
+		{expr: "name=a", wantErr: true},
+		{expr: "name=regex=a", wantErr: true},
+		{expr: "name==", wantErr: true},
+		{expr: "name==a and", wantErr: true},
+		{expr: "(name==a", wantErr: true},
+		{expr: "name==a)", wantErr: true},
+		{expr: "name=='a", wantErr: true},
+		{expr: "name=in=(a;b)", wantErr: true},
+		{expr: "((((((((((((((((((((((name==a))))))))))))))))))))))", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := ParseFilterExpression(tt.expr, allowed)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFilterExpression() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidFilter) {
+				t.Errorf("ParseFilterExpression() error = %v, want %v", err, ErrInvalidFilter)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFilterExpression() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
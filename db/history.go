@@ -0,0 +1,173 @@
+package db
+
+import (
+	"config-service/db/mongo"
+	"config-service/utils/consts"
+	"config-service/utils/log"
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	mongoDB "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// the version of a document before each update and delete is appended to the history collection of its collection
+
+const historyCollectionSuffix = "_history"
+
+// history record fields
+const (
+	historyOperationField = "operation"
+	historyTimeField      = "time"
+	historyDocumentField  = "document"
+)
+
+// history operations
+const (
+	HistoryOperationUpdate = "update"
+	HistoryOperationDelete = "delete"
+)
+
+// HistoryRecord is a previous revision of a document
+type HistoryRecord[T any] struct {
+	GUID      string    `json:"guid" bson:"guid"`
+	Revision  int64     `json:"revision" bson:"revision"`
+	Operation string    `json:"operation" bson:"operation"` //the operation that replaced this revision
+	Time      time.Time `json:"time" bson:"time"`           //the time this revision was replaced
+	Document  T         `json:"document" bson:"document"`
+}
+
+// HistoryCollection returns the name of the history collection of the collection
+func HistoryCollection(collection string) string {
+	return collection + historyCollectionSuffix
+}
+
+// EnsureHistoryIndex creates the index of the history collection of the collection
+func EnsureHistoryIndex(c context.Context, collection string) error {
+	_, err := mongo.GetWriteCollection(HistoryCollection(collection)).Indexes().CreateOne(c, mongoDB.IndexModel{
+		Keys: bson.D{{Key: consts.CustomersField, Value: 1}, {Key: consts.GUIDField, Value: 1}, {Key: consts.RevisionField, Value: -1}},
+	})
+	return err
+}
+
+// FindHistory returns the previous revisions of the customer document with the GUID, latest first
+func FindHistory[T any](c context.Context, guid string) ([]HistoryRecord[T], error) {
+	defer log.LogNTraceEnterExit("FindHistory", c)()
+	collection, _, err := ReadContext(c)
+	if err != nil {
+		return nil, err
+	}
+	result := []HistoryRecord[T]{}
+	cur, err := mongo.GetReadCollection(HistoryCollection(collection)).Find(c,
+		NewFilterBuilder().WithCustomer(c).WithGUID(guid).Get(),
+		options.Find().SetSort(bson.D{{Key: consts.RevisionField, Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	if err := cur.All(c, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetHistoryRecord returns the previous revision of the customer document with the GUID, nil if there is no such revision
+func GetHistoryRecord[T any](c context.Context, guid string, revision int64) (*HistoryRecord[T], error) {
+	defer log.LogNTraceEnterExit("GetHistoryRecord", c)()
+	collection, _, err := ReadContext(c)
+	if err != nil {
+		return nil, err
+	}
+	var result HistoryRecord[T]
+	if err := mongo.GetReadCollection(HistoryCollection(collection)).FindOne(c,
+		NewFilterBuilder().WithCustomer(c).WithGUID(guid).WithValue(consts.RevisionField, revision).Get()).
+		Decode(&result); err != nil {
+		if err == mongoDB.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &result, nil
+}
+
+// newHistoryRecord returns the history record of the raw document replaced by the operation
+func newHistoryRecord(collection string, raw bson.Raw, operation string, now time.Time) (bson.D, error) {
+	version, err := versionOf(raw)
+	if err != nil {
+		return nil, err
+	}
+	record := bson.D{{Key: consts.IdField, Value: fmt.Sprintf("%s/%d", version.ID, version.Revision)}}
+	if customers, err := raw.LookupErr(consts.CustomersField); err == nil {
+		record = append(record, bson.E{Key: consts.CustomersField, Value: customers})
+	} else if collection == consts.CustomersCollection {
+		//customer documents are owned by themselves
+		record = append(record, bson.E{Key: consts.CustomersField, Value: []string{version.ID}})
+	}
+	return append(record,
+		bson.E{Key: consts.GUIDField, Value: version.ID},
+		bson.E{Key: consts.RevisionField, Value: version.Revision},
+		bson.E{Key: historyOperationField, Value: operation},
+		bson.E{Key: historyTimeField, Value: now.UTC()},
+		bson.E{Key: historyDocumentField, Value: raw},
+	), nil
+}
+
+// saveHistory appends the raw document to the history of the collection, a revision that is already in the history is not saved again
+// failures are logged and do not fail the operation
+func saveHistory(c context.Context, collection string, raw bson.Raw, operation string) {
+	record, err := newHistoryRecord(collection, raw, operation, time.Now())
+	if err == nil {
+		_, err = mongo.GetWriteCollection(HistoryCollection(collection)).InsertOne(c, record)
+	}
+	if err != nil && !mongoDB.IsDuplicateKeyError(err) {
+		log.LogNTraceError("failed to save document history", err, c)
+	}
+}
+
+// documents modified between their read and update are read and updated again up to maxUpdateAttempts times
+const maxUpdateAttempts = 3
+
+// updateWithHistory reads the document matching the filter and updates it only if it is still in the read revision, the read revision is appended to the history
+// returns the documents before and after the update, nil if no document matches the filter and ErrUpdateConflict if the document kept changing
+func updateWithHistory(c context.Context, collection string, filter bson.D, update interface{}, operation string) (before, after bson.Raw, err error) {
+	for i := 0; i < maxUpdateAttempts; i++ {
+		if before, err = mongo.GetWriteCollection(collection).FindOne(c, filter).DecodeBytes(); err == mongoDB.ErrNoDocuments {
+			return nil, nil, nil
+		} else if err != nil {
+			return nil, nil, err
+		}
+		version, err := versionOf(before)
+		if err != nil {
+			return nil, nil, err
+		}
+		after, err = mongo.GetWriteCollection(collection).FindOneAndUpdate(c, inRevision(filter, version), update,
+			options.FindOneAndUpdate().SetReturnDocument(options.After)).
+			DecodeBytes()
+		if err == mongoDB.ErrNoDocuments {
+			//modified after it was read
+			continue
+		} else if err != nil {
+			return nil, nil, err
+		}
+		saveHistory(c, collection, before, operation)
+		return before, after, nil
+	}
+	return nil, nil, ErrUpdateConflict
+}
+
+// findOneAndUpdateWithHistory updates the document matching the filter and appends its previous revision to the history
+// returns the document before the update, nil if no document matches the filter
+func findOneAndUpdateWithHistory(c context.Context, collection string, filter bson.D, update interface{}, operation string) (bson.Raw, error) {
+	raw, err := mongo.GetWriteCollection(collection).FindOneAndUpdate(c, filter, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.Before)).
+		DecodeBytes()
+	if err != nil {
+		if err == mongoDB.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	saveHistory(c, collection, raw, operation)
+	return raw, nil
+}
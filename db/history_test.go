@@ -0,0 +1,66 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNewHistoryRecord(t *testing.T) {
+	now := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name       string
+		collection string
+		doc        bson.D
+		wantID     string
+		want       bson.D
+	}{
+		{
+			name:       "customer document",
+			collection: "clusters",
+			doc:        bson.D{{Key: "_id", Value: "guid"}, {Key: "customers", Value: bson.A{"customer"}}, {Key: "revision", Value: int64(2)}},
+			wantID:     "guid/2",
+			want:       bson.D{{Key: "customers", Value: bson.A{"customer"}}, {Key: "guid", Value: "guid"}, {Key: "revision", Value: int64(2)}, {Key: "operation", Value: "update"}, {Key: "time", Value: primitive.NewDateTimeFromTime(now)}},
+		},
+		{
+			name:       "never updated customer",
+			collection: "customers",
+			doc:        bson.D{{Key: "_id", Value: "guid"}},
+			wantID:     "guid/0",
+			want:       bson.D{{Key: "customers", Value: bson.A{"guid"}}, {Key: "guid", Value: "guid"}, {Key: "revision", Value: int64(0)}, {Key: "operation", Value: "update"}, {Key: "time", Value: primitive.NewDateTimeFromTime(now)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := bson.Marshal(tt.doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			record, err := newHistoryRecord(tt.collection, raw, HistoryOperationUpdate, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			//compare the record as stored
+			recordBytes, err := bson.Marshal(record)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got bson.D
+			if err := bson.Unmarshal(recordBytes, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got[0].Value != tt.wantID {
+				t.Errorf("record id = %v, want %v", got[0].Value, tt.wantID)
+			}
+			if document := got[len(got)-1]; document.Key != "document" || !reflect.DeepEqual(document.Value, tt.doc) {
+				t.Errorf("record document = %v, want %v", document, tt.doc)
+			}
+			if got := got[1 : len(got)-1]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("record = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
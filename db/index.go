@@ -0,0 +1,52 @@
+package db
+
+import (
+	"config-service/db/mongo"
+	"config-service/utils/consts"
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	mongoDB "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"k8s.io/utils/strings/slices"
+)
+
+const textIndexName = "textSearch"
+
+// name matches are ranked higher than matches in other fields
+const textIndexNameWeight = 10
+
+// mongo error codes of an existing index with the same name and different keys or options
+const (
+	indexOptionsConflictCode  = 85
+	indexKeySpecsConflictCode = 86
+)
+
+// EnsureTextIndex creates the text index of the collection on the given fields, an existing text index with other fields is replaced
+// fields must be string fields or arrays of strings, values of nested fields are indexed by their full path (e.g. "resources.attributes.name")
+func EnsureTextIndex(c context.Context, collection string, fields []string) error {
+	keys := bson.D{}
+	for _, field := range fields {
+		keys = append(keys, bson.E{Key: field, Value: "text"})
+	}
+	indexOpts := options.Index().SetName(textIndexName)
+	if slices.Contains(fields, consts.NameField) {
+		indexOpts.SetWeights(bson.D{{Key: consts.NameField, Value: textIndexNameWeight}})
+	}
+	model := mongoDB.IndexModel{Keys: keys, Options: indexOpts}
+	indexes := mongo.GetWriteCollection(collection).Indexes()
+	_, err := indexes.CreateOne(c, model)
+	var cmdErr mongoDB.CommandError
+	if errors.As(err, &cmdErr) && (cmdErr.Code == indexOptionsConflictCode || cmdErr.Code == indexKeySpecsConflictCode) {
+		if _, err := indexes.DropOne(c, textIndexName); err != nil {
+			return err
+		}
+		_, err = indexes.CreateOne(c, model)
+		return err
+	}
+	return err
+}
+
+// TextScore is the projection and sort value of the text search relevance score
+var TextScore = bson.D{{Key: "$meta", Value: "textScore"}}
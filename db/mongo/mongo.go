@@ -17,6 +17,9 @@ import (
 
 var mongoDB, mongoDBprimary *mongo.Database
 
+// change streams are available only on replica sets
+var changeStreamsSupported bool
+
 func MustConnect(config utils.MongoConfig) {
 	if err := Connect(config); err != nil {
 		zap.L().Fatal("failed to connect to mongo", zap.Error(err))
@@ -77,6 +80,7 @@ func Connect(config utils.MongoConfig) error {
 	primaryUrl := getPrimaryUrl(config)
 	if primaryUrl != "" {
 		zap.L().Info("connecting to replica set " + config.ReplicaSet)
+		changeStreamsSupported = true
 		if mongoDB = dbClient.Database(config.DB); mongoDB == nil {
 			return fmt.Errorf("failed to connect. database: %s /n url: %s", config.DB, url)
 		}
@@ -115,6 +119,11 @@ func GetWriteCollection(collectionName string) *mongo.Collection {
 	return mongoDBprimary.Collection(collectionName)
 }
 
+// ChangeStreamsSupported returns true if the connected database is a replica set that supports change streams
+func ChangeStreamsSupported() bool {
+	return changeStreamsSupported
+}
+
 func ListCollectionNames(c context.Context) ([]string, error) {
 	return mongoDB.ListCollectionNames(c, bson.D{}, options.ListCollections().SetAuthorizedCollections(true).SetNameOnly(true))
 }
@@ -0,0 +1,154 @@
+package db
+
+import (
+	"config-service/db/mongo"
+	"config-service/utils/consts"
+	"config-service/utils/log"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const DefaultPageLimit = 100
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+var ErrCursorWithSort = fmt.Errorf("%w: cursor cannot be used with sort", ErrInvalidCursor)
+
+// Pagination of find queries, pages are sorted by _id (after the requested sort if set) so skip and cursor pagination are stable
+type Pagination struct {
+	Limit  int    //page size, default DefaultPageLimit, max MaxAggregationLimit
+	Skip   int    //number of documents to skip
+	Cursor string //opaque cursor from the metadata of the previous page, the page starts after the cursor document
+}
+
+// EncodeCursor returns an opaque cursor pointing after the document with the given id
+func EncodeCursor(id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(id))
+}
+
+// DecodeCursor returns the document id of the cursor
+func DecodeCursor(cursor string) (string, error) {
+	id, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(id) == 0 {
+		return "", ErrInvalidCursor
+	}
+	return string(id), nil
+}
+
+// GetPageForCustomer returns a page of the customer docs matching the filter, findOpts may set the projection and sort of the results
+func GetPageForCustomer[T any](c context.Context, filterBuilder *FilterBuilder, findOpts *options.FindOptions, includeGlobals bool, pagination Pagination) (*AggResult[T], error) {
+	defer log.LogNTraceEnterExit("GetPageForCustomer", c)()
+	collection, _, err := ReadContext(c)
+	if err != nil {
+		return nil, err
+	}
+	if filterBuilder == nil {
+		filterBuilder = NewFilterBuilder()
+	}
+	withListScope(c, filterBuilder, includeGlobals)
+	if pagination.Limit <= 0 {
+		pagination.Limit = DefaultPageLimit
+	} else if pagination.Limit > MaxAggregationLimit {
+		pagination.Limit = MaxAggregationLimit
+	}
+	total, err := mongo.GetReadCollection(collection).CountDocuments(c, filterBuilder.Get())
+	if err != nil {
+		return nil, err
+	}
+	if findOpts == nil {
+		findOpts = options.Find()
+	}
+	sort, _ := findOpts.Sort.(bson.D)
+	if pagination.Cursor != "" {
+		if len(sort) > 0 {
+			return nil, ErrCursorWithSort
+		}
+		afterID, err := DecodeCursor(pagination.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		filterBuilder.WithValue(consts.IdField, bson.D{{Key: "$gt", Value: afterID}})
+	}
+	//read one more document to know if there is a next page
+	findOpts.SetSort(append(append(bson.D{}, sort...), bson.E{Key: consts.IdField, Value: 1})).
+		SetSkip(int64(pagination.Skip)).
+		SetLimit(int64(pagination.Limit + 1))
+	cur, err := mongo.GetReadCollection(collection).Find(c, filterBuilder.Get(), findOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(c)
+	page := &AggResult[T]{
+		Metadata: Metadata{Total: int(total), Limit: pagination.Limit},
+		Results:  []T{},
+	}
+	var lastID string
+	for cur.Next(c) {
+		if len(page.Results) == pagination.Limit {
+			//next page exists, cursor pagination is available only in _id order
+			if len(sort) == 0 {
+				page.Metadata.NextCursor = EncodeCursor(lastID)
+			}
+			if pagination.Cursor == "" {
+				page.Metadata.NextSkip = pagination.Skip + pagination.Limit
+			}
+			break
+		}
+		var doc T
+		if err := cur.Decode(&doc); err != nil {
+			return nil, err
+		}
+		page.Results = append(page.Results, doc)
+		if lastID, err = idOf(cur.Current); err != nil {
+			return nil, err
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+func idOf(raw bson.Raw) (string, error) {
+	id, ok := raw.Lookup(consts.IdField).StringValueOK()
+	if !ok {
+		return "", fmt.Errorf("document %s is not a string", consts.IdField)
+	}
+	return id, nil
+}
+
+// findPage returns a page of the documents in the collection matching the filter, sorted by sort, paginated by skip
+func findPage[T any](c context.Context, collection string, filter, sort bson.D, pagination Pagination) (*AggResult[T], error) {
+	if pagination.Limit <= 0 {
+		pagination.Limit = DefaultPageLimit
+	} else if pagination.Limit > MaxAggregationLimit {
+		pagination.Limit = MaxAggregationLimit
+	}
+	readCollection := mongo.GetReadCollection(collection)
+	total, err := readCollection.CountDocuments(c, filter)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := readCollection.Find(c, filter, options.Find().
+		SetSort(sort).
+		SetSkip(int64(pagination.Skip)).
+		SetLimit(int64(pagination.Limit)))
+	if err != nil {
+		return nil, err
+	}
+	page := &AggResult[T]{
+		Metadata: Metadata{Total: int(total), Limit: pagination.Limit},
+		Results:  []T{},
+	}
+	if err := cur.All(c, &page.Results); err != nil {
+		return nil, err
+	}
+	if pagination.Skip+len(page.Results) < int(total) {
+		page.Metadata.NextSkip = pagination.Skip + pagination.Limit
+	}
+	return page, nil
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"config-service/utils/consts"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// documents revision is incremented by every update, documents that were never updated have no revision field and are in revision 0
+
+// AnyRevision is used for updates and deletes that are not conditional on the document revision
+const AnyRevision int64 = -1
+
+// DocVersion identifies a revision of a document
+type DocVersion struct {
+	ID       string
+	Revision int64
+}
+
+// RevisionCondition returns the condition matching documents in the given revision
+func RevisionCondition(revision int64) bson.D {
+	if revision == 0 {
+		return bson.D{{Key: consts.RevisionField, Value: bson.D{{Key: "$exists", Value: false}}}}
+	}
+	return bson.D{{Key: consts.RevisionField, Value: revision}}
+}
+
+// inRevision returns the filter matching the version of the document only if it also matches the given filter
+func inRevision(filter bson.D, version DocVersion) bson.D {
+	return bson.D{{Key: "$and", Value: bson.A{filter, bson.D{{Key: consts.IdField, Value: version.ID}}, RevisionCondition(version.Revision)}}}
+}
+
+// withRevisionIncrement returns the update command with increment of the document revision
+func withRevisionIncrement(update bson.D) bson.D {
+	inc := bson.E{Key: consts.RevisionField, Value: 1}
+	result := bson.D{}
+	incremented := false
+	for _, e := range update {
+		if e.Key == "$inc" {
+			if incFields, ok := e.Value.(bson.D); ok {
+				e.Value = append(append(bson.D{}, incFields...), inc)
+				incremented = true
+			}
+		}
+		result = append(result, e)
+	}
+	if !incremented {
+		result = append(result, bson.E{Key: "$inc", Value: bson.D{inc}})
+	}
+	return result
+}
+
+// withRevisionProjection adds the revision to projections that include specific fields
+func withRevisionProjection(projection bson.D) bson.D {
+	for _, e := range projection {
+		if e.Value == 1 {
+			return append(append(bson.D{}, projection...), bson.E{Key: consts.RevisionField, Value: 1})
+		}
+	}
+	return projection
+}
+
+// versionOf returns the id and revision of the raw document
+func versionOf(raw bson.Raw) (DocVersion, error) {
+	id, err := idOf(raw)
+	if err != nil {
+		return DocVersion{}, err
+	}
+	revision, _ := raw.Lookup(consts.RevisionField).AsInt64OK()
+	return DocVersion{ID: id, Revision: revision}, nil
+}
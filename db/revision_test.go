@@ -0,0 +1,80 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestWithRevisionIncrement(t *testing.T) {
+	tests := []struct {
+		name   string
+		update bson.D
+		want   bson.D
+	}{
+		{
+			name:   "add increment",
+			update: bson.D{{Key: "$set", Value: bson.M{"name": "a"}}},
+			want:   bson.D{{Key: "$set", Value: bson.M{"name": "a"}}, {Key: "$inc", Value: bson.D{{Key: "revision", Value: 1}}}},
+		},
+		{
+			name:   "merge with existing increment",
+			update: bson.D{{Key: "$inc", Value: bson.D{{Key: "count", Value: 2}}}},
+			want:   bson.D{{Key: "$inc", Value: bson.D{{Key: "count", Value: 2}, {Key: "revision", Value: 1}}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := withRevisionIncrement(tt.update); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("withRevisionIncrement() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionOf(t *testing.T) {
+	for _, revision := range []interface{}{nil, int32(3), int64(3)} {
+		doc := bson.M{"_id": "id"}
+		want := DocVersion{ID: "id"}
+		if revision != nil {
+			doc["revision"] = revision
+			want.Revision = 3
+		}
+		raw, err := bson.Marshal(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := versionOf(raw); err != nil || got != want {
+			t.Errorf("versionOf(%v) = %v, %v, want %v", doc, got, err, want)
+		}
+	}
+}
+
+func TestInRevision(t *testing.T) {
+	filter := bson.D{{Key: "customers", Value: "customer"}}
+	tests := []struct {
+		name    string
+		version DocVersion
+		want    bson.D
+	}{
+		{
+			name:    "never updated",
+			version: DocVersion{ID: "id"},
+			want: bson.D{{Key: "$and", Value: bson.A{filter, bson.D{{Key: "_id", Value: "id"}},
+				bson.D{{Key: "revision", Value: bson.D{{Key: "$exists", Value: false}}}}}}},
+		},
+		{
+			name:    "updated",
+			version: DocVersion{ID: "id", Revision: 2},
+			want:    bson.D{{Key: "$and", Value: bson.A{filter, bson.D{{Key: "_id", Value: "id"}}, bson.D{{Key: "revision", Value: int64(2)}}}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inRevision(filter, tt.version); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("inRevision() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package db
+
+import (
+	"config-service/db/mongo"
+	"config-service/utils/consts"
+	"config-service/utils/log"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"go.mongodb.org/mongo-driver/bson"
+	mongoDB "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// deleted documents are marked with the deleted field and the deletion time, they are hidden from all reads and updates
+// until they are restored or purged after the retention period
+
+// softDeleteCommand returns the update command that marks a document as deleted
+func softDeleteCommand(deletedTime time.Time) bson.D {
+	return withRevisionIncrement(bson.D{{Key: "$set", Value: bson.D{
+		{Key: consts.DeletedField, Value: true},
+		{Key: consts.DeletedTimeField, Value: deletedTime.UTC()},
+	}}})
+}
+
+// restoreCommand returns the update command that restores a deleted document
+// update holds the changes to apply to the document content together with the restore, it may be nil
+func restoreCommand(update bson.D) bson.D {
+	return withRevisionIncrement(append(append(bson.D{}, update...), bson.E{Key: "$unset", Value: bson.D{
+		{Key: consts.DeletedField, Value: ""},
+		{Key: consts.DeletedTimeField, Value: ""},
+	}}))
+}
+
+// withListScope scopes the filter of list requests to the customer documents
+// requests that list deleted documents (consts.ListDeleted is set in the context) are scoped to the customer deleted documents without the global documents
+func withListScope(c context.Context, filterBuilder *FilterBuilder, includeGlobals bool) {
+	if listDeleted, _ := c.Value(consts.ListDeleted).(bool); listDeleted {
+		filterBuilder.WithCustomer(c).WithDeleted()
+	} else if includeGlobals {
+		filterBuilder.WithNotDeleteForCustomerAndGlobal(c)
+	} else {
+		filterBuilder.WithNotDeleteForCustomer(c)
+	}
+}
+
+// FindDeletedForCustomer returns the customer deleted docs matching the filter, findOpts may set the projection and sort of the results
+func FindDeletedForCustomer[T any](c context.Context, filterBuilder *FilterBuilder, findOpts *options.FindOptions) ([]T, error) {
+	defer log.LogNTraceEnterExit("FindDeletedForCustomer", c)()
+	collection, _, err := ReadContext(c)
+	if err != nil {
+		return nil, err
+	}
+	if filterBuilder == nil {
+		filterBuilder = NewFilterBuilder()
+	}
+	result := []T{}
+	cur, err := mongo.GetReadCollection(collection).Find(c, filterBuilder.WithCustomer(c).WithDeleted().Get(), findOpts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cur.All(c, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RestoreByGUID restores the deleted customer document with the GUID and returns it, nil if there is no such deleted document
+// update holds the changes to apply to the document content together with the restore, it may be nil
+func RestoreByGUID[T any](c context.Context, guid string, update bson.D) (*T, error) {
+	defer log.LogNTraceEnterExit("RestoreByGUID", c)()
+	collection, _, err := ReadContext(c)
+	if err != nil {
+		return nil, err
+	}
+	var restoredDoc T
+	filter := NewFilterBuilder().WithCustomer(c).WithDeleted().WithGUID(guid).Get()
+	if err := mongo.GetWriteCollection(collection).FindOneAndUpdate(c, filter, restoreCommand(update),
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).
+		Decode(&restoredDoc); err != nil {
+		if err == mongoDB.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &restoredDoc, nil
+}
+
+// AdminPurgeDeletedDocs removes the documents of all customers in all collections that were deleted before the given time with their history
+func AdminPurgeDeletedDocs(c context.Context, deletedBefore time.Time) (purgedCount int64, err error) {
+	defer log.LogNTraceEnterExit("AdminPurgeDeletedDocs", c)()
+	collections, err := mongo.ListCollectionNames(c)
+	if err != nil {
+		return 0, err
+	}
+	filter := NewFilterBuilder().
+		WithDeleted().
+		WithValue(consts.DeletedTimeField, bson.D{{Key: "$lt", Value: deletedBefore.UTC()}}).
+		Get()
+	var purgeErrs error
+	for _, collection := range collections {
+		if strings.HasSuffix(collection, historyCollectionSuffix) {
+			continue
+		}
+		purged, err := purgeCollection(c, collection, filter)
+		if err != nil {
+			log.LogNTraceError(fmt.Sprintf("AdminPurgeDeletedDocs errors when purging documents in collection:%s", collection), err, c)
+			purgeErrs = multierror.Append(purgeErrs, err)
+		}
+		if purged > 0 {
+			purgedCount += purged
+			log.LogNTrace(fmt.Sprintf("AdminPurgeDeletedDocs purged %d documents in collection:%s", purged, collection), c)
+		}
+	}
+	return purgedCount, purgeErrs
+}
+
+// purgeCollection removes the deleted documents matching the filter and their history
+func purgeCollection(c context.Context, collection string, filter bson.D) (int64, error) {
+	cur, err := mongo.GetWriteCollection(collection).Find(c, filter, options.Find().SetProjection(NewProjectionBuilder().Include(consts.IdField).Get()))
+	if err != nil {
+		return 0, err
+	}
+	var docs []struct {
+		ID string `bson:"_id"`
+	}
+	if err := cur.All(c, &docs); err != nil || len(docs) == 0 {
+		return 0, err
+	}
+	ids := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		ids = append(ids, doc.ID)
+	}
+	res, err := mongo.GetWriteCollection(collection).DeleteMany(c, NewFilterBuilder().WithIDs(ids).WithFilter(filter).Get())
+	if err != nil {
+		return 0, err
+	}
+	_, err = mongo.GetWriteCollection(HistoryCollection(collection)).DeleteMany(c, NewFilterBuilder().WithIn(consts.GUIDField, ids).Get())
+	return res.DeletedCount, err
+}
+
+// StartPurgeJob purges the documents deleted more than retention ago every interval until the returned stop function is called
+func StartPurgeJob(retention, interval time.Duration) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				purged, err := AdminPurgeDeletedDocs(ctx, time.Now().Add(-retention))
+				if err != nil {
+					zap.L().Error("purge job failed", zap.Int64("purged", purged), zap.Error(err))
+				} else if purged > 0 {
+					zap.L().Info("purge job completed", zap.Int64("purged", purged))
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
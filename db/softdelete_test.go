@@ -0,0 +1,51 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestSoftDeleteCommands(t *testing.T) {
+	deletedTime := time.Date(2023, 1, 2, 3, 4, 5, 0, time.FixedZone("", 3600))
+	tests := []struct {
+		name string
+		got  bson.D
+		want bson.D
+	}{
+		{
+			name: "delete",
+			got:  softDeleteCommand(deletedTime),
+			want: bson.D{
+				{Key: "$set", Value: bson.D{{Key: "is_deleted", Value: true}, {Key: "deletedTime", Value: deletedTime.UTC()}}},
+				{Key: "$inc", Value: bson.D{{Key: "revision", Value: 1}}},
+			},
+		},
+		{
+			name: "restore",
+			got:  restoreCommand(nil),
+			want: bson.D{
+				{Key: "$unset", Value: bson.D{{Key: "is_deleted", Value: ""}, {Key: "deletedTime", Value: ""}}},
+				{Key: "$inc", Value: bson.D{{Key: "revision", Value: 1}}},
+			},
+		},
+		{
+			name: "restore with changes",
+			got:  restoreCommand(bson.D{{Key: "$set", Value: bson.M{"attributes.alias": "ABC"}}}),
+			want: bson.D{
+				{Key: "$set", Value: bson.M{"attributes.alias": "ABC"}},
+				{Key: "$unset", Value: bson.D{{Key: "is_deleted", Value: ""}, {Key: "deletedTime", Value: ""}}},
+				{Key: "$inc", Value: bson.D{{Key: "revision", Value: 1}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
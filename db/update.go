@@ -2,6 +2,7 @@ package db
 
 import (
 	"config-service/types"
+	"reflect"
 	"strings"
 
 	"github.com/chidiwilliams/flatbson"
@@ -18,7 +19,53 @@ func GetUpdateDocCommand[T types.DocContent](i T, includeFields []string, exclud
 	if err != nil {
 		return nil, err
 	}
+	filterUpdateFields(m, includeFields, excludeFields)
+	if len(m) == 0 {
+		return nil, NoFieldsToUpdateError{}
+	}
+	return bson.D{bson.E{Key: "$set", Value: m}}, nil
+}
+
+// GetMergeUpdateCommand creates update command that sets the fields changed in newDoc and unsets the fields removed from oldDoc
+// if includeFields is not empty, only the fields in the list will be included
+func GetMergeUpdateCommand[T types.DocContent](oldDoc, newDoc T, includeFields []string, excludeFields ...string) (bson.D, error) {
+	oldM, err := flatbson.Flatten(oldDoc)
+	if err != nil {
+		return nil, err
+	}
+	newM, err := flatbson.Flatten(newDoc)
+	if err != nil {
+		return nil, err
+	}
+	filterUpdateFields(oldM, includeFields, excludeFields)
+	filterUpdateFields(newM, includeFields, excludeFields)
+	set := bson.M{}
+	for k, v := range newM {
+		if oldV, ok := oldM[k]; !ok || !reflect.DeepEqual(oldV, v) {
+			set[k] = v
+		}
+	}
+	unset := bson.M{}
+	for k := range oldM {
+		if _, ok := newM[k]; !ok {
+			unset[k] = ""
+		}
+	}
+	update := bson.D{}
+	if len(set) > 0 {
+		update = append(update, bson.E{Key: "$set", Value: set})
+	}
+	if len(unset) > 0 {
+		update = append(update, bson.E{Key: "$unset", Value: unset})
+	}
+	if len(update) == 0 {
+		return nil, NoFieldsToUpdateError{}
+	}
+	return update, nil
+}
 
+// filterUpdateFields removes the excluded fields and, if includeFields is not empty, the fields that are not included
+func filterUpdateFields(m map[string]interface{}, includeFields []string, excludeFields []string) {
 	for _, f := range excludeFields {
 		delete(m, f)
 	}
@@ -36,10 +83,6 @@ func GetUpdateDocCommand[T types.DocContent](i T, includeFields []string, exclud
 			}
 		}
 	}
-	if len(m) == 0 {
-		return nil, NoFieldsToUpdateError{}
-	}
-	return bson.D{bson.E{Key: "$set", Value: m}}, nil
 }
 
 func GetUpdateAddToSetCommand(arrayFieldName string, value interface{}) bson.D {
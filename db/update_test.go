@@ -0,0 +1,57 @@
+package db
+
+import (
+	"config-service/types"
+	"reflect"
+	"testing"
+
+	"github.com/armosec/armoapi-go/armotypes"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestGetMergeUpdateCommand(t *testing.T) {
+	oldDoc := &types.Cluster{PortalBase: armotypes.PortalBase{GUID: "guid", Name: "name", UpdatedTime: "old", Attributes: map[string]interface{}{"a": "b"}}}
+	tests := []struct {
+		name          string
+		newDoc        *types.Cluster
+		includeFields []string
+		want          bson.D
+		wantErr       bool
+	}{
+		{
+			name:   "set changed fields",
+			newDoc: &types.Cluster{PortalBase: armotypes.PortalBase{GUID: "guid", Name: "name", UpdatedTime: "new", Attributes: map[string]interface{}{"a": "c"}}},
+			want:   bson.D{{Key: "$set", Value: bson.M{"updatedTime": "new", "attributes": map[string]interface{}{"a": "c"}}}},
+		},
+		{
+			name:   "unset removed fields",
+			newDoc: &types.Cluster{PortalBase: armotypes.PortalBase{GUID: "guid", Name: "name", UpdatedTime: "new"}},
+			want:   bson.D{{Key: "$set", Value: bson.M{"updatedTime": "new"}}, {Key: "$unset", Value: bson.M{"attributes": ""}}},
+		},
+		{
+			name:    "read only fields",
+			newDoc:  &types.Cluster{PortalBase: armotypes.PortalBase{GUID: "guid", Name: "other", UpdatedTime: "old", Attributes: map[string]interface{}{"a": "b"}}},
+			wantErr: true,
+		},
+		{
+			name:          "included fields",
+			newDoc:        &types.Cluster{PortalBase: armotypes.PortalBase{GUID: "guid", Name: "name", UpdatedTime: "new"}},
+			includeFields: []string{"updatedTime"},
+			want:          bson.D{{Key: "$set", Value: bson.M{"updatedTime": "new"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetMergeUpdateCommand(oldDoc, tt.newDoc, tt.includeFields, oldDoc.GetReadOnlyFields()...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetMergeUpdateCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !IsNoFieldsToUpdateError(err) {
+				t.Errorf("GetMergeUpdateCommand() error = %v, want no fields to update", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetMergeUpdateCommand() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 	"go.mongodb.org/mongo-driver/bson"
@@ -28,33 +29,25 @@ func GetAllForCustomer[T any](c context.Context, includeGlobals bool) ([]T, erro
 // GetAllForCustomerWithProjection returns all docs for customer with projection
 func GetAllForCustomerWithProjection[T any](c context.Context, projection bson.D, includeGlobals bool) ([]T, error) {
 	defer log.LogNTraceEnterExit("GetAllForCustomerWithProjection", c)()
-	collection, _, err := ReadContext(c)
-	result := []T{}
-	if err != nil {
-		return nil, err
-	}
-	fb := NewFilterBuilder()
-	if includeGlobals {
-		fb.WithNotDeleteForCustomerAndGlobal(c)
-	} else {
-		fb.WithNotDeleteForCustomer(c)
-	}
-	filter := fb.Get()
-	findOpts := options.Find().SetNoCursorTimeout(true)
+	findOpts := options.Find()
 	if projection != nil {
 		findOpts.SetProjection(projection)
 	}
-	if cur, err := mongo.GetReadCollection(collection).
-		Find(c, filter, findOpts); err != nil {
-		return nil, err
-	} else if err := cur.All(c, &result); err != nil {
-		return nil, err
-	}
-	return result, nil
+	return FindForCustomerWithOptions[T](c, nil, includeGlobals, findOpts)
 }
 
 func FindForCustomer[T any](c context.Context, filterBuilder *FilterBuilder, projection bson.D) ([]T, error) {
 	defer log.LogNTraceEnterExit("FindForCustomer", c)()
+	findOpts := options.Find()
+	if projection != nil {
+		findOpts.SetProjection(projection)
+	}
+	return FindForCustomerWithOptions[T](c, filterBuilder, false, findOpts)
+}
+
+// FindForCustomerWithOptions returns the customer docs matching the filter, findOpts may set the projection and sort of the results
+func FindForCustomerWithOptions[T any](c context.Context, filterBuilder *FilterBuilder, includeGlobals bool, findOpts *options.FindOptions) ([]T, error) {
+	defer log.LogNTraceEnterExit("FindForCustomerWithOptions", c)()
 	collection, _, err := ReadContext(c)
 	result := []T{}
 	if err != nil {
@@ -63,59 +56,78 @@ func FindForCustomer[T any](c context.Context, filterBuilder *FilterBuilder, pro
 	if filterBuilder == nil {
 		filterBuilder = NewFilterBuilder()
 	}
-	filter := filterBuilder.WithNotDeleteForCustomer(c).Get()
-	findOpts := options.Find().SetNoCursorTimeout(true)
-	if projection != nil {
-		findOpts.SetProjection(projection)
+	withListScope(c, filterBuilder, includeGlobals)
+	if findOpts == nil {
+		findOpts = options.Find()
 	}
+	findOpts.SetNoCursorTimeout(true)
 	if cur, err := mongo.GetReadCollection(collection).
-		Find(c, filter, findOpts); err != nil {
+		Find(c, filterBuilder.Get(), findOpts); err != nil {
+		return nil, err
+	} else if err := cur.All(c, &result); err != nil {
 		return nil, err
-	} else {
-
-		if err := cur.All(c, &result); err != nil {
-			return nil, err
-		}
 	}
 	return result, nil
 }
 
 // UpdateDocument updates document by GUID and update command
 func UpdateDocument[T any](c context.Context, id string, update bson.D) ([]T, error) {
-	defer log.LogNTraceEnterExit("UpdateDocument", c)()
+	return UpdateDocumentInRevision[T](c, id, AnyRevision, update)
+}
+
+// UpdateDocumentInRevision updates document by GUID and update command only if the document is in the given revision
+// returns the old and the updated documents, nil if the document does not exist and ErrUpdateConflict if it is in another revision
+func UpdateDocumentInRevision[T any](c context.Context, id string, revision int64, update bson.D) ([]T, error) {
+	defer log.LogNTraceEnterExit("UpdateDocumentInRevision", c)()
 	collection, _, err := ReadContext(c)
 	if err != nil {
 		return nil, err
 	}
-	var oldDoc T
-	if err := mongo.GetReadCollection(collection).
-		FindOne(c,
-			NewFilterBuilder().
-				WithNotDeleteForCustomer(c).
-				WithID(id).
-				Get()).
-		Decode(&oldDoc); err != nil {
-		if err == mongoDB.ErrNoDocuments {
-			return nil, nil
+	filterBuilder := NewFilterBuilder().WithNotDeleteForCustomer(c).WithID(id)
+	if revision != AnyRevision {
+		filterBuilder.WithFilter(RevisionCondition(revision))
+	}
+	docs, err := updateOne[T](c, collection, filterBuilder.Get(), withRevisionIncrement(update))
+	if err != nil {
+		return nil, err
+	} else if docs == nil && revision != AnyRevision {
+		//the document is in another revision if it exists
+		if exists, err := DocExist(c, NewFilterBuilder().WithID(id).Get()); err != nil {
+			return nil, err
+		} else if exists {
+			return nil, ErrUpdateConflict
 		}
-		log.LogNTraceError("failed to get document by id", err, c)
+	}
+	return docs, nil
+}
+
+var ErrUpdateConflict = errors.New("document was modified")
+
+// UpdateDocumentWithConditions updates the customer document only if it matches the conditions and returns the updated document
+// returns ErrUpdateConflict if the document does not exist or does not match the conditions
+func UpdateDocumentWithConditions[T any](c context.Context, id string, conditions bson.D, update bson.D) (*T, error) {
+	defer log.LogNTraceEnterExit("UpdateDocumentWithConditions", c)()
+	collection, _, err := ReadContext(c)
+	if err != nil {
 		return nil, err
 	}
-	var newDoc T
-	filter := NewFilterBuilder().WithNotDeleteForCustomer(c).WithID(id).Get()
-	if err := mongo.GetWriteCollection(collection).FindOneAndUpdate(c, filter, update,
-		options.FindOneAndUpdate().SetReturnDocument(options.After)).
-		Decode(&newDoc); err != nil {
+	filter := NewFilterBuilder().WithNotDeleteForCustomer(c).WithID(id).WithFilter(conditions).Get()
+	docs, err := updateOne[T](c, collection, filter, withRevisionIncrement(update))
+	if err != nil {
 		return nil, err
+	} else if docs == nil {
+		return nil, ErrUpdateConflict
 	}
-	return []T{oldDoc, newDoc}, nil
+	return &docs[1], nil
 }
 
-func AddToArray(c context.Context, id string, arrayPath string, value interface{}) (modified int64, err error) {
+// AddToArray adds the value to the array of the document if it is not in the array
+// returns the old and the updated documents, nil if the document does not exist or the value is already in the array
+func AddToArray[T any](c context.Context, id string, arrayPath string, value interface{}) ([]T, error) {
 	defer log.LogNTraceEnterExit("AddToArray", c)()
 	collection, _, err := ReadContext(c)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 	//filter documents that already have this value in the array
 	filter := NewFilterBuilder().
@@ -123,41 +135,52 @@ func AddToArray(c context.Context, id string, arrayPath string, value interface{
 		WithNotDeleteForCustomer(c).WithID(id).
 		Get()
 
-	update := GetUpdateAddToSetCommand(arrayPath, value)
-	res, err := mongo.GetWriteCollection(collection).UpdateOne(c, filter, update)
-	if res != nil {
-		modified = res.ModifiedCount
-	}
-	return modified, err
+	update := withRevisionIncrement(GetUpdateAddToSetCommand(arrayPath, value))
+	return updateOne[T](c, collection, filter, update)
 }
 
-func UpdateOne(c context.Context, id string, update interface{}) (modified int64, err error) {
-	defer log.LogNTraceEnterExit("AddToArray", c)()
+// UpdateOne updates the document by the update command
+// returns the old and the updated documents, nil if the document does not exist
+func UpdateOne[T any](c context.Context, id string, update bson.D) ([]T, error) {
+	defer log.LogNTraceEnterExit("UpdateOne", c)()
 	collection, _, err := ReadContext(c)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 	filterBuilder := NewFilterBuilder().WithNotDeleteForCustomer(c).WithID(id)
-	res, err := mongo.GetWriteCollection(collection).UpdateOne(c, filterBuilder.Get(), update)
-	if res != nil {
-		modified = res.ModifiedCount
-	}
-	return modified, err
+	return updateOne[T](c, collection, filterBuilder.Get(), withRevisionIncrement(update))
 }
 
-func PullFromArray(c context.Context, id string, arrayPath string, value interface{}) (modified int64, err error) {
+// PullFromArray removes the value from the array of the document
+// returns the old and the updated documents, nil if the document does not exist or the value is not in the array
+func PullFromArray[T any](c context.Context, id string, arrayPath string, value interface{}) ([]T, error) {
 	defer log.LogNTraceEnterExit("PullFromArray", c)()
 	collection, _, err := ReadContext(c)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	filterBuilder := NewFilterBuilder().WithNotDeleteForCustomer(c).WithID(id)
-	update := GetUpdatePullFromSetCommand(arrayPath, value)
-	res, err := mongo.GetWriteCollection(collection).UpdateOne(c, filterBuilder.Get(), update)
-	if res != nil {
-		modified = res.ModifiedCount
+	//filter documents that have this value in the array
+	filterBuilder := NewFilterBuilder().
+		WithElementMatch(value).WarpWithField(arrayPath).
+		WithNotDeleteForCustomer(c).WithID(id)
+	update := withRevisionIncrement(GetUpdatePullFromSetCommand(arrayPath, value))
+	return updateOne[T](c, collection, filterBuilder.Get(), update)
+}
+
+// updateOne updates the document matching the filter and returns the old and the updated documents, nil if no document matches the filter
+func updateOne[T any](c context.Context, collection string, filter bson.D, update bson.D) ([]T, error) {
+	before, after, err := updateWithHistory(c, collection, filter, update, HistoryOperationUpdate)
+	if err != nil || before == nil {
+		return nil, err
+	}
+	var oldDoc, newDoc T
+	if err := bson.Unmarshal(before, &oldDoc); err != nil {
+		return nil, err
+	}
+	if err := bson.Unmarshal(after, &newDoc); err != nil {
+		return nil, err
 	}
-	return modified, err
+	return []T{oldDoc, newDoc}, nil
 }
 
 // DocExist returns true if at least one document with given filter exists
@@ -208,6 +231,47 @@ func GetDocByGUID[T any](c context.Context, guid string) (*T, error) {
 	return &result, nil
 }
 
+// FindOneForCustomer returns the customer document matching the filter, projection may limit the returned fields
+func FindOneForCustomer[T any](c context.Context, filterBuilder *FilterBuilder, projection bson.D) (*T, error) {
+	result, _, err := FindOneForCustomerWithVersion[T](c, filterBuilder, projection)
+	return result, err
+}
+
+// FindOneForCustomerWithVersion returns the customer document matching the filter and its version, projection may limit the returned fields
+func FindOneForCustomerWithVersion[T any](c context.Context, filterBuilder *FilterBuilder, projection bson.D) (*T, *DocVersion, error) {
+	defer log.LogNTraceEnterExit("FindOneForCustomerWithVersion", c)()
+	collection, _, err := ReadContext(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	if filterBuilder == nil {
+		filterBuilder = NewFilterBuilder()
+	}
+	findOpts := options.FindOne()
+	if projection != nil {
+		findOpts.SetProjection(withRevisionProjection(projection))
+	}
+	raw, err := mongo.GetReadCollection(collection).
+		FindOne(c, filterBuilder.WithNotDeleteForCustomer(c).Get(), findOpts).
+		DecodeBytes()
+	if err != nil {
+		if err == mongoDB.ErrNoDocuments {
+			return nil, nil, nil
+		}
+		log.LogNTraceError("failed to find document", err, c)
+		return nil, nil, err
+	}
+	var result T
+	if err := bson.Unmarshal(raw, &result); err != nil {
+		return nil, nil, err
+	}
+	version, err := versionOf(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &result, &version, nil
+}
+
 // GetDo returns document by given filter
 func GetDoc[T any](c context.Context, filter *FilterBuilder) (*T, error) {
 	defer log.LogNTraceEnterExit("GetDoc", c)()
@@ -310,58 +374,90 @@ func InsertDocuments[T types.DocContent](c context.Context, docs []T) ([]T, erro
 }
 
 func DeleteByName[T types.DocContent](c context.Context, name string) (deletedDoc *T, err error) {
-	defer log.LogNTraceEnterExit("DeleteByName", c)()
-	collection, err := readCollection(c)
-	if err != nil {
-		return nil, err
-	}
-	toBeDeleted, err := GetDocByName[T](c, name)
-	if err != nil {
-		return nil, err
-	} else if toBeDeleted == nil {
-		return nil, nil
-	}
+	return DeleteByNameInRevision[T](c, name, AnyRevision)
+}
 
-	if res, err := mongo.GetWriteCollection(collection).DeleteOne(c, bson.M{consts.IdField: (*toBeDeleted).GetGUID()}); err != nil {
-		return nil, err
-	} else if res.DeletedCount == 0 {
-		return nil, nil
-	}
-	return toBeDeleted, nil
+// DeleteByNameInRevision marks the document with the name as deleted only if it is in the given revision
+// returns nil if the document does not exist and ErrUpdateConflict if it is in another revision
+func DeleteByNameInRevision[T types.DocContent](c context.Context, name string, revision int64) (deletedDoc *T, err error) {
+	defer log.LogNTraceEnterExit("DeleteByName", c)()
+	return deleteOne[T](c, NewFilterBuilder().WithName(name), revision)
 }
 
 func DeleteByGUID[T types.DocContent](c context.Context, guid string) (deletedDoc *T, err error) {
+	return DeleteByGUIDInRevision[T](c, guid, AnyRevision)
+}
+
+// DeleteByGUIDInRevision marks the document with the GUID as deleted only if it is in the given revision
+// returns nil if the document does not exist and ErrUpdateConflict if it is in another revision
+func DeleteByGUIDInRevision[T types.DocContent](c context.Context, guid string, revision int64) (deletedDoc *T, err error) {
 	defer log.LogNTraceEnterExit("DeleteByGUID", c)()
+	return deleteOne[T](c, NewFilterBuilder().WithGUID(guid), revision)
+}
+
+func deleteOne[T types.DocContent](c context.Context, filterBuilder *FilterBuilder, revision int64) (deletedDoc *T, err error) {
 	collection, err := readCollection(c)
 	if err != nil {
 		return nil, err
 	}
-	toBeDeleted, err := GetDocByGUID[T](c, guid)
+	docFilter := append(bson.D{}, filterBuilder.Get()...)
+	filterBuilder.WithNotDeleteForCustomer(c)
+	if revision != AnyRevision {
+		filterBuilder.WithFilter(RevisionCondition(revision))
+	}
+	raw, err := findOneAndUpdateWithHistory(c, collection, filterBuilder.Get(), softDeleteCommand(time.Now()), HistoryOperationDelete)
 	if err != nil {
 		return nil, err
-	} else if toBeDeleted == nil {
+	} else if raw == nil {
+		if revision != AnyRevision {
+			//the document is in another revision if it exists
+			if exists, err := DocExist(c, docFilter); err != nil {
+				return nil, err
+			} else if exists {
+				return nil, ErrUpdateConflict
+			}
+		}
 		return nil, nil
 	}
-	if res, err := mongo.GetWriteCollection(collection).DeleteOne(c, bson.M{consts.IdField: guid}); err != nil {
+	var result T
+	if err := bson.Unmarshal(raw, &result); err != nil {
 		return nil, err
-	} else if res.DeletedCount == 0 {
-		return nil, nil
 	}
-	return toBeDeleted, nil
+	return &result, nil
 }
 
-func BulkDeleteByName[T types.DocContent](c context.Context, names []string) (deletedCount int64, err error) {
+// BulkDeleteByName marks the documents with the names as deleted and returns the deleted documents
+// the documents are read first and only the read documents are deleted
+func BulkDeleteByName[T types.DocContent](c context.Context, names []string) ([]T, error) {
 	defer log.LogNTraceEnterExit("BulkDeleteByName", c)()
 	collection, err := readCollection(c)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	filter := NewFilterBuilder().WithIn("name", names).WithNotDeleteForCustomer(c)
-	if res, err := mongo.GetWriteCollection(collection).DeleteMany(c, filter.Get()); err != nil {
-		return 0, err
-	} else {
-		return res.DeletedCount, nil
+	raws, err := FindForCustomer[bson.Raw](c, NewFilterBuilder().WithIn("name", names), nil)
+	if err != nil || len(raws) == 0 {
+		return nil, err
 	}
+	ids := make([]string, 0, len(raws))
+	deletedDocs := make([]T, 0, len(raws))
+	for _, raw := range raws {
+		id, err := idOf(raw)
+		if err != nil {
+			return nil, err
+		}
+		var doc T
+		if err := bson.Unmarshal(raw, &doc); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+		deletedDocs = append(deletedDocs, doc)
+		saveHistory(c, collection, raw, HistoryOperationDelete)
+	}
+	filter := NewFilterBuilder().WithIn(consts.IdField, ids).WithNotDeleteForCustomer(c)
+	if _, err := mongo.GetWriteCollection(collection).UpdateMany(c, filter.Get(), softDeleteCommand(time.Now())); err != nil {
+		return nil, err
+	}
+	return deletedDocs, nil
 }
 
 func DeleteCustomerDocs(c context.Context) (deletedCount int64, err error) {
@@ -436,6 +532,30 @@ func AdminDeleteCustomersDocs(c context.Context, customerGUIDs ...string) (delet
 	return atomic.LoadInt64(&deletedCount), deletionErrs
 }
 
+// GetDocWithOwnersFromCollection returns the document that matches the filter in the given collection and its owners (customers)
+// the customer in context is not used, callers are responsible for authorization
+func GetDocWithOwnersFromCollection[T any](c context.Context, collection string, filter *FilterBuilder) (doc *T, customers []string, err error) {
+	defer log.LogNTraceEnterExit("GetDocWithOwnersFromCollection", c)()
+	raw, err := mongo.GetReadCollection(collection).FindOne(c, filter.WithNotDeleted().Get()).DecodeBytes()
+	if err != nil {
+		if err == mongoDB.ErrNoDocuments {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	var result T
+	if err := bson.Unmarshal(raw, &result); err != nil {
+		return nil, nil, err
+	}
+	owners := struct {
+		Customers []string `bson:"customers"`
+	}{}
+	if err := bson.Unmarshal(raw, &owners); err != nil {
+		return nil, nil, err
+	}
+	return &result, owners.Customers, nil
+}
+
 // helpers
 
 // ReadContext reads collection and customerGUID from context
@@ -0,0 +1,302 @@
+package db
+
+import (
+	"config-service/db/mongo"
+	"config-service/types"
+	"config-service/utils/consts"
+	"config-service/utils/log"
+	"context"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	mongoDB "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"k8s.io/utils/strings/slices"
+)
+
+// documents changes are watched with mongo change streams when the database is a replica set,
+// otherwise with an in-process event bus that sees only the changes published by this instance
+
+// change event types
+const (
+	ChangeEventCreate = "create" //created or restored document
+	ChangeEventUpdate = "update"
+	ChangeEventDelete = "delete"
+)
+
+const (
+	watchBufferSize      = 100  //events buffered per watcher, bus watchers that fall behind are closed
+	changeBusHistorySize = 1000 //last bus events kept to resume watchers
+)
+
+// mongo error codes of resume tokens that are invalid or no longer in the oplog
+const (
+	invalidResumeTokenErrorCode      = 260
+	changeStreamHistoryLostErrorCode = 286
+)
+
+var ErrInvalidResumeToken = errors.New("invalid or expired resume token")
+
+// ChangeEvent is a change of a customer document
+type ChangeEvent struct {
+	ID       string   //the event id, pass it as the resume token to watch the events after it
+	Type     string   //create, update or delete
+	GUID     string   //the changed document GUID
+	Document bson.Raw //the document after the change, or before a delete
+}
+
+// WatchForCustomer returns the changes of the customer documents in the context collection until the context is done
+// when resumeToken is set, the events after the event with this id are returned first
+func WatchForCustomer(c context.Context, resumeToken string) (<-chan ChangeEvent, error) {
+	defer log.LogNTraceEnterExit("WatchForCustomer", c)()
+	collection, _, err := ReadContext(c)
+	if err != nil {
+		return nil, err
+	}
+	filter := NewFilterBuilder().WithCustomer(c).Get()
+	if mongo.ChangeStreamsSupported() {
+		return watchChangeStream(c, collection, filter, resumeToken)
+	}
+	return changesBus.watch(c, collection, filter, resumeToken)
+}
+
+// PublishChange publishes the change of the customer document in the context collection to its watchers
+// doc is the document after the change, or before a delete. it is a no-op when the changes are watched with change streams
+func PublishChange[T types.DocContent](c context.Context, eventType string, doc T) error {
+	if mongo.ChangeStreamsSupported() {
+		return nil
+	}
+	collection, err := readCollection(c)
+	if err != nil {
+		return err
+	}
+	//customers are the owners of their own document
+	customerGUID, _ := c.Value(consts.CustomerGUID).(string)
+	if collection == consts.CustomersCollection {
+		customerGUID = doc.GetGUID()
+	}
+	raw, err := bson.Marshal(types.Document[T]{ID: doc.GetGUID(), Customers: []string{customerGUID}, Content: doc})
+	if err != nil {
+		return err
+	}
+	return changesBus.publish(collection, eventType, raw)
+}
+
+// changeStreamEvent is the change stream event fields used to create change events
+type changeStreamEvent struct {
+	ID            bson.Raw `bson:"_id"`
+	OperationType string   `bson:"operationType"`
+	DocumentKey   struct {
+		ID string `bson:"_id"`
+	} `bson:"documentKey"`
+	FullDocument      bson.Raw `bson:"fullDocument"`
+	UpdateDescription struct {
+		RemovedFields []string `bson:"removedFields"`
+	} `bson:"updateDescription"`
+}
+
+func watchChangeStream(c context.Context, collection string, filter bson.D, resumeToken string) (<-chan ChangeEvent, error) {
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if resumeToken != "" {
+		if _, err := hex.DecodeString(resumeToken); err != nil {
+			return nil, ErrInvalidResumeToken
+		}
+		opts.SetResumeAfter(bson.D{{Key: "_data", Value: resumeToken}})
+	}
+	pipeline := mongoDB.Pipeline{{{Key: "$match", Value: changeStreamMatch(filter)}}}
+	stream, err := mongo.GetWriteCollection(collection).Watch(c, pipeline, opts)
+	if err != nil {
+		var serverErr mongoDB.ServerError
+		if errors.As(err, &serverErr) && (serverErr.HasErrorCode(invalidResumeTokenErrorCode) || serverErr.HasErrorCode(changeStreamHistoryLostErrorCode)) {
+			return nil, ErrInvalidResumeToken
+		}
+		return nil, err
+	}
+	events := make(chan ChangeEvent, watchBufferSize)
+	go func() {
+		defer close(events)
+		defer stream.Close(context.Background())
+		for stream.Next(c) {
+			var streamEvent changeStreamEvent
+			if err := stream.Decode(&streamEvent); err != nil {
+				log.LogNTraceError("failed to decode change stream event", err, c)
+				continue
+			}
+			event, ok := newChangeEvent(streamEvent)
+			if !ok {
+				continue
+			}
+			select {
+			case events <- event:
+			case <-c.Done():
+				return
+			}
+		}
+		if err := stream.Err(); err != nil && c.Err() == nil {
+			log.LogNTraceError("change stream failed", err, c)
+		}
+	}()
+	return events, nil
+}
+
+// changeStreamMatch returns the change stream match stage of the changes of the documents matching the filter
+// soft deletes are updates so all the watched operations have the full document
+func changeStreamMatch(filter bson.D) bson.D {
+	match := bson.D{{Key: "operationType", Value: bson.D{{Key: "$in", Value: bson.A{"insert", "update", "replace"}}}}}
+	for _, e := range filter {
+		match = append(match, bson.E{Key: "fullDocument." + e.Key, Value: e.Value})
+	}
+	return match
+}
+
+// newChangeEvent returns the change event of the change stream event, false if the event is not a document change
+func newChangeEvent(streamEvent changeStreamEvent) (ChangeEvent, bool) {
+	event := ChangeEvent{GUID: streamEvent.DocumentKey.ID, Document: streamEvent.FullDocument}
+	if token, ok := streamEvent.ID.Lookup("_data").StringValueOK(); ok {
+		event.ID = token
+	}
+	switch streamEvent.OperationType {
+	case "insert":
+		event.Type = ChangeEventCreate
+	case "update", "replace":
+		if deleted, _ := streamEvent.FullDocument.Lookup(consts.DeletedField).BooleanOK(); deleted {
+			event.Type = ChangeEventDelete
+		} else if slices.Contains(streamEvent.UpdateDescription.RemovedFields, consts.DeletedField) {
+			event.Type = ChangeEventCreate
+		} else {
+			event.Type = ChangeEventUpdate
+		}
+	default:
+		return event, false
+	}
+	return event, len(streamEvent.FullDocument) > 0
+}
+
+// changesBus is the in-process event bus used when change streams are not supported
+var changesBus = newChangeBus(changeBusHistorySize)
+
+type changeBus struct {
+	mu          sync.Mutex
+	lastID      uint64
+	historySize int
+	history     []busEvent //the last published events, oldest first
+	watchers    map[*busWatcher]struct{}
+}
+
+type busEvent struct {
+	id         uint64
+	collection string
+	doc        bson.M
+	event      ChangeEvent
+}
+
+type busWatcher struct {
+	collection string
+	filter     bson.D
+	events     chan ChangeEvent
+}
+
+func newChangeBus(historySize int) *changeBus {
+	return &changeBus{historySize: historySize, watchers: map[*busWatcher]struct{}{}}
+}
+
+func (b *changeBus) publish(collection, eventType string, doc bson.Raw) error {
+	var docMap bson.M
+	if err := bson.Unmarshal(doc, &docMap); err != nil {
+		return err
+	}
+	guid, _ := docMap[consts.IdField].(string)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastID++
+	published := busEvent{
+		id:         b.lastID,
+		collection: collection,
+		doc:        docMap,
+		event:      ChangeEvent{ID: strconv.FormatUint(b.lastID, 10), Type: eventType, GUID: guid, Document: doc},
+	}
+	b.history = append(b.history, published)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history)-b.historySize:]
+	}
+	for watcher := range b.watchers {
+		if !watcher.matches(published) {
+			continue
+		}
+		select {
+		case watcher.events <- published.event:
+		default:
+			//the watcher fell behind, it can resume from its last event
+			delete(b.watchers, watcher)
+			close(watcher.events)
+		}
+	}
+	return nil
+}
+
+func (b *changeBus) watch(c context.Context, collection string, filter bson.D, resumeToken string) (<-chan ChangeEvent, error) {
+	watcher := &busWatcher{collection: collection, filter: filter}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	replay := []ChangeEvent{}
+	if resumeToken != "" {
+		afterID, err := strconv.ParseUint(resumeToken, 10, 64)
+		if err != nil || afterID > b.lastID || (afterID < b.lastID && afterID+1 < b.history[0].id) {
+			return nil, ErrInvalidResumeToken
+		}
+		for _, published := range b.history {
+			if published.id > afterID && watcher.matches(published) {
+				replay = append(replay, published.event)
+			}
+		}
+	}
+	watcher.events = make(chan ChangeEvent, len(replay)+watchBufferSize)
+	for _, event := range replay {
+		watcher.events <- event
+	}
+	b.watchers[watcher] = struct{}{}
+	go func() {
+		<-c.Done()
+		b.unwatch(watcher)
+	}()
+	return watcher.events, nil
+}
+
+func (b *changeBus) unwatch(watcher *busWatcher) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.watchers[watcher]; ok {
+		delete(b.watchers, watcher)
+		close(watcher.events)
+	}
+}
+
+// matches returns true if the event is in the watched collection and its document matches the watcher filter
+// only equality filters are supported (like the customer filter), array fields match if they contain the value
+func (w *busWatcher) matches(published busEvent) bool {
+	if published.collection != w.collection {
+		return false
+	}
+	for _, e := range w.filter {
+		value := published.doc[e.Key]
+		if values, ok := value.(bson.A); ok {
+			found := false
+			for _, v := range values {
+				if reflect.DeepEqual(v, e.Value) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		} else if !reflect.DeepEqual(value, e.Value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,149 @@
+package db
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestChangeBus(t *testing.T) {
+	bus := newChangeBus(3)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	publish := func(collection, guid, customer string) {
+		doc, err := bson.Marshal(bson.D{{Key: "_id", Value: guid}, {Key: "customers", Value: bson.A{customer}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := bus.publish(collection, ChangeEventUpdate, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	received := func(events <-chan ChangeEvent) (ids []string) {
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return append(ids, "closed")
+				}
+				ids = append(ids, event.ID+":"+event.GUID)
+			default:
+				return ids
+			}
+		}
+	}
+	customerFilter := bson.D{{Key: "customers", Value: "customer"}}
+
+	events, err := bus.watch(ctx, "clusters", customerFilter, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	publish("clusters", "a", "customer")
+	publish("clusters", "b", "other-customer")
+	publish("frameworks", "c", "customer")
+	publish("clusters", "d", "customer")
+	if got, want := received(events), []string{"1:a", "4:d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("watched events = %v, want %v", got, want)
+	}
+
+	//resume
+	tests := []struct {
+		resumeToken string
+		want        []string
+		wantErr     bool
+	}{
+		{resumeToken: "2", want: []string{"4:d"}},
+		{resumeToken: "1", want: []string{"4:d"}},
+		{resumeToken: "4", want: nil},
+		{resumeToken: "0", wantErr: true}, //event 1 is no longer in the history
+		{resumeToken: "5", wantErr: true},
+		{resumeToken: "abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.resumeToken, func(t *testing.T) {
+			resumed, err := bus.watch(ctx, "clusters", customerFilter, tt.resumeToken)
+			if tt.wantErr {
+				if err != ErrInvalidResumeToken {
+					t.Errorf("watch() error = %v, want %v", err, ErrInvalidResumeToken)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if got := received(resumed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resumed events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	//watchers that fall behind are closed
+	for i := 0; i < watchBufferSize+1; i++ {
+		publish("clusters", "e", "customer")
+	}
+	if got := received(events); len(got) != watchBufferSize+1 || got[watchBufferSize] != "closed" {
+		t.Errorf("slow watcher received %d events, want %d and closed", len(got), watchBufferSize)
+	}
+
+	//watchers are closed when their context is done
+	watchCtx, watchCancel := context.WithCancel(context.Background())
+	watched, err := bus.watch(watchCtx, "clusters", customerFilter, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	watchCancel()
+	if _, ok := <-watched; ok {
+		t.Errorf("watcher is not closed after its context is done")
+	}
+}
+
+func TestNewChangeEvent(t *testing.T) {
+	token, _ := bson.Marshal(bson.D{{Key: "_data", Value: "8263"}})
+	doc, _ := bson.Marshal(bson.D{{Key: "_id", Value: "guid"}})
+	deletedDoc, _ := bson.Marshal(bson.D{{Key: "_id", Value: "guid"}, {Key: "is_deleted", Value: true}})
+	tests := []struct {
+		name          string
+		operationType string
+		fullDocument  bson.Raw
+		removedFields []string
+		wantType      string
+		wantOk        bool
+	}{
+		{name: "insert", operationType: "insert", fullDocument: doc, wantType: ChangeEventCreate, wantOk: true},
+		{name: "update", operationType: "update", fullDocument: doc, wantType: ChangeEventUpdate, wantOk: true},
+		{name: "replace", operationType: "replace", fullDocument: doc, wantType: ChangeEventUpdate, wantOk: true},
+		{name: "soft delete", operationType: "update", fullDocument: deletedDoc, wantType: ChangeEventDelete, wantOk: true},
+		{name: "restore", operationType: "update", fullDocument: doc, removedFields: []string{"deletedTime", "is_deleted"}, wantType: ChangeEventCreate, wantOk: true},
+		{name: "update of removed document", operationType: "update", wantType: ChangeEventUpdate, wantOk: false},
+		{name: "purge", operationType: "delete", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			streamEvent := changeStreamEvent{ID: token, OperationType: tt.operationType, FullDocument: tt.fullDocument}
+			streamEvent.DocumentKey.ID = "guid"
+			streamEvent.UpdateDescription.RemovedFields = tt.removedFields
+			event, ok := newChangeEvent(streamEvent)
+			if ok != tt.wantOk {
+				t.Fatalf("newChangeEvent() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if !ok {
+				return
+			}
+			if event.Type != tt.wantType || event.ID != "8263" || event.GUID != "guid" {
+				t.Errorf("newChangeEvent() = %+v, want type %s", event, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestChangeStreamMatch(t *testing.T) {
+	got := changeStreamMatch(bson.D{{Key: "customers", Value: "customer"}})
+	want := bson.D{
+		{Key: "operationType", Value: bson.D{{Key: "$in", Value: bson.A{"insert", "update", "replace"}}}},
+		{Key: "fullDocument.customers", Value: "customer"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("changeStreamMatch() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"config-service/db/mongo"
+	"config-service/types"
+	"config-service/utils/consts"
+	"config-service/utils/log"
+	"context"
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	mongoDB "go.mongodb.org/mongo-driver/mongo"
+)
+
+// webhook delivery statuses
+const (
+	DeliveryStatusPending    = "pending"
+	DeliveryStatusDelivered  = "delivered"
+	DeliveryStatusDeadLetter = "deadLetter" //all the delivery attempts failed, the payload is kept for redelivery
+)
+
+// WebhookDelivery is the delivery log record of a document change posted to a webhook
+type WebhookDelivery struct {
+	ID           string            `json:"id" bson:"_id"` //sent in the delivery header
+	Customers    []string          `json:"-" bson:"customers"`
+	WebhookGUID  string            `json:"webhookGUID" bson:"webhookGUID"`
+	EventID      string            `json:"eventID" bson:"eventID"`
+	EventType    string            `json:"eventType" bson:"eventType"`
+	Collection   string            `json:"collection" bson:"collection"`
+	DocumentGUID string            `json:"documentGUID" bson:"documentGUID"`
+	Status       string            `json:"status" bson:"status"`
+	Attempts     []DeliveryAttempt `json:"attempts" bson:"attempts"`
+	Payload      json.RawMessage   `json:"payload,omitempty" bson:"payload,omitempty"` //the posted payload of dead letters
+	CreationTime time.Time         `json:"creationTime" bson:"creationTime"`
+}
+
+// DeliveryAttempt is a webhook delivery request, StatusCode is empty when no response was received
+type DeliveryAttempt struct {
+	Time       time.Time `json:"time" bson:"time"`
+	StatusCode int       `json:"statusCode,omitempty" bson:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty" bson:"error,omitempty"`
+}
+
+// webhook deliveries fields
+const (
+	deliveryWebhookGUIDField  = "webhookGUID"
+	deliveryStatusField       = "status"
+	deliveryAttemptsField     = "attempts"
+	deliveryPayloadField      = "payload"
+	deliveryCreationTimeField = "creationTime"
+)
+
+// EnsureWebhookDeliveriesIndex creates the index of the webhook deliveries collection
+func EnsureWebhookDeliveriesIndex(c context.Context) error {
+	_, err := mongo.GetWriteCollection(consts.WebhookDeliveriesCollection).Indexes().CreateOne(c, mongoDB.IndexModel{
+		Keys: bson.D{{Key: consts.CustomersField, Value: 1}, {Key: deliveryWebhookGUIDField, Value: 1}, {Key: deliveryCreationTimeField, Value: -1}},
+	})
+	return err
+}
+
+// FindWebhooksForEvent returns the customer webhooks subscribed to the event type in the collection
+func FindWebhooksForEvent(c context.Context, customerGUID, collection, eventType string) ([]types.Webhook, error) {
+	filter := NewFilterBuilder().
+		WithCustomers([]string{customerGUID}).
+		WithNotDeleted().
+		WithValue("$and", bson.A{allOrValue(consts.CollectionsField, collection), allOrValue(consts.EventTypesField, eventType)}).
+		Get()
+	webhooks := []types.Webhook{}
+	cur, err := mongo.GetReadCollection(consts.WebhookCollection).Find(c, filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := cur.All(c, &webhooks); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// allOrValue returns the filter of array fields that are empty (match all values) or contain the value
+func allOrValue(field, value string) bson.D {
+	return bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: field, Value: bson.D{{Key: "$exists", Value: false}}}},
+		bson.D{{Key: field, Value: bson.D{{Key: "$size", Value: 0}}}},
+		bson.D{{Key: field, Value: value}},
+	}}}
+}
+
+// InsertWebhookDelivery adds the delivery to the delivery log
+func InsertWebhookDelivery(c context.Context, delivery WebhookDelivery) error {
+	_, err := mongo.GetWriteCollection(consts.WebhookDeliveriesCollection).InsertOne(c, delivery)
+	return err
+}
+
+// AddWebhookDeliveryAttempt adds the attempt to the delivery log record and sets its status, the payload is kept for dead letters
+func AddWebhookDeliveryAttempt(c context.Context, deliveryID string, attempt DeliveryAttempt, status string, payload json.RawMessage) error {
+	set := bson.D{{Key: deliveryStatusField, Value: status}}
+	if status == DeliveryStatusDeadLetter {
+		set = append(set, bson.E{Key: deliveryPayloadField, Value: payload})
+	}
+	_, err := mongo.GetWriteCollection(consts.WebhookDeliveriesCollection).UpdateOne(c,
+		NewFilterBuilder().WithID(deliveryID).Get(),
+		bson.D{
+			{Key: "$push", Value: bson.D{{Key: deliveryAttemptsField, Value: attempt}}},
+			{Key: "$set", Value: set},
+		})
+	return err
+}
+
+// FindWebhookDeliveries returns a page of the delivery log of the customer webhook, latest first, all statuses when status is empty
+func FindWebhookDeliveries(c context.Context, webhookGUID, status string, pagination Pagination) (*AggResult[WebhookDelivery], error) {
+	defer log.LogNTraceEnterExit("FindWebhookDeliveries", c)()
+	filterBuilder := NewFilterBuilder().WithCustomer(c).WithValue(deliveryWebhookGUIDField, webhookGUID)
+	if status != "" {
+		filterBuilder.WithValue(deliveryStatusField, status)
+	}
+	return findPage[WebhookDelivery](c, consts.WebhookDeliveriesCollection, filterBuilder.Get(),
+		bson.D{{Key: deliveryCreationTimeField, Value: -1}, {Key: consts.IdField, Value: -1}}, pagination)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"config-service/handlers"
+	"config-service/types"
+	"config-service/utils/consts"
+	"net/http"
+
+	"github.com/armosec/armoapi-go/armotypes"
+)
+
+func (suite *MainTestSuite) TestETag() {
+	cluster := &types.Cluster{PortalBase: armotypes.PortalBase{Name: "etag-cluster", Attributes: map[string]interface{}{"env": "dev"}}}
+	cluster = testPostDoc(suite, consts.ClusterPath, cluster, newClusterCompareFilter)
+	path := consts.ClusterPath + "/" + cluster.GUID
+
+	getETag := func() string {
+		w := suite.doRequest(http.MethodGet, path, nil)
+		suite.Equal(http.StatusOK, w.Code)
+		etag := w.Header().Get(consts.ETagHeader)
+		suite.NotEmpty(etag)
+		return etag
+	}
+	etag := getETag()
+	//same etag when nothing changed and on get by name
+	suite.Equal(etag, getETag())
+	w := suite.doRequest(http.MethodGet, consts.ClusterPath+"?name="+cluster.Name, nil)
+	suite.Equal(http.StatusOK, w.Code)
+	suite.Equal(etag, w.Header().Get(consts.ETagHeader))
+
+	//put with matching etag
+	cluster.Attributes["env"] = "prod"
+	suite.requestHeaders = map[string]string{consts.IfMatchHeader: etag}
+	w = suite.doRequest(http.MethodPut, consts.ClusterPath, cluster)
+	suite.Equal(http.StatusOK, w.Code, w.Body.String())
+	suite.requestHeaders = nil
+	newETag := getETag()
+	suite.NotEqual(etag, newETag)
+
+	//stale etag
+	suite.requestHeaders = map[string]string{consts.IfMatchHeader: etag}
+	cluster.Attributes["env"] = "staging"
+	testBadRequest(suite, http.MethodPut, consts.ClusterPath, errorPreconditionFailed, cluster, http.StatusPreconditionFailed)
+	suite.requestHeaders = map[string]string{consts.IfMatchHeader: etag, "Content-Type": handlers.MergePatchContentType}
+	testBadRequest(suite, http.MethodPatch, path, errorPreconditionFailed, map[string]interface{}{"attributes": map[string]interface{}{"env": "staging"}}, http.StatusPreconditionFailed)
+	suite.requestHeaders = map[string]string{consts.IfMatchHeader: etag}
+	testBadRequest(suite, http.MethodDelete, path, errorPreconditionFailed, nil, http.StatusPreconditionFailed)
+
+	//patch with one of the listed etags
+	suite.requestHeaders = map[string]string{consts.IfMatchHeader: etag + ", " + newETag, "Content-Type": handlers.MergePatchContentType}
+	w = suite.doRequest(http.MethodPatch, path, map[string]interface{}{"attributes": map[string]interface{}{"env": "staging"}})
+	suite.Equal(http.StatusOK, w.Code, w.Body.String())
+	suite.requestHeaders = nil
+	etag = getETag()
+	suite.NotEqual(newETag, etag)
+
+	//not existing document
+	suite.requestHeaders = map[string]string{consts.IfMatchHeader: "*"}
+	testBadRequest(suite, http.MethodDelete, consts.ClusterPath+"/not-exist", errorPreconditionFailed, nil, http.StatusPreconditionFailed)
+	//delete with matching etag
+	suite.requestHeaders = map[string]string{consts.IfMatchHeader: etag}
+	w = suite.doRequest(http.MethodDelete, path, nil)
+	suite.Equal(http.StatusOK, w.Code, w.Body.String())
+	suite.requestHeaders = nil
+}
+
+func (suite *MainTestSuite) TestConditionalGet() {
+	conditionalGet := func(path string, etag string, expectedCode int) string {
+		suite.requestHeaders = map[string]string{consts.IfNoneMatchHeader: etag}
+		w := suite.doRequest(http.MethodGet, path, nil)
+		suite.requestHeaders = nil
+		suite.Equal(expectedCode, w.Code)
+		if expectedCode == http.StatusNotModified {
+			suite.Empty(w.Body.String())
+		}
+		suite.NotEmpty(w.Header().Get(consts.ETagHeader))
+		return w.Header().Get(consts.ETagHeader)
+	}
+	//merged cluster config
+	configPath := consts.CustomerConfigPath + "?" + consts.ClusterNameParam + "=conditional-get-cluster"
+	etag := conditionalGet(configPath, "", http.StatusOK)
+	suite.Equal(etag, conditionalGet(configPath, etag, http.StatusNotModified))
+
+	//list and single document
+	cluster := &types.Cluster{PortalBase: armotypes.PortalBase{Name: "conditional-get-cluster", Attributes: map[string]interface{}{"env": "dev"}}}
+	cluster = testPostDoc(suite, consts.ClusterPath, cluster, newClusterCompareFilter)
+	listETag := conditionalGet(consts.ClusterPath, "", http.StatusOK)
+	conditionalGet(consts.ClusterPath, listETag, http.StatusNotModified)
+	docPath := consts.ClusterPath + "/" + cluster.GUID
+	docETag := conditionalGet(docPath, "", http.StatusOK)
+	conditionalGet(docPath, docETag, http.StatusNotModified)
+
+	//modified documents are sent again
+	cluster.Attributes["env"] = "prod"
+	w := suite.doRequest(http.MethodPut, consts.ClusterPath, cluster)
+	suite.Equal(http.StatusOK, w.Code)
+	suite.NotEqual(listETag, conditionalGet(consts.ClusterPath, listETag, http.StatusOK))
+	suite.NotEqual(docETag, conditionalGet(docPath, docETag, http.StatusOK))
+
+	testDeleteDocByGUID(suite, consts.ClusterPath, cluster, newClusterCompareFilter)
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"config-service/db"
+	"config-service/types"
+	"config-service/utils/consts"
+	"config-service/utils/log"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fields that are not part of the audited diff, fields that change in every update and secrets
+var auditIgnoredFields = []string{consts.UpdatedTimeField, consts.SigningKeyField, consts.KeyHashField}
+
+// AuditMiddleware records the requests that modified documents in the audit log
+// handlers add the modified documents to the record with AddAuditChange and AddAuditTargets
+func AuditMiddleware(c *gin.Context) {
+	c.Next()
+	switch c.Request.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return
+	}
+	if c.Writer.Status() >= http.StatusBadRequest {
+		return
+	}
+	if err := db.InsertAuditRecord(c, newAuditRecord(c)); err != nil {
+		log.LogNTraceError("failed to record audit", err, c)
+	}
+}
+
+// AddAuditChange adds the field level diff of a document modified by the request to the audit record
+// before is nil for created documents and after is nil for deleted documents
+func AddAuditChange[T types.DocContent](c *gin.Context, before, after *T) {
+	var guid string
+	var beforeDoc, afterDoc interface{}
+	if before != nil {
+		guid, beforeDoc = (*before).GetGUID(), *before
+	}
+	if after != nil {
+		guid, afterDoc = (*after).GetGUID(), *after
+	}
+	diff, err := auditDiff(beforeDoc, afterDoc)
+	if err != nil {
+		log.LogNTraceError(fmt.Sprintf("failed to audit changes of document %s", guid), err, c)
+	}
+	addAuditChanges(c, db.AuditChange{GUID: guid, Diff: diff})
+}
+
+// AddAuditTargets adds the GUIDs of documents modified by the request to the audit record without their changes
+func AddAuditTargets(c *gin.Context, guids ...string) {
+	changes := make([]db.AuditChange, 0, len(guids))
+	for _, guid := range guids {
+		changes = append(changes, db.AuditChange{GUID: guid})
+	}
+	addAuditChanges(c, changes...)
+}
+
+func addAuditChanges(c *gin.Context, changes ...db.AuditChange) {
+	c.Set(consts.AuditChanges, append(getAuditChanges(c), changes...))
+}
+
+func getAuditChanges(c *gin.Context) []db.AuditChange {
+	if iChanges, ok := c.Get(consts.AuditChanges); ok {
+		if changes, ok := iChanges.([]db.AuditChange); ok {
+			return changes
+		}
+		log.LogNTraceError("invalid audit changes type", fmt.Errorf("invalid audit changes type"), c)
+	}
+	return nil
+}
+
+// newAuditRecord returns the audit record of the served request
+func newAuditRecord(c *gin.Context) db.AuditRecord {
+	customerGUID := c.GetString(consts.CustomerGUID)
+	impersonatedBy := c.GetString(consts.ImpersonatedBy)
+	record := db.AuditRecord{
+		ID:            uuid.NewV4().String(),
+		Time:          time.Now().UTC(),
+		CustomerGUID:  customerGUID,
+		ActorGUID:     customerGUID,
+		Admin:         c.GetBool(consts.AdminAccess),
+		Impersonation: impersonatedBy != "",
+		Method:        c.Request.Method,
+		Path:          c.Request.URL.Path,
+		Query:         c.Request.URL.RawQuery,
+		Collection:    c.GetString(consts.Collection),
+		Status:        c.Writer.Status(),
+	}
+	if record.Impersonation {
+		record.ActorGUID = impersonatedBy
+	}
+	for _, change := range getAuditChanges(c) {
+		record.TargetGUIDs = append(record.TargetGUIDs, change.GUID)
+		if len(change.Diff) > 0 {
+			record.Changes = append(record.Changes, change)
+		}
+	}
+	if spanContext := trace.SpanFromContext(c.Request.Context()).SpanContext(); spanContext.IsValid() {
+		record.TraceID = spanContext.TraceID().String()
+	}
+	return record
+}
+
+// auditDiff returns the changed fields between the flattened documents, sorted by field name, nil documents have no fields
+func auditDiff(before, after interface{}) ([]db.FieldDiff, error) {
+	beforeFields, err := flattenAuditDoc(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := flattenAuditDoc(after)
+	if err != nil {
+		return nil, err
+	}
+	diff := []db.FieldDiff{}
+	for field, beforeValue := range beforeFields {
+		if afterValue, ok := afterFields[field]; !ok || !reflect.DeepEqual(beforeValue, afterValue) {
+			diff = append(diff, db.FieldDiff{Field: field, Before: beforeValue, After: afterValue})
+		}
+	}
+	for field, afterValue := range afterFields {
+		if _, ok := beforeFields[field]; !ok {
+			diff = append(diff, db.FieldDiff{Field: field, After: afterValue})
+		}
+	}
+	sort.Slice(diff, func(i, j int) bool {
+		return diff[i].Field < diff[j].Field
+	})
+	return diff, nil
+}
+
+// flattenAuditDoc returns the document fields by their dotted path, embedded documents and maps are flattened and arrays are kept as values
+func flattenAuditDoc(doc interface{}) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if doc == nil {
+		return fields, nil
+	}
+	docBytes, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var docMap bson.M
+	if err := bson.Unmarshal(docBytes, &docMap); err != nil {
+		return nil, err
+	}
+	flattenAuditFields("", docMap, fields)
+	for _, field := range auditIgnoredFields {
+		delete(fields, field)
+	}
+	return fields, nil
+}
+
+func flattenAuditFields(prefix string, doc bson.M, fields map[string]interface{}) {
+	for key, value := range doc {
+		if embedded, ok := value.(bson.M); ok && len(embedded) > 0 {
+			flattenAuditFields(prefix+key+".", embedded, fields)
+		} else {
+			fields[prefix+key] = value
+		}
+	}
+}
+
+// HandleGetAuditRecords - get a page of the audit records of the customer, latest first
+// admins can query the records of other customers with the customers param, or of all customers without it
+func HandleGetAuditRecords(c *gin.Context) {
+	defer log.LogNTraceEnterExit("HandleGetAuditRecords", c)()
+	query := db.AuditQuery{
+		ActorGUID:  c.Query(consts.ActorParam),
+		Collection: c.Query(consts.CollectionParam),
+		TargetGUID: c.Query(consts.TargetGUIDParam),
+	}
+	if customersGUIDs := c.QueryArray(consts.CustomersParam); !c.GetBool(consts.AdminAccess) {
+		if len(customersGUIDs) > 0 {
+			ResponseForbidden(c, "only admins can query the audit log of other customers")
+			return
+		}
+		query.CustomerGUIDs = []string{c.GetString(consts.CustomerGUID)}
+	} else {
+		query.CustomerGUIDs = customersGUIDs
+	}
+	var err error
+	if query.From, err = readAuditTime(c, consts.FromDateParam); err != nil {
+		ResponseBadRequest(c, err.Error())
+		return
+	}
+	if query.To, err = readAuditTime(c, consts.ToDateParam); err != nil {
+		ResponseBadRequest(c, err.Error())
+		return
+	}
+	pagination, ok := readSkipPagination(c)
+	if !ok {
+		return
+	}
+	if page, err := db.FindAuditRecords(c, query, pagination); err != nil {
+		ResponseInternalServerError(c, "failed to read audit records", err)
+	} else {
+		c.JSON(http.StatusOK, page)
+	}
+}
+
+// readAuditTime returns the time in the query param, nil if the param is not set
+func readAuditTime(c *gin.Context, param string) (*time.Time, error) {
+	timeStr := c.Query(param)
+	if timeStr == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, timeStr)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be in RFC3339 format", param)
+	}
+	return &t, nil
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"config-service/db"
+	"config-service/types"
+	"reflect"
+	"testing"
+
+	"github.com/armosec/armoapi-go/armotypes"
+)
+
+func TestAuditDiff(t *testing.T) {
+	cluster := func(name, env string) *types.Cluster {
+		return &types.Cluster{PortalBase: armotypes.PortalBase{GUID: "guid", Name: name, Attributes: map[string]interface{}{"env": env}, UpdatedTime: name}}
+	}
+	tests := []struct {
+		name   string
+		before interface{}
+		after  interface{}
+		want   []db.FieldDiff
+	}{
+		{
+			name:   "updated field",
+			before: cluster("cluster", "dev"),
+			after:  cluster("cluster", "prod"),
+			want:   []db.FieldDiff{{Field: "attributes.env", Before: "dev", After: "prod"}},
+		},
+		{
+			name:   "no changes",
+			before: cluster("cluster", "dev"),
+			after:  cluster("cluster", "dev"),
+			want:   []db.FieldDiff{},
+		},
+		{
+			name:  "created document",
+			after: cluster("cluster", "dev"),
+			want: []db.FieldDiff{
+				{Field: "attributes.env", After: "dev"},
+				{Field: "guid", After: "guid"},
+				{Field: "name", After: "cluster"},
+			},
+		},
+		{
+			name:  "api key hash",
+			after: &types.APIKey{PortalBase: armotypes.PortalBase{GUID: "guid"}, Key: "key", KeyHash: "hash", KeyPrefix: "prefix"},
+			want: []db.FieldDiff{
+				{Field: "creationTime", After: ""},
+				{Field: "guid", After: "guid"},
+				{Field: "keyPrefix", After: "prefix"},
+				{Field: "name", After: ""},
+				{Field: "scopes", After: nil},
+			},
+		},
+		{
+			name:   "deleted document",
+			before: cluster("cluster", "dev"),
+			want: []db.FieldDiff{
+				{Field: "attributes.env", Before: "dev"},
+				{Field: "guid", Before: "guid"},
+				{Field: "name", Before: "cluster"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := auditDiff(tt.before, tt.after)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("auditDiff() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"bytes"
+	"config-service/db"
+	"config-service/types"
+	"config-service/utils/consts"
+	"config-service/utils/log"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bulk POST modes (mode query param)
+const (
+	BulkModePartial = "partial" //create the valid documents and respond with the result of each item
+	BulkModeAtomic  = "atomic"  //create all the documents or none of them
+)
+
+// bulk item statuses
+const (
+	BulkStatusCreated   = "created"
+	BulkStatusUpdated   = "updated"
+	BulkStatusNotFound  = "notFound"
+	BulkStatusInvalid   = "invalid"
+	BulkStatusDuplicate = "duplicate"
+	BulkStatusConflict  = "conflict"
+	BulkStatusFailed    = "failed"
+)
+
+// BulkItemResult is the result of one document of a bulk request
+type BulkItemResult struct {
+	Index  int    `json:"index"`
+	GUID   string `json:"guid,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// validateBulkItems calls the validators with each document of a bulk request separately
+// returns the valid documents and the results of all the items where the results of the valid documents have no status yet
+func validateBulkItems[T types.DocContent](c *gin.Context, docs []T, validators []MutatorValidator[T]) ([]T, []BulkItemResult) {
+	validDocs := []T{}
+	results := make([]BulkItemResult, len(docs))
+	for i, doc := range docs {
+		results[i] = BulkItemResult{Index: i, GUID: doc.GetGUID()}
+		//validators respond on failure, the response is recorded as the item result
+		itemContext := c.Copy()
+		itemContext.Set(consts.BulkValidDocs, validDocs)
+		recorder := newResponseRecorder(c.Writer)
+		itemContext.Writer = recorder
+		valid := true
+		for _, validator := range validators {
+			var validated []T
+			if validated, valid = validator(itemContext, []T{doc}); !valid {
+				break
+			}
+			doc = validated[0]
+		}
+		if !valid {
+			results[i].Status, results[i].Error = recorder.result()
+			continue
+		}
+		results[i].GUID = doc.GetGUID()
+		validDocs = append(validDocs, doc)
+	}
+	return validDocs, results
+}
+
+// BulkPutDocsHandler - helper to update the valid documents of a bulk PUT request and respond with the result of each item
+// validDocs are the documents of the results without status
+func BulkPutDocsHandler[T types.DocContent](c *gin.Context, validDocs []T, results []BulkItemResult) {
+	defer log.LogNTraceEnterExit("BulkPutDocsHandler", c)()
+	updates := []db.BulkUpdate{}
+	updatesResults := []*BulkItemResult{}
+	next := 0
+	for i := range results {
+		if results[i].Status != "" {
+			continue
+		}
+		doc := validDocs[next]
+		next++
+		doc.SetUpdatedTime(nil)
+		update, err := db.GetUpdateDocCommand(doc, GetCustomPutFields(c), doc.GetReadOnlyFields()...)
+		if err != nil {
+			results[i].Status, results[i].Error = BulkStatusInvalid, err.Error()
+			continue
+		}
+		updates = append(updates, db.BulkUpdate{ID: doc.GetGUID(), Update: update})
+		updatesResults = append(updatesResults, &results[i])
+	}
+	if len(updates) > 0 {
+		updated, failed, err := db.BulkUpdateDocuments[T](c, updates)
+		if err != nil {
+			ResponseInternalServerError(c, "failed to update documents", err)
+			return
+		}
+		for i, result := range updatesResults {
+			if doc, ok := updated[i]; ok {
+				result.Status = BulkStatusUpdated
+				onDocChange(c, &doc.Before, &doc.After)
+			} else if err := failed[i]; errors.Is(err, db.ErrDocumentNotFound) {
+				result.Status, result.Error = BulkStatusNotFound, DocumentNotFound
+			} else if errors.Is(err, db.ErrUpdateConflict) {
+				result.Status, result.Error = BulkStatusConflict, DocumentModified
+			} else {
+				result.Status, result.Error = BulkStatusFailed, err.Error()
+			}
+		}
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+// BulkPostDocsHandler - helper to create the valid documents of a bulk POST request and respond with the result of each item
+// validDocs are the documents of the results without status
+func BulkPostDocsHandler[T types.DocContent](c *gin.Context, validDocs []T, results []BulkItemResult) {
+	defer log.LogNTraceEnterExit("BulkPostDocsHandler", c)()
+	if len(validDocs) > 0 {
+		failed, err := db.InsertDocumentsUnordered(c, validDocs)
+		if err != nil {
+			ResponseInternalServerError(c, "failed to create documents", err)
+			return
+		}
+		next := 0
+		for i := range results {
+			if results[i].Status != "" {
+				continue
+			}
+			results[i].GUID = validDocs[next].GetGUID()
+			if err, ok := failed[next]; !ok {
+				results[i].Status = BulkStatusCreated
+				onDocChange(c, nil, &validDocs[next])
+			} else if db.IsDuplicateKeyError(err) {
+				results[i].Status, results[i].Error = BulkStatusDuplicate, err.Error()
+			} else {
+				results[i].Status, results[i].Error = BulkStatusFailed, err.Error()
+			}
+			next++
+		}
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+// GetBulkValidDocs returns the documents of a bulk request that were validated before the document in validation
+// validators of unique values use them to find duplicates in the request
+func GetBulkValidDocs[T types.DocContent](c *gin.Context) []T {
+	if iDocs, ok := c.Get(consts.BulkValidDocs); ok {
+		if docs, ok := iDocs.([]T); ok {
+			return docs
+		}
+		log.LogNTraceError("invalid bulk valid docs type", fmt.Errorf("invalid bulk valid docs type"), c)
+	}
+	return nil
+}
+
+// GetBulkResults returns the items results of a bulk request, returns false if the request is not a bulk request
+func GetBulkResults(c *gin.Context) ([]BulkItemResult, bool) {
+	if iResults, ok := c.Get(consts.BulkItemsResults); ok {
+		if results, ok := iResults.([]BulkItemResult); ok {
+			return results, true
+		}
+		log.LogNTraceError("invalid bulk results type", fmt.Errorf("invalid bulk results type"), c)
+	}
+	return nil, false
+}
+
+// responseRecorder records the response written by validators of bulk items
+type responseRecorder struct {
+	gin.ResponseWriter
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newResponseRecorder(w gin.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w, header: http.Header{}}
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+}
+
+func (r *responseRecorder) WriteHeaderNow() {}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	return r.Write([]byte(s))
+}
+
+func (r *responseRecorder) Status() int {
+	return r.status
+}
+
+func (r *responseRecorder) Size() int {
+	return r.body.Len()
+}
+
+func (r *responseRecorder) Written() bool {
+	return r.status != 0
+}
+
+// result returns the bulk item status and error of the recorded error response
+func (r *responseRecorder) result() (string, string) {
+	var errResponse struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(r.body.Bytes(), &errResponse); err != nil || errResponse.Error == "" {
+		errResponse.Error = r.body.String()
+	}
+	switch {
+	case r.status == http.StatusNotFound:
+		return BulkStatusNotFound, errResponse.Error
+	case r.status >= http.StatusInternalServerError:
+		return BulkStatusFailed, errResponse.Error
+	}
+	return BulkStatusInvalid, errResponse.Error
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"config-service/types"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/armosec/armoapi-go/armotypes"
+	"github.com/gin-gonic/gin"
+)
+
+func TestValidateBulkItems(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("PUT", "/test", nil)
+	validator := func(c *gin.Context, docs []*types.Cluster) ([]*types.Cluster, bool) {
+		switch docs[0].Name {
+		case "invalid":
+			ResponseBadRequest(c, "invalid name")
+			return nil, false
+		case "missing":
+			ResponseDocumentNotFound(c)
+			return nil, false
+		}
+		docs[0].GUID = docs[0].Name + "-guid"
+		return docs, true
+	}
+	docs := []*types.Cluster{}
+	for _, name := range []string{"a", "invalid", "missing", "b"} {
+		docs = append(docs, &types.Cluster{PortalBase: armotypes.PortalBase{Name: name}})
+	}
+	validDocs, results := validateBulkItems(c, docs, []MutatorValidator[*types.Cluster]{validator})
+	if len(validDocs) != 2 || validDocs[0].GUID != "a-guid" || validDocs[1].GUID != "b-guid" {
+		t.Errorf("unexpected valid docs %v", validDocs)
+	}
+	expected := []BulkItemResult{
+		{Index: 0, GUID: "a-guid"},
+		{Index: 1, Status: BulkStatusInvalid, Error: "invalid name"},
+		{Index: 2, Status: BulkStatusNotFound, Error: DocumentNotFound},
+		{Index: 3, GUID: "b-guid"},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("validateBulkItems() results = %v, want %v", results, expected)
+	}
+	if c.IsAborted() || w.Body.Len() != 0 {
+		t.Errorf("items validation responded to the request")
+	}
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"config-service/db"
+	"config-service/types"
+	"config-service/utils/consts"
+	"config-service/utils/log"
+	"config-service/utils/webhook"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// onDocChange is called after a document was created, updated or deleted by the request
+// before is nil for created documents and after is nil for deleted documents
+func onDocChange[T types.DocContent](c *gin.Context, before, after *T) {
+	AddAuditChange(c, before, after)
+	switch {
+	case before == nil:
+		notifyDocChange(c, db.ChangeEventCreate, *after)
+	case after == nil:
+		notifyDocChange(c, db.ChangeEventDelete, *before)
+	default:
+		notifyDocChange(c, db.ChangeEventUpdate, *after)
+	}
+}
+
+// notifyDocChange publishes the document change to the collection watchers and dispatches it to the customer webhooks
+// doc is the document after the change, or before a delete
+func notifyDocChange[T types.DocContent](c *gin.Context, eventType string, doc T) {
+	if err := db.PublishChange(c, eventType, doc); err != nil {
+		log.LogNTraceError(fmt.Sprintf("failed to publish %s of document %s", eventType, doc.GetGUID()), err, c)
+	}
+	//documents created without a customer (e.g. tenants created with a provisioning token) have no webhooks
+	if customerGUID := c.GetString(consts.CustomerGUID); customerGUID != "" {
+		var payload interface{} = doc
+		if redactor, ok := payload.(types.Redactor); ok {
+			payload = redactor.Redacted()
+		}
+		webhook.Dispatch(c, webhook.NewEvent(eventType, c.GetString(consts.Collection), customerGUID, doc.GetGUID(), payload))
+	}
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"config-service/db"
+	"config-service/types"
+	"config-service/utils/consts"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// docETag returns the entity tag of the document version
+func docETag(version db.DocVersion) string {
+	return fmt.Sprintf(`"%s-%d"`, version.ID, version.Revision)
+}
+
+// bodyETag returns a strong entity tag of the response body
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// SetDocETag sets the ETag header of a document response
+// returns true if the ETag matches the If-None-Match header of the request and 304 Not Modified was sent
+func SetDocETag(c *gin.Context, version *db.DocVersion) bool {
+	if version == nil {
+		return false
+	}
+	etag := docETag(*version)
+	c.Header(consts.ETagHeader, etag)
+	return notModified(c, etag)
+}
+
+// ResponseJSONWithETag responds with the json of obj and a strong ETag of it or with 304 Not Modified if the ETag matches the If-None-Match header
+// only GET requests are conditional, other requests are responded without ETag
+func ResponseJSONWithETag(c *gin.Context, code int, obj interface{}) {
+	if c.Request.Method != http.MethodGet {
+		c.JSON(code, obj)
+		return
+	}
+	body, err := json.Marshal(obj)
+	if err != nil {
+		ResponseInternalServerError(c, "failed to encode response", err)
+		return
+	}
+	etag := bodyETag(body)
+	c.Header(consts.ETagHeader, etag)
+	if notModified(c, etag) {
+		return
+	}
+	c.Data(code, gin.MIMEJSON+"; charset=utf-8", body)
+}
+
+// notModified responds with 304 Not Modified if the etag matches the If-None-Match header of GET requests
+func notModified(c *gin.Context, etag string) bool {
+	ifNoneMatch := c.GetHeader(consts.IfNoneMatchHeader)
+	if c.Request.Method != http.MethodGet || ifNoneMatch == "" || !matchWeakETag(ifNoneMatch, etag) {
+		return false
+	}
+	c.AbortWithStatus(http.StatusNotModified)
+	return true
+}
+
+// matchWeakETag returns true if one of the entity tags in the If-None-Match header value matches the etag, ignoring weak indicators
+func matchWeakETag(ifNoneMatch string, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// matchETag returns true if one of the entity tags in the If-Match header value matches the etag, weak tags never match
+func matchETag(ifMatch string, etag string) bool {
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchRevision checks the If-Match precondition against the current version of the document matching the filter
+// returns the matched revision to make the update conditional on, db.AnyRevision if the request has no If-Match header
+// returns false if the precondition failed and the response was sent
+func ifMatchRevision[T types.DocContent](c *gin.Context, filterBuilder *db.FilterBuilder) (int64, bool) {
+	ifMatch := c.GetHeader(consts.IfMatchHeader)
+	if ifMatch == "" {
+		return db.AnyRevision, true
+	}
+	doc, version, err := db.FindOneForCustomerWithVersion[T](c, filterBuilder, db.NewProjectionBuilder().Include(consts.IdField).Get())
+	if err != nil {
+		ResponseInternalServerError(c, "failed to read document", err)
+		return 0, false
+	}
+	if doc == nil || !matchETag(ifMatch, docETag(*version)) {
+		ResponsePreconditionFailed(c)
+		return 0, false
+	}
+	return version.Revision, true
+}
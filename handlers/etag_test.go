@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"config-service/utils/consts"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMatchETag(t *testing.T) {
+	tests := []struct {
+		ifMatch string
+		want    bool
+	}{
+		{`"guid-1"`, true},
+		{`*`, true},
+		{`"guid-0", "guid-1"`, true},
+		{`"guid-0"`, false},
+		{`W/"guid-1"`, false},
+	}
+	for _, tt := range tests {
+		if got := matchETag(tt.ifMatch, `"guid-1"`); got != tt.want {
+			t.Errorf("matchETag(%s) = %v, want %v", tt.ifMatch, got, tt.want)
+		}
+	}
+}
+
+func TestResponseJSONWithETag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	respond := func(method, ifNoneMatch string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(method, "/", nil)
+		if ifNoneMatch != "" {
+			c.Request.Header.Set(consts.IfNoneMatchHeader, ifNoneMatch)
+		}
+		ResponseJSONWithETag(c, http.StatusOK, gin.H{"name": "config"})
+		return w
+	}
+	w := respond(http.MethodGet, "")
+	etag := w.Header().Get(consts.ETagHeader)
+	if w.Code != http.StatusOK || etag == "" || w.Body.String() != `{"name":"config"}` {
+		t.Fatalf("unexpected response %d %s %s", w.Code, etag, w.Body.String())
+	}
+	if w = respond(http.MethodGet, `"other", W/`+etag); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("expected not modified, got %d %s", w.Code, w.Body.String())
+	}
+	if w = respond(http.MethodGet, `"other"`); w.Code != http.StatusOK {
+		t.Errorf("expected ok, got %d", w.Code)
+	}
+	if w = respond(http.MethodPut, etag); w.Code != http.StatusOK || w.Header().Get(consts.ETagHeader) != "" {
+		t.Errorf("expected unconditional response, got %d", w.Code)
+	}
+}
@@ -5,12 +5,16 @@ import (
 	"config-service/types"
 	"config-service/utils/consts"
 	"config-service/utils/log"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.mongodb.org/mongo-driver/bson"
 	"k8s.io/utils/strings/slices"
 )
 
@@ -26,18 +30,26 @@ func HandleGetDocWithGUIDInPath[T types.DocContent](c *gin.Context) {
 		ResponseMissingGUID(c)
 		return
 	}
-	if doc, err := db.GetDocByGUID[T](c, guid); err != nil {
+	projection, err := readProjection(c)
+	if err != nil {
+		ResponseBadRequest(c, err.Error())
+		return
+	}
+	if doc, version, err := db.FindOneForCustomerWithVersion[T](c, db.NewFilterBuilder().WithGUID(guid), projection); err != nil {
 		ResponseInternalServerError(c, "failed to read document", err)
 		return
 	} else {
-		docResponse(c, doc)
+		if !SetDocETag(c, version) {
+			docResponse(c, doc)
+		}
 	}
 
 }
 
 func HandleGet[T types.DocContent](opts *routerOptions[T]) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if (!opts.serveGetNamesList || !GetNamesListHandler[T](c, opts.serveGetIncludeGlobalDocs)) &&
+		if !GetDeletedHandler[T](c, opts.QueryConfig) &&
+			(!opts.serveGetNamesList || !GetNamesListHandler[T](c, opts.serveGetIncludeGlobalDocs)) &&
 			!GetByNameParamHandler[T](c, opts.nameQueryParam) &&
 			!GetByScopeParamsHandler[T](c, opts.QueryConfig) {
 			HandleGetAll[T](c)
@@ -48,20 +60,47 @@ func HandleGet[T types.DocContent](opts *routerOptions[T]) gin.HandlerFunc {
 // HandleGetAll - get all customer's documents of type T for collection in context
 func HandleGetAll[T types.DocContent](c *gin.Context) {
 	defer log.LogNTraceEnterExit("HandleGetAll", c)()
-	if docs, err := db.GetAllForCustomer[T](c, false); err != nil {
-		ResponseInternalServerError(c, "failed to read all documents for customer", err)
-		return
-	} else {
-		docsResponse(c, docs)
-	}
+	FindHandler[T](c, nil, false)
 }
 
 // HandleGetAll - get all global and customer's documents of type T for collection in context
 func HandleGetAllWithGlobals[T types.DocContent](c *gin.Context) {
 	defer log.LogNTraceEnterExit("HandleGetAllWithGlobals", c)()
-	if docs, err := db.GetAllForCustomer[T](c, true); err != nil {
-		ResponseInternalServerError(c, "failed to read all documents for customer", err)
+	FindHandler[T](c, nil, true)
+}
+
+// FindHandler responds with the customer's documents matching the filter, sorted and paginated according to the query params
+func FindHandler[T types.DocContent](c *gin.Context, filterBuilder *db.FilterBuilder, includeGlobals bool) {
+	findOpts, err := readFindOptions(c)
+	if err != nil {
+		ResponseBadRequest(c, err.Error())
 		return
+	}
+	if filterBuilder, err = readTextSearch(c, filterBuilder, findOpts); err != nil {
+		ResponseBadRequest(c, err.Error())
+		return
+	}
+	if c.GetBool(consts.ListDeleted) && findOpts.Sort == nil && c.Query(consts.CursorParam) == "" {
+		//recently deleted first, cursor pages are read in _id order
+		findOpts.SetSort(bson.D{{Key: consts.DeletedTimeField, Value: -1}})
+	}
+	pagination, err := readPagination(c)
+	if err != nil {
+		ResponseBadRequest(c, err.Error())
+		return
+	}
+	if pagination != nil {
+		if page, err := db.GetPageForCustomer[T](c, filterBuilder, findOpts, includeGlobals, *pagination); errors.Is(err, db.ErrInvalidCursor) {
+			ResponseBadRequest(c, err.Error())
+		} else if err != nil {
+			ResponseInternalServerError(c, "failed to read documents", err)
+		} else {
+			pageResponse(c, page)
+		}
+		return
+	}
+	if docs, err := db.FindForCustomerWithOptions[T](c, filterBuilder, includeGlobals, findOpts); err != nil {
+		ResponseInternalServerError(c, "failed to read documents", err)
 	} else {
 		docsResponse(c, docs)
 	}
@@ -80,13 +119,31 @@ func GetNamesListHandler[T types.DocContent](c *gin.Context, includeGlobals bool
 			for _, docContent := range docNames {
 				names = append(names, docContent.GetName())
 			}
-			c.JSON(http.StatusOK, names)
+			ResponseJSONWithETag(c, http.StatusOK, names)
 			return true
 		}
 	}
 	return false
 }
 
+// GetDeletedHandler check for "deleted" query param and return the customer's deleted documents, returns false if not served by this handler
+// the deleted documents are filtered, sorted and paginated like the existing documents
+func GetDeletedHandler[T types.DocContent](c *gin.Context, conf *QueryParamsConfig) bool {
+	deleted, err := strconv.ParseBool(c.DefaultQuery(consts.DeletedParam, "false"))
+	if err != nil {
+		ResponseBadRequest(c, consts.DeletedParam+" must be true or false")
+		return true
+	} else if !deleted {
+		return false
+	}
+	defer log.LogNTraceEnterExit("GetDeletedHandler", c)()
+	c.Set(consts.ListDeleted, true)
+	if !GetByScopeParamsHandler[T](c, conf) {
+		FindHandler[T](c, nil, false)
+	}
+	return true
+}
+
 // HandleGetNameList check for <nameParam> query param and return the element with this name, returns false if not served by this handler
 func GetByNameParamHandler[T types.DocContent](c *gin.Context, nameParam string) bool {
 	if nameParam == "" {
@@ -95,11 +152,18 @@ func GetByNameParamHandler[T types.DocContent](c *gin.Context, nameParam string)
 	if name := c.Query(nameParam); name != "" {
 		defer log.LogNTraceEnterExit("GetByNameParamHandler", c)()
 		//get document by name
-		if doc, err := db.GetDocByName[T](c, name); err != nil {
+		projection, err := readProjection(c)
+		if err != nil {
+			ResponseBadRequest(c, err.Error())
+			return true
+		}
+		if doc, version, err := db.FindOneForCustomerWithVersion[T](c, db.NewFilterBuilder().WithName(name), projection); err != nil {
 			ResponseInternalServerError(c, "failed to read document", err)
 			return true
 		} else {
-			docResponse(c, doc)
+			if !SetDocETag(c, version) {
+				docResponse(c, doc)
+			}
 			return true
 		}
 	}
@@ -113,6 +177,20 @@ func GetByScopeParamsHandler[T types.DocContent](c *gin.Context, conf *QueryPara
 	}
 	defer log.LogNTraceEnterExit("GetByScopeParamsHandler", c)()
 
+	//filter expression
+	var expressionFilter bson.D
+	if expression := c.Query(consts.FilterParam); expression != "" {
+		if len(conf.FilterFields) == 0 {
+			ResponseBadRequest(c, "filter is not supported")
+			return true
+		}
+		var err error
+		if expressionFilter, err = db.ParseFilterExpression(expression, conf.FilterFields); err != nil {
+			ResponseBadRequest(c, err.Error())
+			return true
+		}
+	}
+
 	//keep filter builder per field name
 	filterBuilders := map[string]*db.FilterBuilder{}
 	getFilterBuilder := func(paramName string) *db.FilterBuilder {
@@ -123,9 +201,15 @@ func GetByScopeParamsHandler[T types.DocContent](c *gin.Context, conf *QueryPara
 		filterBuilders[paramName] = filterBuilder
 		return filterBuilder
 	}
+	//operators conditions per field name, conditions are combined with $and so conditions on the same key are not or-ed
+	operators := map[string]bson.A{}
 
 	qParams := c.Request.URL.Query()
 	for paramKey, vals := range qParams {
+		if slices.Contains(reservedQueryParams, paramKey) {
+			continue
+		}
+		paramKey, operator := splitQueryOperator(paramKey)
 		keys := strings.Split(paramKey, ".")
 		//clean whitespaces
 		values := slices.Filter([]string{}, vals, func(s string) bool { return s != "" })
@@ -157,6 +241,15 @@ func GetByScopeParamsHandler[T types.DocContent](c *gin.Context, conf *QueryPara
 		} else if QueryConfig.FieldName != "" {
 			key = QueryConfig.FieldName + "." + key
 		}
+		if operator != "" {
+			condition, err := operatorCondition(key, operator, values)
+			if err != nil {
+				ResponseBadRequest(c, err.Error())
+				return true
+			}
+			operators[QueryConfig.FieldName] = append(operators[QueryConfig.FieldName], condition)
+			continue
+		}
 		//get the field filter builder
 		filterBuilder := getFilterBuilder(QueryConfig.FieldName)
 		//case of single value
@@ -170,6 +263,15 @@ func GetByScopeParamsHandler[T types.DocContent](c *gin.Context, conf *QueryPara
 			filterBuilder.WithFilter(fb.WarpOr().Get())
 		}
 	}
+	//conditions of fields that are not arrays and the filter expression are combined in one top level $and
+	andConditions := bson.A{}
+	for fieldName, conditions := range operators {
+		if conf.Params2Query[fieldName].IsArray {
+			getFilterBuilder(fieldName).WithValue("$and", conditions)
+		} else {
+			andConditions = append(andConditions, conditions...)
+		}
+	}
 	//aggregate all filters
 	allQueriesFilter := db.NewFilterBuilder()
 	for key, filterBuilder := range filterBuilders {
@@ -180,18 +282,19 @@ func GetByScopeParamsHandler[T types.DocContent](c *gin.Context, conf *QueryPara
 		}
 		allQueriesFilter.WithFilter(filterBuilder.Get())
 	}
+	if expressionFilter != nil {
+		//keep the expression in its own clause so its keys do not mix with the scope params
+		andConditions = append(andConditions, expressionFilter)
+	}
+	if len(andConditions) > 0 {
+		allQueriesFilter.WithValue("$and", andConditions)
+	}
 	if len(allQueriesFilter.Get()) == 0 {
 		return false //not served by this handler
 	}
 	log.LogNTrace(fmt.Sprintf("query params: %v search query %v", qParams, allQueriesFilter.Get()), c)
-	if docs, err := db.FindForCustomer[T](c, allQueriesFilter, nil); err != nil {
-		ResponseInternalServerError(c, "failed to read documents", err)
-		return true
-	} else {
-		log.LogNTrace(fmt.Sprintf("scope query found %d documents", len(docs)), c)
-		docsResponse(c, docs)
-		return true
-	}
+	FindHandler[T](c, allQueriesFilter, false)
+	return true
 }
 
 // ////////////////////////////////////////POST///////////////////////////////////////////////
@@ -211,22 +314,37 @@ func HandlePostDocFromContext[T types.DocContent](c *gin.Context) {
 	if err != nil {
 		return
 	}
+	if results, bulk := GetBulkResults(c); bulk {
+		BulkPostDocsHandler(c, docs, results)
+		return
+	}
 	PostDocHandler(c, docs)
 }
 
 // PostDoc - helper to put document(s) of type T, custom handler should use this function to do the final POST handling
+// with mode=atomic query param all the documents are created or none of them
 func PostDocHandler[T types.DocContent](c *gin.Context, docs []T) {
 	defer log.LogNTraceEnterExit("PostDocHandler", c)()
+	insert := db.InsertDocuments[T]
+	if c.Query(consts.ModeParam) == BulkModeAtomic {
+		insert = db.InsertDocumentsAtomic[T]
+	}
 	var err error
-	if docs, err = db.InsertDocuments(c, docs); err != nil {
+	if docs, err = insert(c, docs); err != nil {
 		if db.IsDuplicateKeyError(err) {
 			ResponseDuplicateKey(c, consts.GUIDField)
 			return
+		} else if errors.Is(err, db.ErrTransactionsNotSupported) {
+			ResponseBadRequest(c, err.Error())
+			return
 		} else {
 			ResponseInternalServerError(c, "failed to create document", err)
 			return
 		}
 	} else {
+		for i := range docs {
+			onDocChange(c, nil, &docs[i])
+		}
 		if len(docs) == 1 {
 			c.JSON(http.StatusCreated, docs[0])
 		} else {
@@ -244,6 +362,7 @@ func PostDBDocumentHandler[T types.DocContent](c *gin.Context, dbDoc types.Docum
 		ResponseInternalServerError(c, "failed to create document", err)
 		return
 	} else {
+		onDocChange(c, nil, &dbDoc.Content)
 		c.JSON(http.StatusCreated, dbDoc.Content)
 	}
 }
@@ -260,18 +379,100 @@ func HandlePutDocWithGUIDValidation[T types.DocContent]() []gin.HandlerFunc {
 	return []gin.HandlerFunc{PutValidationMiddleware(ValidateGUIDExistence[T]), HandlePutDocFromContext[T]}
 }
 
-// HandlePutDocFromContext - handles updates a document of type T
+// HandlePutDocWithUpsert - chains upsert and put document handlers, upsert requests (upsert=true query param or upsertByDefault)
+// are handled by UpsertDocHandler, other requests by the put handlers
+func HandlePutDocWithUpsert[T types.DocContent](upsertByDefault bool, postValidators, putValidators []MutatorValidator[T]) []gin.HandlerFunc {
+	upsert := func(c *gin.Context) {
+		if isUpsert, err := strconv.ParseBool(c.DefaultQuery(consts.UpsertParam, strconv.FormatBool(upsertByDefault))); err != nil {
+			ResponseBadRequest(c, fmt.Sprintf("%s must be true or false", consts.UpsertParam))
+			return
+		} else if !isUpsert {
+			c.Next()
+			return
+		}
+		UpsertDocHandler(c, postValidators, putValidators)
+		c.Abort()
+	}
+	return append([]gin.HandlerFunc{upsert}, HandlePutDocWithValidation(putValidators...)...)
+}
+
+// UpsertDocHandler - handles PUT of a document by the name query param or the name in body
+// if a document with the name does not exist it is created with the post validators and responded with 201
+// otherwise it is updated with the put validators and responded with 200
+func UpsertDocHandler[T types.DocContent](c *gin.Context, postValidators, putValidators []MutatorValidator[T]) {
+	defer log.LogNTraceEnterExit("UpsertDocHandler", c)()
+	var doc T
+	if customDecoder, _ := GetCustomBodyDecoder[T](c); customDecoder != nil {
+		docs, err := customDecoder(c)
+		if err != nil {
+			ResponseFailedToBindJson(c, err)
+			return
+		} else if len(docs) != 1 {
+			ResponseBulkNotSupported(c)
+			return
+		}
+		doc = docs[0]
+	} else if err := c.ShouldBindBodyWith(&doc, binding.JSON); err != nil || doc == nil {
+		ResponseFailedToBindJson(c, err)
+		return
+	}
+	name := c.Query(consts.NameParam)
+	if name == "" {
+		name = doc.GetName()
+	} else if doc.GetName() != "" && doc.GetName() != name {
+		ResponseBadRequest(c, "name in body does not match the name query param")
+		return
+	}
+	if name == "" {
+		ResponseMissingName(c)
+		return
+	}
+	doc.SetName(name)
+	existing, err := db.GetDocByName[T](c, name)
+	if err != nil {
+		ResponseInternalServerError(c, "failed to read document", err)
+		return
+	}
+	validators := postValidators
+	if existing != nil {
+		doc.SetGUID((*existing).GetGUID())
+		validators = putValidators
+	}
+	docs := []T{doc}
+	for _, validator := range validators {
+		var ok bool
+		if docs, ok = validator(c, docs); !ok {
+			return
+		}
+	}
+	if existing == nil {
+		PostDocHandler(c, docs)
+	} else {
+		PutDocHandler(c, docs[0])
+	}
+}
+
+// HandlePutDocFromContext - handles updates a document of type T or the documents of a bulk request
 func HandlePutDocFromContext[T types.DocContent](c *gin.Context) {
 	docs, err := MustGetDocContentFromContext[T](c)
 	if err != nil {
 		return
 	}
+	if results, bulk := GetBulkResults(c); bulk {
+		BulkPutDocsHandler(c, docs, results)
+		return
+	}
 	PutDocHandler(c, docs[0])
 }
 
 // PutDoc - helper to put document of type T, custom handler should use this function to do the final PUT handling
+// if the request has If-Match header the document is updated only if it was not modified since the matching version was read
 func PutDocHandler[T types.DocContent](c *gin.Context, doc T) {
 	defer log.LogNTraceEnterExit("PutDocHandler", c)()
+	revision, ok := ifMatchRevision[T](c, db.NewFilterBuilder().WithGUID(doc.GetGUID()))
+	if !ok {
+		return
+	}
 	doc.SetUpdatedTime(nil)
 	update, err := db.GetUpdateDocCommand(doc, GetCustomPutFields(c), doc.GetReadOnlyFields()...)
 	if err != nil {
@@ -282,16 +483,87 @@ func PutDocHandler[T types.DocContent](c *gin.Context, doc T) {
 		ResponseInternalServerError(c, "failed to generate update command", err)
 		return
 	}
-	if res, err := db.UpdateDocument[T](c, doc.GetGUID(), update); err != nil {
+	if res, err := db.UpdateDocumentInRevision[T](c, doc.GetGUID(), revision, update); errors.Is(err, db.ErrUpdateConflict) {
+		ResponsePreconditionFailed(c)
+	} else if err != nil {
 		ResponseInternalServerError(c, "failed to update document", err)
 	} else if res == nil {
 		ResponseDocumentNotFound(c)
 		return
 	} else {
+		onDocChange(c, &res[0], &res[1])
 		docsResponse(c, res)
 	}
 }
 
+// ////////////////////////////////////////PATCH///////////////////////////////////////////////
+
+// HandlePatchDocWithValidation - chains patch validation and patch document handlers, validators are called with the patched document
+func HandlePatchDocWithValidation[T types.DocContent](validators ...MutatorValidator[T]) []gin.HandlerFunc {
+	return []gin.HandlerFunc{PatchValidationMiddleware(validators...), HandlePatchDocFromContext[T]}
+}
+
+// HandlePatchDocFromContext - handles patch of a document of type T, expects the original and the patched documents in context
+func HandlePatchDocFromContext[T types.DocContent](c *gin.Context) {
+	docs, err := MustGetDocContentFromContext[T](c)
+	if err != nil {
+		return
+	}
+	if len(docs) != 2 {
+		ResponseInternalServerError(c, "invalid patch doc content", nil)
+		return
+	}
+	PatchDocHandler(c, docs[0], docs[1])
+}
+
+// PatchDocHandler - helper to update the patched document, custom handler should use this function to do the final PATCH handling
+// json patch operations are translated to update operators when possible, otherwise the fields changed in the patched document are updated
+// if the original document was not modified since it was read
+// if the request has If-Match header the document is updated only if it was not modified since the matching version was read
+func PatchDocHandler[T types.DocContent](c *gin.Context, oldDoc, newDoc T) {
+	defer log.LogNTraceEnterExit("PatchDocHandler", c)()
+	revision, ok := ifMatchRevision[T](c, db.NewFilterBuilder().WithGUID(newDoc.GetGUID()))
+	if !ok {
+		return
+	}
+	newDoc.SetUpdatedTime(nil)
+	var update, conditions bson.D
+	translated := false
+	if operations := getJSONPatchOperations(c); operations != nil {
+		update, conditions, translated = translateJSONPatch(operations, oldDoc, newDoc, GetCustomPutFields(c), newDoc.GetReadOnlyFields())
+	}
+	if !translated {
+		var err error
+		if update, err = db.GetMergeUpdateCommand(oldDoc, newDoc, GetCustomPutFields(c), newDoc.GetReadOnlyFields()...); err != nil {
+			if db.IsNoFieldsToUpdateError(err) {
+				ResponseBadRequest(c, "no fields to update")
+				return
+			}
+			ResponseInternalServerError(c, "failed to generate update command", err)
+			return
+		}
+		//the If-Match revision condition replaces the read revision condition
+		if revision == db.AnyRevision {
+			conditions = unmodifiedConditions(c)
+		}
+	}
+	if revision != db.AnyRevision {
+		conditions = append(conditions, db.RevisionCondition(revision)...)
+	}
+	if updatedDoc, err := db.UpdateDocumentWithConditions[T](c, newDoc.GetGUID(), conditions, update); errors.Is(err, db.ErrUpdateConflict) {
+		if revision != db.AnyRevision {
+			ResponsePreconditionFailed(c)
+			return
+		}
+		ResponseConflict(c, "document was modified, read it and patch again")
+	} else if err != nil {
+		ResponseInternalServerError(c, "failed to update document", err)
+	} else {
+		onDocChange(c, &oldDoc, updatedDoc)
+		docsResponse(c, []T{oldDoc, *updatedDoc})
+	}
+}
+
 // ////////////////////////////////////////DELETE///////////////////////////////////////////////
 
 // HandleDeleteDoc  - delete document by id in path
@@ -337,36 +609,102 @@ func HandleDeleteDocByName[T types.DocContent](nameParam string) gin.HandlerFunc
 
 func BulkDeleteDocByNameHandler[T types.DocContent](c *gin.Context, names []string) {
 	defer log.LogNTraceEnterExit("BulkDeleteDocByNameHandler", c)()
-	if deletedCount, err := db.BulkDeleteByName[T](c, names); err != nil {
-	} else if deletedCount == 0 {
+	if deletedDocs, err := db.BulkDeleteByName[T](c, names); err != nil {
+		ResponseInternalServerError(c, "failed to delete documents", err)
+	} else if len(deletedDocs) == 0 {
 		ResponseDocumentNotFound(c)
 	} else {
-		c.JSON(http.StatusOK, gin.H{"deletedCount": deletedCount})
+		for i := range deletedDocs {
+			onDocChange(c, &deletedDocs[i], nil)
+		}
+		c.JSON(http.StatusOK, gin.H{"deletedCount": len(deletedDocs)})
 	}
 }
 
 func DeleteDocByGUIDHandler[T types.DocContent](c *gin.Context, guid string) {
 	defer log.LogNTraceEnterExit("DeleteDocByGUIDHandler", c)()
-	if deletedDoc, err := db.DeleteByGUID[T](c, guid); err != nil {
+	revision, ok := ifMatchRevision[T](c, db.NewFilterBuilder().WithGUID(guid))
+	if !ok {
+		return
+	}
+	if deletedDoc, err := db.DeleteByGUIDInRevision[T](c, guid, revision); errors.Is(err, db.ErrUpdateConflict) {
+		ResponsePreconditionFailed(c)
+	} else if err != nil {
 		ResponseInternalServerError(c, "failed to delete document", err)
 	} else if deletedDoc == nil {
 		ResponseDocumentNotFound(c)
 	} else {
+		onDocChange(c, deletedDoc, nil)
 		c.JSON(http.StatusOK, deletedDoc)
 	}
 }
 
 func DeleteDocByNameHandler[T types.DocContent](c *gin.Context, name string) {
 	defer log.LogNTraceEnterExit("DeleteDocByNameHandler", c)()
-	if deletedDoc, err := db.DeleteByName[T](c, name); err != nil {
+	revision, ok := ifMatchRevision[T](c, db.NewFilterBuilder().WithName(name))
+	if !ok {
+		return
+	}
+	if deletedDoc, err := db.DeleteByNameInRevision[T](c, name, revision); errors.Is(err, db.ErrUpdateConflict) {
+		ResponsePreconditionFailed(c)
+	} else if err != nil {
 		ResponseInternalServerError(c, "failed to read collection from context", err)
 	} else if deletedDoc == nil {
 		ResponseDocumentNotFound(c)
 	} else {
+		onDocChange(c, deletedDoc, nil)
 		c.JSON(http.StatusOK, deletedDoc)
 	}
 }
 
+// ////////////////////////////////////////RESTORE///////////////////////////////////////////////
+
+// HandleRestoreDoc - restore deleted document by id in path
+// the deleted document is passed to the validators before it is restored, so it meets the constraints of new documents (e.g. unique name)
+// changes made by the validators are saved with the restored document
+func HandleRestoreDoc[T types.DocContent](validators ...MutatorValidator[T]) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer log.LogNTraceEnterExit("HandleRestoreDoc", c)()
+		guid := c.Param(consts.GUIDField)
+		if guid == "" {
+			ResponseMissingGUID(c)
+			return
+		}
+		docs, err := db.FindDeletedForCustomer[T](c, db.NewFilterBuilder().WithGUID(guid), nil)
+		if err != nil {
+			ResponseInternalServerError(c, "failed to read deleted document", err)
+			return
+		} else if len(docs) == 0 {
+			ResponseDocumentNotFound(c)
+			return
+		}
+		docs = docs[:1]
+		var ok bool
+		for _, validator := range validators {
+			if docs, ok = validator(c, docs); !ok {
+				return
+			}
+		}
+		var update bson.D
+		if len(validators) > 0 {
+			docs[0].SetUpdatedTime(nil)
+			if update, err = db.GetUpdateDocCommand(docs[0], nil, docs[0].GetReadOnlyFields()...); err != nil && !db.IsNoFieldsToUpdateError(err) {
+				ResponseInternalServerError(c, "failed to generate update command", err)
+				return
+			}
+		}
+		if restoredDoc, err := db.RestoreByGUID[T](c, guid, update); err != nil {
+			ResponseInternalServerError(c, "failed to restore document", err)
+		} else if restoredDoc == nil {
+			ResponseDocumentNotFound(c)
+		} else {
+			AddAuditTargets(c, guid)
+			notifyDocChange(c, db.ChangeEventCreate, *restoredDoc)
+			c.JSON(http.StatusOK, restoredDoc)
+		}
+	}
+}
+
 // MustGetDocContentFromContext returns document(s) content from context and aborts if not found
 func MustGetDocContentFromContext[T types.DocContent](c *gin.Context) ([]T, error) {
 	var docs []T
@@ -384,7 +722,7 @@ func MustGetDocContentFromContext[T types.DocContent](c *gin.Context) ([]T, erro
 	return docs, nil
 }
 
-func HandlerAddToArray(requestHandler ContainerHandler) func(c *gin.Context) {
+func HandlerAddToArray[T types.DocContent](requestHandler ContainerHandler) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		pathToArray, item, valid := requestHandler(c)
 		if !valid {
@@ -395,16 +733,16 @@ func HandlerAddToArray(requestHandler ContainerHandler) func(c *gin.Context) {
 			ResponseMissingGUID(c)
 			return
 		}
-		if modified, err := db.AddToArray(c, guid, pathToArray, item); err != nil {
+		if docs, err := db.AddToArray[T](c, guid, pathToArray, item); err != nil {
 			ResponseInternalServerError(c, "failed to add to unsubscribedUsers", err)
 			return
 		} else {
-			c.JSON(http.StatusOK, gin.H{"added": modified})
+			c.JSON(http.StatusOK, gin.H{"added": onContainerChange(c, docs)})
 		}
 	}
 }
 
-func HandlerRemoveFromArray(requestHandler ContainerHandler) func(c *gin.Context) {
+func HandlerRemoveFromArray[T types.DocContent](requestHandler ContainerHandler) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		pathToArray, item, valid := requestHandler(c)
 		if !valid {
@@ -415,16 +753,16 @@ func HandlerRemoveFromArray(requestHandler ContainerHandler) func(c *gin.Context
 			ResponseMissingGUID(c)
 			return
 		}
-		if modified, err := db.PullFromArray(c, guid, pathToArray, item); err != nil {
+		if docs, err := db.PullFromArray[T](c, guid, pathToArray, item); err != nil {
 			ResponseInternalServerError(c, "failed to remove from  unsubscribedUsers", err)
 			return
 		} else {
-			c.JSON(http.StatusOK, gin.H{"removed": modified})
+			c.JSON(http.StatusOK, gin.H{"removed": onContainerChange(c, docs)})
 		}
 	}
 }
 
-func HandlerSetField(requestHandler ContainerHandler, set bool) func(c *gin.Context) {
+func HandlerSetField[T types.DocContent](requestHandler ContainerHandler, set bool) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		pathToField, value, valid := requestHandler(c)
 		if !valid {
@@ -435,17 +773,27 @@ func HandlerSetField(requestHandler ContainerHandler, set bool) func(c *gin.Cont
 			ResponseMissingGUID(c)
 			return
 		}
-		var update interface{}
+		var update bson.D
 		if set {
 			update = db.GetUpdateSetFieldCommand(pathToField, value)
 		} else { //unset
 			update = db.GetUpdateUnsetFieldCommand(pathToField)
 		}
-		if modified, err := db.UpdateOne(c, guid, update); err != nil {
+		if docs, err := db.UpdateOne[T](c, guid, update); err != nil {
 			ResponseInternalServerError(c, "failed to add to unsubscribedUsers", err)
 			return
 		} else {
-			c.JSON(http.StatusOK, gin.H{"modified": modified})
+			c.JSON(http.StatusOK, gin.H{"modified": onContainerChange(c, docs)})
 		}
 	}
 }
+
+// onContainerChange reports the change of a container update and returns the number of modified documents
+// docs are the old and the updated documents, nil if the document was not modified
+func onContainerChange[T types.DocContent](c *gin.Context, docs []T) int64 {
+	if len(docs) != 2 {
+		return 0
+	}
+	onDocChange(c, &docs[0], &docs[1])
+	return 1
+}
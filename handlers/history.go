@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"config-service/db"
+	"config-service/types"
+	"config-service/utils/consts"
+	"config-service/utils/log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleGetHistory - get the previous revisions of the document by id in path, latest first
+func HandleGetHistory[T types.DocContent](c *gin.Context) {
+	defer log.LogNTraceEnterExit("HandleGetHistory", c)()
+	guid := c.Param(consts.GUIDField)
+	if guid == "" {
+		ResponseMissingGUID(c)
+		return
+	}
+	if records, err := db.FindHistory[T](c, guid); err != nil {
+		ResponseInternalServerError(c, "failed to read document history", err)
+	} else {
+		ResponseJSONWithETag(c, http.StatusOK, records)
+	}
+}
+
+// HandleGetHistoryRevision - get the document by id in path as it was in the revision in path
+func HandleGetHistoryRevision[T types.DocContent](c *gin.Context) {
+	defer log.LogNTraceEnterExit("HandleGetHistoryRevision", c)()
+	guid, revision, ok := readHistoryParams(c)
+	if !ok {
+		return
+	}
+	record, err := db.GetHistoryRecord[T](c, guid, revision)
+	if err != nil {
+		ResponseInternalServerError(c, "failed to read document history", err)
+		return
+	} else if record != nil {
+		docResponse(c, &record.Document)
+		return
+	}
+	//the requested revision may be the current one
+	doc, version, err := db.FindOneForCustomerWithVersion[T](c, db.NewFilterBuilder().WithGUID(guid), nil)
+	if err != nil {
+		ResponseInternalServerError(c, "failed to read document", err)
+	} else if doc == nil || version.Revision != revision {
+		ResponseDocumentNotFound(c)
+	} else if !SetDocETag(c, version) {
+		docResponse(c, doc)
+	}
+}
+
+// HandleRollbackDoc - restore the document by id in path to the revision in path
+// the validators are called with the document of the revision, like in PUT, and the document is updated like in PATCH
+func HandleRollbackDoc[T types.DocContent](validators ...MutatorValidator[T]) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer log.LogNTraceEnterExit("HandleRollbackDoc", c)()
+		guid, revision, ok := readHistoryParams(c)
+		if !ok {
+			return
+		}
+		currentDoc, version, err := db.FindOneForCustomerWithVersion[T](c, db.NewFilterBuilder().WithGUID(guid), nil)
+		if err != nil {
+			ResponseInternalServerError(c, "failed to read document", err)
+			return
+		} else if currentDoc == nil {
+			ResponseDocumentNotFound(c)
+			return
+		}
+		c.Set(consts.ReadRevision, version.Revision)
+		record, err := db.GetHistoryRecord[T](c, guid, revision)
+		if err != nil {
+			ResponseInternalServerError(c, "failed to read document history", err)
+			return
+		} else if record == nil {
+			ResponseDocumentNotFound(c)
+			return
+		}
+		docs := []T{record.Document}
+		docs[0].SetGUID(guid)
+		for _, validator := range validators {
+			if docs, ok = validator(c, docs); !ok {
+				return
+			}
+		}
+		PatchDocHandler(c, *currentDoc, docs[0])
+	}
+}
+
+// readHistoryParams reads the document id and revision path params, returns false if they are invalid and the response was sent
+func readHistoryParams(c *gin.Context) (string, int64, bool) {
+	guid := c.Param(consts.GUIDField)
+	if guid == "" {
+		ResponseMissingGUID(c)
+		return "", 0, false
+	}
+	revision, err := strconv.ParseInt(c.Param(consts.RevisionField), 10, 64)
+	if err != nil || revision < 0 {
+		ResponseBadRequest(c, consts.RevisionField+" must be a non negative number")
+		return "", 0, false
+	}
+	return guid, revision, true
+}
@@ -0,0 +1,508 @@
+package handlers
+
+import (
+	"config-service/types"
+	"config-service/utils/consts"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"golang.org/x/exp/slices"
+)
+
+const JSONPatchContentType = "application/json-patch+json"
+
+var errJSONPatchTestFailed = errors.New("json patch test failed")
+
+// jsonPatchOperation is a JSON patch (RFC 6902) operation
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// parseJSONPatch parses and validates the operations of a JSON patch
+func parseJSONPatch(patch []byte) ([]jsonPatchOperation, error) {
+	var operations []jsonPatchOperation
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, fmt.Errorf("invalid json patch: %w", err)
+	}
+	for i, operation := range operations {
+		switch operation.Op {
+		case "add", "replace", "test":
+			if operation.Value == nil {
+				return nil, fmt.Errorf("json patch operation %d: %s requires a value", i, operation.Op)
+			}
+		case "move", "copy":
+			if _, err := parseJSONPointer(operation.From); err != nil {
+				return nil, fmt.Errorf("json patch operation %d: %w", i, err)
+			}
+		case "remove":
+		default:
+			return nil, fmt.Errorf("json patch operation %d: unsupported op %s", i, operation.Op)
+		}
+		if _, err := parseJSONPointer(operation.Path); err != nil {
+			return nil, fmt.Errorf("json patch operation %d: %w", i, err)
+		}
+	}
+	return operations, nil
+}
+
+// parseJSONPointer returns the reference tokens of a JSON pointer (RFC 6901)
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid path %s", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(tokens[i], "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// applyJSONPatch applies a JSON patch on the document, returns errJSONPatchTestFailed if a test operation failed
+func applyJSONPatch(doc []byte, patch []byte) ([]byte, error) {
+	operations, err := parseJSONPatch(patch)
+	if err != nil {
+		return nil, err
+	}
+	var docValue interface{}
+	if err := json.Unmarshal(doc, &docValue); err != nil {
+		return nil, err
+	}
+	for _, operation := range operations {
+		if docValue, err = applyJSONPatchOperation(docValue, operation); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(docValue)
+}
+
+func applyJSONPatchOperation(doc interface{}, operation jsonPatchOperation) (interface{}, error) {
+	path, _ := parseJSONPointer(operation.Path)
+	var value interface{}
+	if operation.Value != nil {
+		if err := json.Unmarshal(operation.Value, &value); err != nil {
+			return nil, err
+		}
+	}
+	switch operation.Op {
+	case "add":
+		return jsonPatchAdd(doc, path, value)
+	case "remove":
+		return jsonPatchRemove(doc, path)
+	case "replace":
+		if len(path) == 0 {
+			return value, nil
+		}
+		if _, err := jsonPointerGet(doc, path); err != nil {
+			return nil, err
+		}
+		if doc, err := jsonPatchRemove(doc, path); err != nil {
+			return nil, err
+		} else {
+			return jsonPatchAdd(doc, path, value)
+		}
+	case "move", "copy":
+		from, _ := parseJSONPointer(operation.From)
+		if operation.Op == "move" && operation.From != operation.Path && strings.HasPrefix(operation.Path, operation.From+"/") {
+			return nil, fmt.Errorf("cannot move %s into itself", operation.From)
+		}
+		value, err := jsonPointerGet(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if operation.Op == "move" {
+			if doc, err = jsonPatchRemove(doc, from); err != nil {
+				return nil, err
+			}
+		} else {
+			value = deepCopyJSON(value)
+		}
+		return jsonPatchAdd(doc, path, value)
+	case "test":
+		current, err := jsonPointerGet(doc, path)
+		if err != nil || !reflect.DeepEqual(current, value) {
+			return nil, fmt.Errorf("%w: %s", errJSONPatchTestFailed, operation.Path)
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unsupported op %s", operation.Op)
+}
+
+func jsonPatchAdd(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return jsonPointerUpdate(doc, path, func(container interface{}, token string) (interface{}, error) {
+		switch container := container.(type) {
+		case map[string]interface{}:
+			container[token] = value
+			return container, nil
+		case []interface{}:
+			if token == "-" {
+				return append(container, value), nil
+			}
+			index, err := jsonArrayIndex(token, len(container)+1)
+			if err != nil {
+				return nil, err
+			}
+			container = append(container, nil)
+			copy(container[index+1:], container[index:])
+			container[index] = value
+			return container, nil
+		}
+		return nil, fmt.Errorf("path %s does not exist", token)
+	})
+}
+
+func jsonPatchRemove(doc interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("cannot remove the document")
+	}
+	return jsonPointerUpdate(doc, path, func(container interface{}, token string) (interface{}, error) {
+		switch container := container.(type) {
+		case map[string]interface{}:
+			if _, ok := container[token]; !ok {
+				return nil, fmt.Errorf("path %s does not exist", token)
+			}
+			delete(container, token)
+			return container, nil
+		case []interface{}:
+			index, err := jsonArrayIndex(token, len(container))
+			if err != nil {
+				return nil, err
+			}
+			return append(container[:index], container[index+1:]...), nil
+		}
+		return nil, fmt.Errorf("path %s does not exist", token)
+	})
+}
+
+// jsonPointerUpdate calls update with the container of the last path token and sets the returned container in its parent
+func jsonPointerUpdate(node interface{}, path []string, update func(container interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return update(node, path[0])
+	}
+	child, err := jsonPointerGet(node, path[:1])
+	if err != nil {
+		return nil, err
+	}
+	if child, err = jsonPointerUpdate(child, path[1:], update); err != nil {
+		return nil, err
+	}
+	switch node := node.(type) {
+	case map[string]interface{}:
+		node[path[0]] = child
+	case []interface{}:
+		index, _ := jsonArrayIndex(path[0], len(node))
+		node[index] = child
+	}
+	return node, nil
+}
+
+func jsonPointerGet(node interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch container := node.(type) {
+		case map[string]interface{}:
+			child, ok := container[token]
+			if !ok {
+				return nil, fmt.Errorf("path %s does not exist", token)
+			}
+			node = child
+		case []interface{}:
+			index, err := jsonArrayIndex(token, len(container))
+			if err != nil {
+				return nil, err
+			}
+			node = container[index]
+		default:
+			return nil, fmt.Errorf("path %s does not exist", token)
+		}
+	}
+	return node, nil
+}
+
+// jsonArrayIndex parses an array index token, the index must be lower than size
+func jsonArrayIndex(token string, size int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %s", token)
+	}
+	if index >= size {
+		return 0, fmt.Errorf("array index %s is out of range", token)
+	}
+	return index, nil
+}
+
+func deepCopyJSON(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			m[k] = deepCopyJSON(v)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(value))
+		for i, v := range value {
+			a[i] = deepCopyJSON(v)
+		}
+		return a
+	}
+	return value
+}
+
+func getJSONPatchOperations(c *gin.Context) []jsonPatchOperation {
+	if iOperations, ok := c.Get(consts.JSONPatchOperations); ok {
+		operations, _ := iOperations.([]jsonPatchOperation)
+		return operations
+	}
+	return nil
+}
+
+// translateJSONPatch translates the JSON patch operations to a mongo update command and the conditions of its test operations
+// it returns false when the patch cannot be translated, e.g. remove from array, move and copy operations, or operations on fields that cannot be updated
+func translateJSONPatch[T types.DocContent](operations []jsonPatchOperation, oldDoc, newDoc T, includeFields []string, excludeFields []string) (update bson.D, conditions bson.D, ok bool) {
+	set, push, unset := bson.D{}, bson.D{}, bson.D{}
+	conditions = bson.D{}
+	updatedPaths := []string{}
+	tests := []jsonPatchOperation{}
+	newValue := reflect.ValueOf(newDoc)
+	for _, operation := range operations {
+		if operation.Op == "test" {
+			tests = append(tests, operation)
+			continue
+		}
+		if operation.Op != "add" && operation.Op != "replace" && operation.Op != "remove" {
+			return nil, nil, false
+		}
+		path, _ := parseJSONPointer(operation.Path)
+		if len(path) == 0 {
+			return nil, nil, false
+		}
+		parentPath, parent, ok := resolveBSONPath(newValue, path[:len(path)-1])
+		if !ok {
+			return nil, nil, false
+		}
+		token := path[len(path)-1]
+		switch parent.Kind() {
+		case reflect.Struct:
+			field, name, ok := jsonFieldByName(parent, token)
+			if !ok {
+				return nil, nil, false
+			}
+			fieldPath := joinBSONPath(parentPath, name)
+			updatedPaths = append(updatedPaths, fieldPath)
+			if operation.Op == "remove" {
+				unset = append(unset, bson.E{Key: fieldPath, Value: ""})
+			} else {
+				set = append(set, bson.E{Key: fieldPath, Value: field.Interface()})
+			}
+		case reflect.Map:
+			if !isValidBSONKey(token) || parent.Type().Key().Kind() != reflect.String {
+				return nil, nil, false
+			}
+			fieldPath := joinBSONPath(parentPath, token)
+			updatedPaths = append(updatedPaths, fieldPath)
+			if operation.Op == "remove" {
+				unset = append(unset, bson.E{Key: fieldPath, Value: ""})
+			} else {
+				value := parent.MapIndex(reflect.ValueOf(token).Convert(parent.Type().Key()))
+				if !value.IsValid() {
+					return nil, nil, false
+				}
+				set = append(set, bson.E{Key: fieldPath, Value: value.Interface()})
+			}
+		case reflect.Slice:
+			arrayPath := joinBSONPath(parentPath)
+			switch {
+			case operation.Op == "add" && token == "-":
+				updatedPaths = append(updatedPaths, arrayPath)
+				push = append(push, bson.E{Key: arrayPath, Value: parent.Index(parent.Len() - 1).Interface()})
+			case operation.Op == "add":
+				index, err := jsonArrayIndex(token, parent.Len())
+				if err != nil {
+					return nil, nil, false
+				}
+				updatedPaths = append(updatedPaths, arrayPath)
+				push = append(push, bson.E{Key: arrayPath, Value: bson.D{
+					{Key: "$each", Value: []interface{}{parent.Index(index).Interface()}},
+					{Key: "$position", Value: index}}})
+			case operation.Op == "replace":
+				index, err := jsonArrayIndex(token, parent.Len())
+				if err != nil {
+					return nil, nil, false
+				}
+				fieldPath := joinBSONPath(parentPath, token)
+				updatedPaths = append(updatedPaths, fieldPath)
+				set = append(set, bson.E{Key: fieldPath, Value: parent.Index(index).Interface()})
+			default:
+				//remove from array has no update operator
+				return nil, nil, false
+			}
+		default:
+			return nil, nil, false
+		}
+	}
+	//updated time is set with the changed fields
+	if updatedTimePath, updatedTime, ok := resolveBSONPath(newValue, []string{consts.UpdatedTimeField}); ok {
+		updatedPaths = append(updatedPaths, joinBSONPath(updatedTimePath))
+		set = append(set, bson.E{Key: joinBSONPath(updatedTimePath), Value: updatedTime.Interface()})
+	}
+	//the operators of a single update must not update overlapping paths and must respect the fields that can be updated
+	for i, path := range updatedPaths {
+		for _, other := range updatedPaths[i+1:] {
+			if isSubPath(path, other) || isSubPath(other, path) {
+				return nil, nil, false
+			}
+		}
+		for _, excluded := range excludeFields {
+			if isSubPath(excluded, path) {
+				return nil, nil, false
+			}
+		}
+		if len(includeFields) > 0 && slices.IndexFunc(includeFields, func(included string) bool { return isSubPath(included, path) }) < 0 {
+			return nil, nil, false
+		}
+	}
+	//tests of fields that are not updated by the patch are conditions of the update
+	oldValue := reflect.ValueOf(oldDoc)
+	for _, test := range tests {
+		path, _ := parseJSONPointer(test.Path)
+		testPath, _, ok := resolveBSONPath(oldValue, path)
+		if !ok || len(testPath) == 0 {
+			return nil, nil, false
+		}
+		fieldPath := joinBSONPath(testPath)
+		for _, updatedPath := range updatedPaths {
+			if isSubPath(updatedPath, fieldPath) || isSubPath(fieldPath, updatedPath) {
+				return nil, nil, false
+			}
+		}
+		var value interface{}
+		if err := json.Unmarshal(test.Value, &value); err != nil {
+			return nil, nil, false
+		}
+		switch value.(type) {
+		case string, float64, bool:
+			if reflect.ValueOf(value).IsZero() {
+				//zero values may be decoded from missing fields
+				return nil, nil, false
+			}
+			conditions = append(conditions, bson.E{Key: fieldPath, Value: value})
+		default:
+			//documents and arrays equality depends on the stored fields order
+			return nil, nil, false
+		}
+	}
+	update = bson.D{}
+	if len(set) > 0 {
+		update = append(update, bson.E{Key: "$set", Value: set})
+	}
+	if len(unset) > 0 {
+		update = append(update, bson.E{Key: "$unset", Value: unset})
+	}
+	if len(push) > 0 {
+		update = append(update, bson.E{Key: "$push", Value: push})
+	}
+	return update, conditions, true
+}
+
+// resolveBSONPath resolves the JSON pointer tokens in the document value and returns the matching bson path and value
+func resolveBSONPath(value reflect.Value, tokens []string) ([]string, reflect.Value, bool) {
+	path := []string{}
+	for _, token := range tokens {
+		value = indirectValue(value)
+		switch value.Kind() {
+		case reflect.Struct:
+			field, name, ok := jsonFieldByName(value, token)
+			if !ok {
+				return nil, value, false
+			}
+			path = append(path, name)
+			value = field
+		case reflect.Map:
+			if !isValidBSONKey(token) || value.Type().Key().Kind() != reflect.String {
+				return nil, value, false
+			}
+			value = value.MapIndex(reflect.ValueOf(token).Convert(value.Type().Key()))
+			if !value.IsValid() {
+				return nil, value, false
+			}
+			path = append(path, token)
+		case reflect.Slice, reflect.Array:
+			index, err := jsonArrayIndex(token, value.Len())
+			if err != nil {
+				return nil, value, false
+			}
+			path = append(path, token)
+			value = value.Index(index)
+		default:
+			return nil, value, false
+		}
+	}
+	return path, indirectValue(value), true
+}
+
+// jsonFieldByName returns the struct field with the json name and its bson path, embedded structs fields are promoted as in json
+func jsonFieldByName(value reflect.Value, name string) (reflect.Value, string, bool) {
+	for i := 0; i < value.NumField(); i++ {
+		structField := value.Type().Field(i)
+		jsonTag := strings.Split(structField.Tag.Get("json"), ",")[0]
+		if jsonTag == "-" || (!structField.IsExported() && !structField.Anonymous) {
+			continue
+		}
+		bsonTags, err := bsoncodec.DefaultStructTagParser(structField)
+		if err != nil || bsonTags.Skip {
+			continue
+		}
+		field := indirectValue(value.Field(i))
+		if structField.Anonymous && jsonTag == "" && field.Kind() == reflect.Struct {
+			if embedded, embeddedName, ok := jsonFieldByName(field, name); ok {
+				if !bsonTags.Inline {
+					embeddedName = bsonTags.Name + "." + embeddedName
+				}
+				return embedded, embeddedName, true
+			}
+			continue
+		}
+		if jsonTag == "" {
+			jsonTag = structField.Name
+		}
+		if jsonTag == name {
+			return value.Field(i), bsonTags.Name, true
+		}
+	}
+	return reflect.Value{}, "", false
+}
+
+func indirectValue(value reflect.Value) reflect.Value {
+	for (value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface) && !value.IsNil() {
+		value = value.Elem()
+	}
+	return value
+}
+
+func isValidBSONKey(key string) bool {
+	return key != "" && !strings.HasPrefix(key, "$") && !strings.Contains(key, ".")
+}
+
+func joinBSONPath(path []string, tokens ...string) string {
+	return strings.Join(append(append([]string{}, path...), tokens...), ".")
+}
+
+// isSubPath returns true if path is equal to or nested in parent
+func isSubPath(parent, path string) bool {
+	return path == parent || strings.HasPrefix(path, parent+".")
+}
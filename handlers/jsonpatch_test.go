@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"config-service/types"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/armosec/armoapi-go/armotypes"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestApplyJSONPatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		doc        string
+		patch      string
+		want       string
+		wantErr    bool
+		testFailed bool
+	}{
+		{name: "add member", doc: `{"a":"b"}`, patch: `[{"op":"add","path":"/c","value":{"d":1}}]`, want: `{"a":"b","c":{"d":1}}`},
+		{name: "add array element", doc: `{"a":["b","c"]}`, patch: `[{"op":"add","path":"/a/1","value":"x"}]`, want: `{"a":["b","x","c"]}`},
+		{name: "append array element", doc: `{"a":["b"]}`, patch: `[{"op":"add","path":"/a/-","value":"c"}]`, want: `{"a":["b","c"]}`},
+		{name: "remove", doc: `{"a":{"b":"c","d":"e"}}`, patch: `[{"op":"remove","path":"/a/b"}]`, want: `{"a":{"d":"e"}}`},
+		{name: "remove array element", doc: `{"a":["b","c"]}`, patch: `[{"op":"remove","path":"/a/0"}]`, want: `{"a":["c"]}`},
+		{name: "replace", doc: `{"a":["b","c"]}`, patch: `[{"op":"replace","path":"/a/1","value":null}]`, want: `{"a":["b",null]}`},
+		{name: "move", doc: `{"a":{"b":"c"},"d":[]}`, patch: `[{"op":"move","from":"/a/b","path":"/d/0"}]`, want: `{"a":{},"d":["c"]}`},
+		{name: "copy", doc: `{"a":{"b":"c"}}`, patch: `[{"op":"copy","from":"/a","path":"/e"}]`, want: `{"a":{"b":"c"},"e":{"b":"c"}}`},
+		{name: "escaped path", doc: `{"a/b":{"c~d":1}}`, patch: `[{"op":"replace","path":"/a~1b/c~0d","value":2}]`, want: `{"a/b":{"c~d":2}}`},
+		{name: "test", doc: `{"a":{"b":[1,"c"]}}`, patch: `[{"op":"test","path":"/a","value":{"b":[1,"c"]}},{"op":"add","path":"/d","value":true}]`, want: `{"a":{"b":[1,"c"]},"d":true}`},
+		{name: "test failed", doc: `{"a":"b"}`, patch: `[{"op":"test","path":"/a","value":"c"}]`, wantErr: true, testFailed: true},
+		{name: "test missing", doc: `{"a":"b"}`, patch: `[{"op":"test","path":"/c","value":"b"}]`, wantErr: true, testFailed: true},
+		{name: "remove missing", doc: `{"a":"b"}`, patch: `[{"op":"remove","path":"/c"}]`, wantErr: true},
+		{name: "replace missing", doc: `{"a":"b"}`, patch: `[{"op":"replace","path":"/c","value":1}]`, wantErr: true},
+		{name: "add out of range", doc: `{"a":[]}`, patch: `[{"op":"add","path":"/a/1","value":1}]`, wantErr: true},
+		{name: "add to missing parent", doc: `{"a":"b"}`, patch: `[{"op":"add","path":"/c/d","value":1}]`, wantErr: true},
+		{name: "move into itself", doc: `{"a":{"b":"c"}}`, patch: `[{"op":"move","from":"/a","path":"/a/b/c"}]`, wantErr: true},
+		{name: "missing value", doc: `{"a":"b"}`, patch: `[{"op":"add","path":"/c"}]`, wantErr: true},
+		{name: "unsupported op", doc: `{"a":"b"}`, patch: `[{"op":"merge","path":"/a","value":1}]`, wantErr: true},
+		{name: "invalid path", doc: `{"a":"b"}`, patch: `[{"op":"remove","path":"a"}]`, wantErr: true},
+		{name: "not an array", doc: `{"a":"b"}`, patch: `{"op":"remove","path":"/a"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyJSONPatch([]byte(tt.doc), []byte(tt.patch))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyJSONPatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if errors.Is(err, errJSONPatchTestFailed) != tt.testFailed {
+				t.Errorf("applyJSONPatch() error = %v, testFailed %v", err, tt.testFailed)
+			}
+			if tt.wantErr {
+				return
+			}
+			var gotValue, wantValue interface{}
+			_ = json.Unmarshal(got, &gotValue)
+			_ = json.Unmarshal([]byte(tt.want), &wantValue)
+			if !reflect.DeepEqual(gotValue, wantValue) {
+				t.Errorf("applyJSONPatch() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranslateJSONPatch(t *testing.T) {
+	oldDoc := &types.PostureExceptionPolicy{
+		PortalBase:   armotypes.PortalBase{GUID: "guid", Name: "policy", UpdatedTime: "old", Attributes: map[string]interface{}{"env": "dev", "team": "a"}},
+		CreationTime: "created",
+		Resources:    []armotypes.PortalDesignator{{DesignatorType: armotypes.DesignatorAttributes, Attributes: map[string]string{"cluster": "c1"}}},
+	}
+	designator := armotypes.PortalDesignator{DesignatorType: armotypes.DesignatorAttributes, Attributes: map[string]string{"cluster": "c2"}}
+	tests := []struct {
+		name           string
+		patch          string
+		includeFields  []string
+		wantUpdate     bson.D
+		wantConditions bson.D
+		wantFallback   bool
+	}{
+		{
+			name:  "set, unset and push",
+			patch: `[{"op":"test","path":"/name","value":"policy"},{"op":"replace","path":"/attributes/env","value":"prod"},{"op":"remove","path":"/attributes/team"},{"op":"add","path":"/resources/-","value":{"designatorType":"Attributes","attributes":{"cluster":"c2"}}}]`,
+			wantUpdate: bson.D{
+				{Key: "$set", Value: bson.D{{Key: "attributes.env", Value: "prod"}, {Key: "updatedTime", Value: "new"}}},
+				{Key: "$unset", Value: bson.D{{Key: "attributes.team", Value: ""}}},
+				{Key: "$push", Value: bson.D{{Key: "resources", Value: designator}}},
+			},
+			wantConditions: bson.D{{Key: "name", Value: "policy"}},
+		},
+		{
+			name:  "insert and replace array elements",
+			patch: `[{"op":"add","path":"/resources/0","value":{"designatorType":"Attributes","attributes":{"cluster":"c2"}}},{"op":"replace","path":"/attributes","value":{"env":"prod"}}]`,
+			wantUpdate: bson.D{
+				{Key: "$set", Value: bson.D{{Key: "attributes", Value: map[string]interface{}{"env": "prod"}}, {Key: "updatedTime", Value: "new"}}},
+				{Key: "$push", Value: bson.D{{Key: "resources", Value: bson.D{{Key: "$each", Value: []interface{}{designator}}, {Key: "$position", Value: 0}}}}},
+			},
+			wantConditions: bson.D{},
+		},
+		{
+			name:           "test array element",
+			patch:          `[{"op":"test","path":"/resources/0/attributes/cluster","value":"c1"},{"op":"add","path":"/attributes/x","value":"y"}]`,
+			wantUpdate:     bson.D{{Key: "$set", Value: bson.D{{Key: "attributes.x", Value: "y"}, {Key: "updatedTime", Value: "new"}}}},
+			wantConditions: bson.D{{Key: "resources.0.attributes.cluster", Value: "c1"}},
+		},
+		{name: "remove array element", patch: `[{"op":"remove","path":"/resources/0"}]`, wantFallback: true},
+		{name: "move", patch: `[{"op":"move","from":"/attributes/env","path":"/attributes/stage"}]`, wantFallback: true},
+		{name: "overlapping paths", patch: `[{"op":"add","path":"/attributes/x","value":"y"},{"op":"replace","path":"/attributes","value":{}}]`, wantFallback: true},
+		{name: "read only field", patch: `[{"op":"replace","path":"/creationTime","value":"now"}]`, wantFallback: true},
+		{name: "not included field", patch: `[{"op":"add","path":"/attributes/x","value":"y"}]`, includeFields: []string{"resources"}, wantFallback: true},
+		{
+			name:           "included field",
+			patch:          `[{"op":"replace","path":"/attributes/env","value":"prod"}]`,
+			includeFields:  []string{"attributes.env", "updatedTime"},
+			wantUpdate:     bson.D{{Key: "$set", Value: bson.D{{Key: "attributes.env", Value: "prod"}, {Key: "updatedTime", Value: "new"}}}},
+			wantConditions: bson.D{},
+		},
+		{name: "sibling of included field", patch: `[{"op":"add","path":"/attributes/envX","value":"y"}]`, includeFields: []string{"attributes.env", "updatedTime"}, wantFallback: true},
+		{name: "test updated path", patch: `[{"op":"test","path":"/attributes/env","value":"dev"},{"op":"replace","path":"/attributes/env","value":"prod"}]`, wantFallback: true},
+		{name: "test object", patch: `[{"op":"test","path":"/attributes","value":{"env":"dev","team":"a"}},{"op":"add","path":"/policyType","value":"x"}]`, wantFallback: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			operations, err := parseJSONPatch([]byte(tt.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			docJson, _ := json.Marshal(oldDoc)
+			newDoc, err := patchDoc(oldDoc, []byte(tt.patch), applyJSONPatch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			newDoc.UpdatedTime = "new"
+			update, conditions, ok := translateJSONPatch(operations, oldDoc, newDoc, tt.includeFields, oldDoc.GetReadOnlyFields())
+			if ok == tt.wantFallback {
+				t.Fatalf("translateJSONPatch() ok = %v, wantFallback %v", ok, tt.wantFallback)
+			}
+			if after, _ := json.Marshal(oldDoc); string(after) != string(docJson) {
+				t.Errorf("original document was modified")
+			}
+			if tt.wantFallback {
+				return
+			}
+			if !reflect.DeepEqual(update, tt.wantUpdate) {
+				t.Errorf("translateJSONPatch() update = %v, want %v", update, tt.wantUpdate)
+			}
+			if !reflect.DeepEqual(conditions, tt.wantConditions) {
+				t.Errorf("translateJSONPatch() conditions = %v, want %v", conditions, tt.wantConditions)
+			}
+		})
+	}
+}
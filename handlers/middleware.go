@@ -1,9 +1,14 @@
 package handlers
 
 import (
+	"config-service/db"
 	"config-service/types"
+	"config-service/utils/auth"
 	"config-service/utils/consts"
 	"config-service/utils/log"
+	"encoding/json"
+	"errors"
+	"fmt"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
@@ -18,6 +23,19 @@ func DBContextMiddleware(collectionName string) gin.HandlerFunc {
 	}
 }
 
+// AuthorizationMiddleware is a middleware that aborts requests the session roles are not permitted to make on all the route groups
+func AuthorizationMiddleware(routeGroups ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, routeGroup := range routeGroups {
+			if !auth.Authorize(c.GetStringSlice(consts.Roles), c.Request.Method, routeGroup) {
+				ResponseForbidden(c, fmt.Sprintf("%s is not permitted on %s routes", c.Request.Method, routeGroup))
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
 func PutFieldsContextMiddleware(fields []string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Set(consts.PutDocFields, fields)
@@ -25,6 +43,18 @@ func PutFieldsContextMiddleware(fields []string) gin.HandlerFunc {
 	}
 }
 
+func SortFieldsContextMiddleware(fields []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(consts.SortFields, fields)
+		c.Next()
+	}
+}
+
+func TextSearchContextMiddleware(c *gin.Context) {
+	c.Set(consts.TextSearch, true)
+	c.Next()
+}
+
 func BodyDecoderContextMiddleware[T types.DocContent](decoder *BodyDecoder[T]) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Set(consts.BodyDecoder, decoder)
@@ -40,6 +70,8 @@ func ResponseSenderContextMiddleware[T types.DocContent](sender *ResponseSender[
 }
 
 // PostValidationMiddleware validate post request and if valid sets one or many DocContents in context for next handler, otherwise abort request
+// with mode=partial query param each document is validated separately, the valid documents are set in context
+// with the results of all the items (see GetBulkResults)
 func PostValidationMiddleware[T types.DocContent](validators ...MutatorValidator[T]) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		defer log.LogNTraceEnterExit("HandlePostValidation", c)()
@@ -67,6 +99,19 @@ func PostValidationMiddleware[T types.DocContent](validators ...MutatorValidator
 			ResponseBadRequest(c, "no documents in request")
 			return
 		}
+		switch mode := c.Query(consts.ModeParam); mode {
+		case "", BulkModeAtomic:
+		case BulkModePartial:
+			//each document is validated separately, the valid documents are created
+			validDocs, results := validateBulkItems(c, docs, validators)
+			c.Set(consts.BulkItemsResults, results)
+			c.Set(consts.DocContentKey, validDocs)
+			c.Next()
+			return
+		default:
+			ResponseBadRequest(c, fmt.Sprintf("unsupported mode %s, supported modes are: %s,%s", mode, BulkModePartial, BulkModeAtomic))
+			return
+		}
 
 		for _, validator := range validators {
 			var ok bool
@@ -80,19 +125,42 @@ func PostValidationMiddleware[T types.DocContent](validators ...MutatorValidator
 }
 
 // PutValidationMiddleware validate put request and if valid set DocContent in context for next handler, otherwise abort request
+// in bulk requests (array of documents) each document is validated separately, the valid documents are set in context
+// with the results of all the items (see GetBulkResults)
 func PutValidationMiddleware[T types.DocContent](validators ...MutatorValidator[T]) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		defer log.LogNTraceEnterExit("HandlePutValidation", c)()
 		var doc T
+		var docs []T
 		if customDecoder, _ := GetCustomBodyDecoder[T](c); customDecoder != nil {
-			if docs, err := customDecoder(c); err != nil {
+			var err error
+			if docs, err = customDecoder(c); err != nil {
 				ResponseFailedToBindJson(c, err)
 				return
-			} else {
-				doc = docs[0]
+			} else if len(docs) == 1 {
+				doc, docs = docs[0], nil
 			}
-		} else if err := c.ShouldBindJSON(&doc); err != nil {
-			ResponseFailedToBindJson(c, err)
+		} else if err := c.ShouldBindBodyWith(&doc, binding.JSON); err != nil || doc == nil {
+			//check if bulk request
+			if err := c.ShouldBindBodyWith(&docs, binding.JSON); err != nil || docs == nil {
+				ResponseFailedToBindJson(c, err)
+				return
+			}
+		}
+		if doc == nil {
+			//bulk request
+			if len(docs) == 0 {
+				ResponseBadRequest(c, "no documents in request")
+				return
+			}
+			if c.Param(consts.GUIDField) != "" {
+				ResponseBadRequest(c, "GUID in path is not allowed in bulk request")
+				return
+			}
+			validDocs, results := validateBulkItems(c, docs, validators)
+			c.Set(consts.BulkItemsResults, results)
+			c.Set(consts.DocContentKey, validDocs)
+			c.Next()
 			return
 		}
 		//validate
@@ -107,3 +175,67 @@ func PutValidationMiddleware[T types.DocContent](validators ...MutatorValidator[
 		c.Next()
 	}
 }
+
+// PatchValidationMiddleware applies the patch in the request body to the document with the GUID in path, validates the patched document
+// and if valid sets the original and the patched DocContents in context for next handler, otherwise abort request
+func PatchValidationMiddleware[T types.DocContent](validators ...MutatorValidator[T]) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		defer log.LogNTraceEnterExit("HandlePatchValidation", c)()
+		guid := c.Param(consts.GUIDField)
+		if guid == "" {
+			ResponseMissingGUID(c)
+			return
+		}
+		var applyPatch func(doc []byte, patch []byte) ([]byte, error)
+		switch c.ContentType() {
+		case MergePatchContentType:
+			applyPatch = applyMergePatch
+		case JSONPatchContentType:
+			applyPatch = applyJSONPatch
+		default:
+			ResponseUnsupportedMediaType(c, MergePatchContentType, JSONPatchContentType)
+			return
+		}
+		patch, err := c.GetRawData()
+		if err != nil {
+			ResponseFailedToBindJson(c, err)
+			return
+		}
+		oldDoc, version, err := db.FindOneForCustomerWithVersion[T](c, db.NewFilterBuilder().WithGUID(guid), nil)
+		if err != nil {
+			ResponseInternalServerError(c, "failed to read document", err)
+			return
+		} else if oldDoc == nil {
+			ResponseDocumentNotFound(c)
+			return
+		}
+		c.Set(consts.ReadRevision, version.Revision)
+		newDoc, err := patchDoc(*oldDoc, patch, applyPatch)
+		if errors.Is(err, errJSONPatchTestFailed) {
+			ResponseConflict(c, err.Error())
+			return
+		} else if err != nil {
+			ResponseBadRequest(c, err.Error())
+			return
+		}
+		newDoc.SetGUID(guid)
+		patchedJson, _ := json.Marshal(newDoc)
+		//validate
+		for _, validator := range validators {
+			if docs, ok := validator(c, []T{newDoc}); !ok {
+				return
+			} else {
+				newDoc = docs[0]
+			}
+		}
+		//json patch operations can be translated to an update command only if the validators did not change the patched document
+		if c.ContentType() == JSONPatchContentType {
+			if validatedJson, _ := json.Marshal(newDoc); string(validatedJson) == string(patchedJson) {
+				operations, _ := parseJSONPatch(patch)
+				c.Set(consts.JSONPatchOperations, operations)
+			}
+		}
+		c.Set(consts.DocContentKey, []T{*oldDoc, newDoc})
+		c.Next()
+	}
+}
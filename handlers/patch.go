@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"config-service/db"
+	"config-service/types"
+	"config-service/utils/consts"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const MergePatchContentType = "application/merge-patch+json"
+
+// patchDoc applies the patch on the json representation of the document and returns the patched document
+func patchDoc[T types.DocContent](doc T, patch []byte, applyPatch func(doc []byte, patch []byte) ([]byte, error)) (T, error) {
+	var patched T
+	docJson, err := json.Marshal(doc)
+	if err != nil {
+		return patched, err
+	}
+	patchedJson, err := applyPatch(docJson, patch)
+	if err != nil {
+		return patched, err
+	}
+	if err := json.Unmarshal(patchedJson, &patched); err != nil {
+		return patched, fmt.Errorf("patched document is invalid: %w", err)
+	}
+	return patched, nil
+}
+
+// unmodifiedConditions returns the conditions matching the document only if it is still in the revision it was read in
+func unmodifiedConditions(c *gin.Context) bson.D {
+	if revision, ok := c.Value(consts.ReadRevision).(int64); ok {
+		return db.RevisionCondition(revision)
+	}
+	return bson.D{}
+}
+
+// applyMergePatch applies a JSON merge patch (RFC 7396) on the document
+func applyMergePatch(doc []byte, patch []byte) ([]byte, error) {
+	var patchValue interface{}
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, fmt.Errorf("invalid merge patch: %w", err)
+	}
+	if _, ok := patchValue.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("merge patch must be a json object")
+	}
+	var docValue interface{}
+	if err := json.Unmarshal(doc, &docValue); err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergePatch(docValue, patchValue))
+}
+
+// mergePatch merges the patch into the target, null values in the patch remove the target fields
+func mergePatch(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+		} else {
+			targetObj[key] = mergePatch(targetObj[key], value)
+		}
+	}
+	return targetObj
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"config-service/utils/consts"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestApplyMergePatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		patch   string
+		want    string
+		wantErr bool
+	}{
+		{name: "replace", doc: `{"a":"b"}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{name: "add", doc: `{"a":"b"}`, patch: `{"b":"c"}`, want: `{"a":"b","b":"c"}`},
+		{name: "remove", doc: `{"a":"b","b":"c"}`, patch: `{"a":null}`, want: `{"b":"c"}`},
+		{name: "nested", doc: `{"a":{"b":"c","d":"e"}}`, patch: `{"a":{"b":"x","d":null,"f":"g"}}`, want: `{"a":{"b":"x","f":"g"}}`},
+		{name: "replace array", doc: `{"a":["b","c"]}`, patch: `{"a":["d"]}`, want: `{"a":["d"]}`},
+		{name: "object replaces value", doc: `{"a":"b"}`, patch: `{"a":{"c":null,"d":"e"}}`, want: `{"a":{"d":"e"}}`},
+		{name: "empty patch", doc: `{"a":"b"}`, patch: `{}`, want: `{"a":"b"}`},
+		{name: "not an object", doc: `{"a":"b"}`, patch: `["a"]`, wantErr: true},
+		{name: "invalid json", doc: `{"a":"b"}`, patch: `{"a"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyMergePatch([]byte(tt.doc), []byte(tt.patch))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyMergePatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var gotValue, wantValue interface{}
+			_ = json.Unmarshal(got, &gotValue)
+			_ = json.Unmarshal([]byte(tt.want), &wantValue)
+			if !reflect.DeepEqual(gotValue, wantValue) {
+				t.Errorf("applyMergePatch() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmodifiedConditions(t *testing.T) {
+	tests := []struct {
+		name     string
+		revision interface{}
+		want     bson.D
+	}{
+		{name: "read revision", revision: int64(3), want: bson.D{{Key: consts.RevisionField, Value: int64(3)}}},
+		{name: "read document without revision", revision: int64(0), want: bson.D{{Key: consts.RevisionField, Value: bson.D{{Key: "$exists", Value: false}}}}},
+		{name: "no read revision", want: bson.D{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			if tt.revision != nil {
+				c.Set(consts.ReadRevision, tt.revision)
+			}
+			if got := unmodifiedConditions(c); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unmodifiedConditions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
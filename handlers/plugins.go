@@ -50,6 +50,18 @@ func GetCustomResponseSender[T types.DocContent](c *gin.Context) (ResponseSender
 	return nil, nil
 }
 
+func GetSortFields(c *gin.Context) []string {
+	if iFields, ok := c.Get(consts.SortFields); ok {
+		if fieldsNames, ok := iFields.([]string); ok {
+			return fieldsNames
+		}
+		err := fmt.Errorf("invalid sort fields type")
+		log.LogNTraceError("invalid sort fields type", err, c)
+		return nil
+	}
+	return nil
+}
+
 func GetCustomPutFields(c *gin.Context) []string {
 	if iFields, ok := c.Get(consts.PutDocFields); ok {
 		if fieldsNames, ok := iFields.([]string); ok {
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"config-service/db"
+	"config-service/utils/consts"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"k8s.io/utils/strings/slices"
+)
+
+// readFindOptions returns the find options of list queries according to the query params
+func readFindOptions(c *gin.Context) (*options.FindOptions, error) {
+	findOpts := options.Find()
+	if sort, err := readSort(c); err != nil {
+		return nil, err
+	} else if sort != nil {
+		findOpts.SetSort(sort)
+	}
+	if projection, err := readProjection(c); err != nil {
+		return nil, err
+	} else if projection != nil {
+		findOpts.SetProjection(projection)
+	}
+	return findOpts, nil
+}
+
+// readTextSearch adds the text search of the q query param to the filter, results are ranked by relevance unless sorted by the sort query param
+func readTextSearch(c *gin.Context, filterBuilder *db.FilterBuilder, findOpts *options.FindOptions) (*db.FilterBuilder, error) {
+	search := c.Query(consts.SearchParam)
+	if search == "" {
+		return filterBuilder, nil
+	}
+	if !c.GetBool(consts.TextSearch) {
+		return nil, fmt.Errorf("text search is not supported")
+	}
+	if filterBuilder == nil {
+		filterBuilder = db.NewFilterBuilder()
+	}
+	filterBuilder.WithTextSearch(search)
+	projection, _ := findOpts.Projection.(bson.D)
+	findOpts.SetProjection(append(projection, bson.E{Key: consts.TextScoreField, Value: db.TextScore}))
+	if findOpts.Sort == nil {
+		findOpts.SetSort(bson.D{{Key: consts.TextScoreField, Value: db.TextScore}})
+	}
+	return filterBuilder, nil
+}
+
+var fieldPathRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)*$`)
+
+// readProjection parses the fields query param (e.g. fields=name,guid,attributes.alias) to a projection, returns nil if the param is not set
+func readProjection(c *gin.Context) (bson.D, error) {
+	fieldsParam := c.Query(consts.FieldsParam)
+	if fieldsParam == "" {
+		return nil, nil
+	}
+	fields := []string{}
+	for _, field := range strings.Split(fieldsParam, ",") {
+		if !fieldPathRegex.MatchString(field) {
+			return nil, fmt.Errorf("invalid field %s", field)
+		}
+		for _, other := range fields {
+			if field == other || strings.HasPrefix(field, other+".") || strings.HasPrefix(other, field+".") {
+				return nil, fmt.Errorf("fields %s and %s overlap", other, field)
+			}
+		}
+		fields = append(fields, field)
+	}
+	return db.NewProjectionBuilder().Include(fields...).Get(), nil
+}
+
+// readSort parses the sort query param (e.g. sort=name,-updatedTime), returns nil if the param is not set
+// only the router sortable fields are allowed
+func readSort(c *gin.Context) (bson.D, error) {
+	sortParam := c.Query(consts.SortParam)
+	if sortParam == "" {
+		return nil, nil
+	}
+	sortFields := GetSortFields(c)
+	if len(sortFields) == 0 {
+		return nil, fmt.Errorf("sort is not supported")
+	}
+	sort := bson.D{}
+	for _, field := range strings.Split(sortParam, ",") {
+		order := 1
+		if strings.HasPrefix(field, "-") {
+			order = -1
+			field = field[1:]
+		} else {
+			field = strings.TrimPrefix(field, "+")
+		}
+		if !slices.Contains(sortFields, field) {
+			return nil, fmt.Errorf("sort by %s is not supported, sortable fields are: %s", field, strings.Join(sortFields, ","))
+		}
+		sort = append(sort, bson.E{Key: field, Value: order})
+	}
+	return sort, nil
+}
+
+// readPagination returns the pagination query params, nil if the request is not paginated
+func readPagination(c *gin.Context) (*db.Pagination, error) {
+	limitStr, limitOk := c.GetQuery(consts.LimitParam)
+	skipStr, skipOk := c.GetQuery(consts.SkipParam)
+	cursor, cursorOk := c.GetQuery(consts.CursorParam)
+	if !limitOk && !skipOk && !cursorOk {
+		return nil, nil
+	}
+	pagination := &db.Pagination{Cursor: cursor}
+	var err error
+	if limitOk {
+		if pagination.Limit, err = strconv.Atoi(limitStr); err != nil || pagination.Limit <= 0 {
+			return nil, fmt.Errorf("%s must be a positive number", consts.LimitParam)
+		}
+	}
+	if skipOk {
+		if pagination.Skip, err = strconv.Atoi(skipStr); err != nil || pagination.Skip < 0 {
+			return nil, fmt.Errorf("%s must be a non negative number", consts.SkipParam)
+		}
+	}
+	if skipOk && cursorOk {
+		return nil, fmt.Errorf("%s and %s cannot be used together", consts.SkipParam, consts.CursorParam)
+	}
+	return pagination, nil
+}
+
+// readSkipPagination returns the pagination query params of lists that are paginated by skip only, returns false if they are invalid and the response was sent
+func readSkipPagination(c *gin.Context) (db.Pagination, bool) {
+	pagination, err := readPagination(c)
+	if err != nil {
+		ResponseBadRequest(c, err.Error())
+		return db.Pagination{}, false
+	} else if pagination == nil {
+		return db.Pagination{}, true
+	} else if pagination.Cursor != "" {
+		ResponseBadRequest(c, consts.CursorParam+" is not supported, use "+consts.SkipParam)
+		return db.Pagination{}, false
+	}
+	return *pagination, true
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"config-service/db"
+	"config-service/utils/consts"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func newTestContext(query string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/test?"+query, nil)
+	return c
+}
+
+func TestReadPagination(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    *db.Pagination
+		wantErr bool
+	}{
+		{query: "", want: nil},
+		{query: "name=x", want: nil},
+		{query: "limit=10", want: &db.Pagination{Limit: 10}},
+		{query: "limit=10&skip=20", want: &db.Pagination{Limit: 10, Skip: 20}},
+		{query: "skip=5", want: &db.Pagination{Skip: 5}},
+		{query: "cursor=abc&limit=3", want: &db.Pagination{Limit: 3, Cursor: "abc"}},
+		{query: "limit=0", wantErr: true},
+		{query: "limit=ten", wantErr: true},
+		{query: "skip=-1", wantErr: true},
+		{query: "skip=1&cursor=abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := readPagination(newTestContext(tt.query))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readPagination() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("readPagination() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursor(t *testing.T) {
+	id, err := db.DecodeCursor(db.EncodeCursor("some-guid"))
+	if err != nil || id != "some-guid" {
+		t.Errorf("DecodeCursor(EncodeCursor()) = %s, %v", id, err)
+	}
+	if _, err := db.DecodeCursor("not base64!"); err != db.ErrInvalidCursor {
+		t.Errorf("DecodeCursor() error = %v, want %v", err, db.ErrInvalidCursor)
+	}
+}
+
+func TestReadSort(t *testing.T) {
+	tests := []struct {
+		query      string
+		sortFields []string
+		want       bson.D
+		wantErr    bool
+	}{
+		{query: "", sortFields: DefaultSortFields, want: nil},
+		{query: "sort=name", sortFields: DefaultSortFields, want: bson.D{{Key: "name", Value: 1}}},
+		{query: "sort=-updatedTime,%2Bname", sortFields: DefaultSortFields, want: bson.D{{Key: "updatedTime", Value: -1}, {Key: "name", Value: 1}}},
+		{query: "sort=attributes.alias", sortFields: DefaultSortFields, wantErr: true},
+		{query: "sort=name", sortFields: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			c := newTestContext(tt.query)
+			if tt.sortFields != nil {
+				c.Set(consts.SortFields, tt.sortFields)
+			}
+			got, err := readSort(c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readSort() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readSort() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadProjection(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    bson.D
+		wantErr bool
+	}{
+		{query: "", want: nil},
+		{query: "fields=name,guid", want: bson.D{{Key: "name", Value: 1}, {Key: "guid", Value: 1}}},
+		{query: "fields=attributes.alias", want: bson.D{{Key: "attributes.alias", Value: 1}}},
+		{query: "fields=name,", wantErr: true},
+		{query: "fields=$where", wantErr: true},
+		{query: "fields=attributes,attributes.alias", wantErr: true},
+		{query: "fields=name,name", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := readProjection(newTestContext(tt.query))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readProjection() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readProjection() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadTextSearch(t *testing.T) {
+	textScore := bson.E{Key: consts.TextScoreField, Value: db.TextScore}
+	tests := []struct {
+		name           string
+		query          string
+		enabled        bool
+		sort           bson.D
+		projection     bson.D
+		wantFilter     bson.D
+		wantSort       interface{}
+		wantProjection interface{}
+		wantErr        bool
+	}{
+		{name: "no search", query: "", enabled: true},
+		{name: "not supported", query: "q=nginx", enabled: false, wantErr: true},
+		{
+			name: "ranked", query: "q=nginx", enabled: true,
+			wantFilter:     bson.D{{Key: "$text", Value: bson.D{{Key: "$search", Value: "nginx"}}}},
+			wantSort:       bson.D{textScore},
+			wantProjection: bson.D{textScore},
+		},
+		{
+			name: "sorted with projection", query: "q=nginx", enabled: true,
+			sort:           bson.D{{Key: "name", Value: 1}},
+			projection:     bson.D{{Key: "name", Value: 1}},
+			wantFilter:     bson.D{{Key: "$text", Value: bson.D{{Key: "$search", Value: "nginx"}}}},
+			wantSort:       bson.D{{Key: "name", Value: 1}},
+			wantProjection: bson.D{{Key: "name", Value: 1}, textScore},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestContext(tt.query)
+			if tt.enabled {
+				c.Set(consts.TextSearch, true)
+			}
+			findOpts := options.Find()
+			if tt.sort != nil {
+				findOpts.SetSort(tt.sort)
+			}
+			if tt.projection != nil {
+				findOpts.SetProjection(tt.projection)
+			}
+			filterBuilder, err := readTextSearch(c, nil, findOpts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readTextSearch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var filter bson.D
+			if filterBuilder != nil {
+				filter = filterBuilder.Get()
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(filter, tt.wantFilter) {
+				t.Errorf("readTextSearch() filter = %v, want %v", filter, tt.wantFilter)
+			}
+			if !reflect.DeepEqual(findOpts.Sort, tt.wantSort) {
+				t.Errorf("readTextSearch() sort = %v, want %v", findOpts.Sort, tt.wantSort)
+			}
+			if !reflect.DeepEqual(findOpts.Projection, tt.wantProjection) {
+				t.Errorf("readTextSearch() projection = %v, want %v", findOpts.Projection, tt.wantProjection)
+			}
+		})
+	}
+}
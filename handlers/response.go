@@ -20,6 +20,7 @@ const (
 	//error messages
 	MissingKey       = "%s is required"
 	DocumentNotFound = "document not found"
+	DocumentModified = "document was modified, read it and try again"
 )
 
 var pluralize = plural.NewClient()
@@ -110,6 +111,27 @@ func ResponseBadRequest(c *gin.Context, msg string) {
 	c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": msg})
 }
 
+func ResponseForbidden(c *gin.Context, msg string) {
+	log.LogNTrace(msg, c)
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden - " + msg})
+}
+
+func ResponseConflict(c *gin.Context, msg string) {
+	log.LogNTrace(msg, c)
+	c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": msg})
+}
+
+func ResponsePreconditionFailed(c *gin.Context) {
+	log.LogNTrace(DocumentModified, c)
+	c.AbortWithStatusJSON(http.StatusPreconditionFailed, gin.H{"error": DocumentModified})
+}
+
+func ResponseUnsupportedMediaType(c *gin.Context, supportedTypes ...string) {
+	msg := "unsupported content type, supported types are: " + strings.Join(supportedTypes, ",")
+	log.LogNTrace(msg, c)
+	c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, gin.H{"error": msg})
+}
+
 func ResponseFailedToBindJson(c *gin.Context, err error) {
 	log.LogNTraceError("failed to bind json", err, c)
 	c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -128,6 +150,10 @@ func docResponse[T types.DocContent](c *gin.Context, doc *T) {
 
 }
 
+func pageResponse[T types.DocContent](c *gin.Context, page *db.AggResult[T]) {
+	ResponseJSONWithETag(c, http.StatusOK, page)
+}
+
 func docsResponse[T types.DocContent](c *gin.Context, docs []T) {
 	if docs == nil {
 		ResponseDocumentNotFound(c)
@@ -137,5 +163,5 @@ func docsResponse[T types.DocContent](c *gin.Context, docs []T) {
 		sender(c, nil, docs)
 		return
 	}
-	c.JSON(http.StatusOK, docs)
+	ResponseJSONWithETag(c, http.StatusOK, docs)
 }
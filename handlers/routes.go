@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"config-service/db"
 	"config-service/types"
 	"config-service/utils/consts"
+	"context"
 	"fmt"
 
 	"github.com/gin-gonic/gin"
@@ -10,30 +12,39 @@ import (
 
 // router options
 type routerOptions[T types.DocContent] struct {
-	dbCollection              string                     //mandatory db collection name
-	path                      string                     //mandatory uri path
-	serveGet                  bool                       //default true, serve GET /<path> to get all documents and GET /<path>/<GUID> to get document by GUID
-	serveGetNamesList         bool                       //default true, GET will return all documents names if "list" query param exist
-	serveGetWithGUIDOnly      bool                       //default false, GET will return the document by GUID only
-	serveGetIncludeGlobalDocs bool                       //default false, when true, in GET all the response will include global documents (with customers[""])
-	servePost                 bool                       //default true, serve POST
-	servePut                  bool                       //default true, serve PUT /<path> to update document by GUID in body and PUT /<path>/<GUID> to update document by GUID in path
-	serveDelete               bool                       //default true, serve DELETE  /<path>/<GUID> to delete document by GUID in path
-	serveDeleteByName         bool                       //default false, when true, DELETE will check for name param and will delete the document by name
-	validatePostUniqueName    bool                       //default true, POST will validate that the name is unique
-	validatePutGUID           bool                       //default true, PUT will validate GUID existence in body or path
-	nameQueryParam            string                     //default empty, the param name that indicates query by name (e.g. clusterName) when set GET will check for this param and will return the document by name
-	QueryConfig               *QueryParamsConfig         //default nil, when set, GET will check for the specified query params and will return the documents by the query params
-	uniqueShortName           func(T) string             //default nil, when set, POST will create a unique short name (aka "alias") attribute from the value returned from the function & Put will validate that the short name is not deleted
-	putValidators             []MutatorValidator[T]      //default nil, when set, PUT will call the mutators/validators before updating the document
-	postValidators            []MutatorValidator[T]      //default nil, when set, POST will call the mutators/validators before creating the document
-	bodyDecoder               BodyDecoder[T]             //default nil, when set, replace the default body decoder
-	responseSender            ResponseSender[T]          //default nil, when set, replace the default response sender
-	putFields                 []string                   //default nil, when set, PUT will update only the specified fields
+	dbCollection              string                    //mandatory db collection name
+	path                      string                    //mandatory uri path
+	serveGet                  bool                      //default true, serve GET /<path> to get all documents, GET /<path>/<GUID> to get document by GUID and GET /<path>/<GUID>/history[/<revision>] to get its previous revisions and GET /<path>/watch to stream the documents changes
+	serveGetNamesList         bool                      //default true, GET will return all documents names if "list" query param exist
+	serveGetWithGUIDOnly      bool                      //default false, GET will return the document by GUID only
+	serveGetIncludeGlobalDocs bool                      //default false, when true, in GET all the response will include global documents (with customers[""])
+	servePost                 bool                      //default true, serve POST
+	servePut                  bool                      //default true, serve PUT /<path> to update document by GUID in body and PUT /<path>/<GUID> to update document by GUID in path, PATCH /<path>/<GUID> to patch the document and POST /<path>/<GUID>/rollback/<revision> to restore a previous revision
+	serveDelete               bool                      //default true, serve DELETE  /<path>/<GUID> to delete document by GUID in path
+	serveDeleteByName         bool                      //default false, when true, DELETE will check for name param and will delete the document by name
+	serveRestore              bool                      //default false, when true, serve POST /<path>/<GUID>/restore to restore a deleted document
+	validatePostUniqueName    bool                      //default true, POST will validate that the name is unique
+	validatePutGUID           bool                      //default true, PUT will validate GUID existence in body or path
+	nameQueryParam            string                    //default empty, the param name that indicates query by name (e.g. clusterName) when set GET will check for this param and will return the document by name
+	QueryConfig               *QueryParamsConfig        //default FilterQueryConfig, GET will check for the specified query params and will return the documents by the query params
+	uniqueShortName           func(T) string            //default nil, when set, POST will create a unique short name (aka "alias") attribute from the value returned from the function & Put will validate that the short name is not deleted
+	putValidators             []MutatorValidator[T]     //default nil, when set, PUT will call the mutators/validators before updating the document
+	postValidators            []MutatorValidator[T]     //default nil, when set, POST will call the mutators/validators before creating the document
+	bodyDecoder               BodyDecoder[T]            //default nil, when set, replace the default body decoder
+	responseSender            ResponseSender[T]         //default nil, when set, replace the default response sender
+	putFields                 []string                  //default nil, when set, PUT will update only the specified fields
 	containersHandlers        []containerHandlerOptions //default nil, list of container handlers to put and remove items from document's containers
+	middlewares               []gin.HandlerFunc         //default nil, when set, the middlewares are added to the router group before the routes handlers
+	routeGroup                string                    //default config, the route group used to authorize requests by the session roles
+	sortFields                []string                  //default nil, fields that list GET requests can sort by with the sort query param
+	textSearchFields          []string                  //default nil, when set, a text index is created on the fields and list GET requests can search with the q query param
+	upsert                    bool                      //default false, when true, PUT /<path> creates the document by name if it does not exist unless upsert=false query param is set, when false upsert=true query param does it
 
 }
 
+// DefaultSortFields are the sortable fields of routers with common document fields
+var DefaultSortFields = []string{consts.NameField, consts.GUIDField, consts.UpdatedTimeField}
+
 type ContainerType string
 
 const (
@@ -60,6 +71,8 @@ func newRouterOptions[T types.DocContent]() *routerOptions[T] {
 		serveGetNamesList:         true,
 		serveGetIncludeGlobalDocs: false,
 		serveDeleteByName:         false,
+		routeGroup:                consts.RouteGroupConfig,
+		QueryConfig:               FilterQueryConfig(),
 	}
 }
 
@@ -71,7 +84,9 @@ func AddRoutes[T types.DocContent](g *gin.Engine, options ...RouterOption[T]) *g
 	}
 	routerGroup := g.Group(opts.path)
 	//add middleware
+	routerGroup.Use(AuthorizationMiddleware(opts.routeGroup))
 	routerGroup.Use(DBContextMiddleware(opts.dbCollection))
+	routerGroup.Use(AuditMiddleware)
 	if opts.responseSender != nil {
 		routerGroup.Use(ResponseSenderContextMiddleware(&opts.responseSender))
 	}
@@ -81,23 +96,43 @@ func AddRoutes[T types.DocContent](g *gin.Engine, options ...RouterOption[T]) *g
 	if opts.putFields != nil {
 		routerGroup.Use(PutFieldsContextMiddleware(opts.putFields))
 	}
+	if opts.sortFields != nil {
+		routerGroup.Use(SortFieldsContextMiddleware(opts.sortFields))
+	}
+	if len(opts.textSearchFields) > 0 {
+		if err := db.EnsureTextIndex(context.Background(), opts.dbCollection, opts.textSearchFields); err != nil {
+			panic(err)
+		}
+		routerGroup.Use(TextSearchContextMiddleware)
+	}
+	if opts.middlewares != nil {
+		routerGroup.Use(opts.middlewares...)
+	}
+	if opts.servePut || opts.serveDelete {
+		if err := db.EnsureHistoryIndex(context.Background(), opts.dbCollection); err != nil {
+			panic(err)
+		}
+	}
 
 	//add routes
 	if opts.serveGet {
 		if !opts.serveGetWithGUIDOnly {
 			routerGroup.GET("", HandleGet(opts))
 		}
+		routerGroup.GET("/watch", HandleWatch[T])
 		routerGroup.GET("/:"+consts.GUIDField, HandleGetDocWithGUIDInPath[T])
+		routerGroup.GET("/:"+consts.GUIDField+"/history", HandleGetHistory[T])
+		routerGroup.GET("/:"+consts.GUIDField+"/history/:"+consts.RevisionField, HandleGetHistoryRevision[T])
+	}
+	postValidators := []MutatorValidator[T]{}
+	if opts.validatePostUniqueName {
+		postValidators = append(postValidators, ValidateUniqueValues(NameKeyGetter[T]))
+	}
+	if opts.uniqueShortName != nil {
+		postValidators = append(postValidators, ValidatePostAttributeShortName(opts.uniqueShortName))
 	}
+	postValidators = append(postValidators, opts.postValidators...)
 	if opts.servePost {
-		postValidators := []MutatorValidator[T]{}
-		if opts.validatePostUniqueName {
-			postValidators = append(postValidators, ValidateUniqueValues(NameKeyGetter[T]))
-		}
-		if opts.uniqueShortName != nil {
-			postValidators = append(postValidators, ValidatePostAttributeShortName(opts.uniqueShortName))
-		}
-		postValidators = append(postValidators, opts.postValidators...)
 		routerGroup.POST("", HandlePostDocWithValidation(postValidators...)...)
 	}
 	if opts.servePut {
@@ -109,8 +144,14 @@ func AddRoutes[T types.DocContent](g *gin.Engine, options ...RouterOption[T]) *g
 			putValidators = append(putValidators, ValidatePutAttributerShortName[T])
 		}
 		putValidators = append(putValidators, opts.putValidators...)
-		routerGroup.PUT("", HandlePutDocWithValidation(putValidators...)...)
+		if opts.servePost {
+			routerGroup.PUT("", HandlePutDocWithUpsert(opts.upsert, postValidators, putValidators)...)
+		} else {
+			routerGroup.PUT("", HandlePutDocWithValidation(putValidators...)...)
+		}
 		routerGroup.PUT("/:"+consts.GUIDField, HandlePutDocWithValidation(putValidators...)...)
+		routerGroup.PATCH("/:"+consts.GUIDField, HandlePatchDocWithValidation(putValidators...)...)
+		routerGroup.POST("/:"+consts.GUIDField+"/rollback/:"+consts.RevisionField, HandleRollbackDoc(putValidators...))
 	}
 	if opts.serveDelete {
 		if opts.serveDeleteByName {
@@ -118,22 +159,25 @@ func AddRoutes[T types.DocContent](g *gin.Engine, options ...RouterOption[T]) *g
 		}
 		routerGroup.DELETE("/:"+consts.GUIDField, HandleDeleteDoc[T])
 	}
+	if opts.serveRestore {
+		routerGroup.POST("/:"+consts.GUIDField+"/restore", HandleRestoreDoc(postValidators...))
+	}
 	//add array handlers
 	for _, containerHandler := range opts.containersHandlers {
 		switch containerHandler.containerType {
 		case ContainerTypeArray:
 			if containerHandler.servePut {
-				routerGroup.PUT(containerHandler.path, HandlerAddToArray(containerHandler.ContainerHandler))
+				routerGroup.PUT(containerHandler.path, HandlerAddToArray[T](containerHandler.ContainerHandler))
 			}
 			if containerHandler.serveDelete {
-				routerGroup.DELETE(containerHandler.path, HandlerRemoveFromArray(containerHandler.ContainerHandler))
+				routerGroup.DELETE(containerHandler.path, HandlerRemoveFromArray[T](containerHandler.ContainerHandler))
 			}
 		case ContainerTypeMap:
 			if containerHandler.servePut {
-				routerGroup.PUT(containerHandler.path, HandlerSetField(containerHandler.ContainerHandler, true))
+				routerGroup.PUT(containerHandler.path, HandlerSetField[T](containerHandler.ContainerHandler, true))
 			}
 			if containerHandler.serveDelete {
-				routerGroup.DELETE(containerHandler.path, HandlerSetField(containerHandler.ContainerHandler, false))
+				routerGroup.DELETE(containerHandler.path, HandlerSetField[T](containerHandler.ContainerHandler, false))
 			}
 		}
 	}
@@ -141,7 +185,8 @@ func AddRoutes[T types.DocContent](g *gin.Engine, options ...RouterOption[T]) *g
 }
 
 // Common router config for policies
-func AddPolicyRoutes[T types.DocContent](g *gin.Engine, path, dbCollection string, paramConf *QueryParamsConfig) *gin.RouterGroup {
+// textSearchFields are the fields searched by the q query param of list GET requests
+func AddPolicyRoutes[T types.DocContent](g *gin.Engine, path, dbCollection string, paramConf *QueryParamsConfig, textSearchFields ...string) *gin.RouterGroup {
 	return AddRoutes(g, NewRouterOptionsBuilder[T]().
 		WithPath(path).
 		WithDBCollection(dbCollection).
@@ -149,8 +194,12 @@ func AddPolicyRoutes[T types.DocContent](g *gin.Engine, path, dbCollection strin
 		WithQueryConfig(paramConf).
 		WithIncludeGlobalDocs(true).
 		WithDeleteByName(true).
+		WithServeRestore(true).
 		WithValidatePostUniqueName(true).
 		WithValidatePutGUID(true).
+		WithRouteGroup(consts.RouteGroupSecurity).
+		WithSortFields(DefaultSortFields...).
+		WithTextSearch(textSearchFields...).
 		Get()...)
 }
 
@@ -170,9 +219,18 @@ func (opts *routerOptions[T]) validate() error {
 	if opts.serveDeleteByName && !opts.serveDelete {
 		return fmt.Errorf("serveDeleteByName can only be true when serveDelete is true")
 	}
+	if opts.serveRestore && !opts.serveDelete {
+		return fmt.Errorf("serveRestore can only be true when serveDelete is true")
+	}
 	if opts.uniqueShortName != nil && (!opts.servePost || !opts.servePut) {
 		return fmt.Errorf("uniqueShortName can only be set when servePost and servePut are true")
 	}
+	if opts.upsert && (!opts.servePost || !opts.servePut) {
+		return fmt.Errorf("upsert can only be true when servePost and servePut are true")
+	}
+	if opts.routeGroup == "" {
+		return fmt.Errorf("routeGroup must be set")
+	}
 	if opts.serveGetWithGUIDOnly && !opts.serveGet {
 		return fmt.Errorf("serveGetWithGUIDOnly can only be true when serveGet is true")
 	}
@@ -200,6 +258,35 @@ func (b *RouterOptionsBuilder[T]) WithPutFields(fields []string) *RouterOptionsB
 	return b
 }
 
+// WithTextSearch creates a text index on the fields, list GET requests can search them with the q query param
+func (b *RouterOptionsBuilder[T]) WithTextSearch(fields ...string) *RouterOptionsBuilder[T] {
+	b.options = append(b.options, func(opts *routerOptions[T]) {
+		opts.textSearchFields = fields
+	})
+	return b
+}
+
+func (b *RouterOptionsBuilder[T]) WithSortFields(fields ...string) *RouterOptionsBuilder[T] {
+	b.options = append(b.options, func(opts *routerOptions[T]) {
+		opts.sortFields = fields
+	})
+	return b
+}
+
+func (b *RouterOptionsBuilder[T]) WithMiddlewares(middlewares ...gin.HandlerFunc) *RouterOptionsBuilder[T] {
+	b.options = append(b.options, func(opts *routerOptions[T]) {
+		opts.middlewares = append(opts.middlewares, middlewares...)
+	})
+	return b
+}
+
+func (b *RouterOptionsBuilder[T]) WithRouteGroup(routeGroup string) *RouterOptionsBuilder[T] {
+	b.options = append(b.options, func(opts *routerOptions[T]) {
+		opts.routeGroup = routeGroup
+	})
+	return b
+}
+
 func (b *RouterOptionsBuilder[T]) WithBodyDecoder(decoder BodyDecoder[T]) *RouterOptionsBuilder[T] {
 	b.options = append(b.options, func(opts *routerOptions[T]) {
 		opts.bodyDecoder = decoder
@@ -221,6 +308,13 @@ func (b *RouterOptionsBuilder[T]) WithDBCollection(dbCollection string) *RouterO
 	return b
 }
 
+func (b *RouterOptionsBuilder[T]) WithUpsert(upsert bool) *RouterOptionsBuilder[T] {
+	b.options = append(b.options, func(opts *routerOptions[T]) {
+		opts.upsert = upsert
+	})
+	return b
+}
+
 func (b *RouterOptionsBuilder[T]) WithPath(path string) *RouterOptionsBuilder[T] {
 	b.options = append(b.options, func(opts *routerOptions[T]) {
 		opts.path = path
@@ -277,6 +371,13 @@ func (b *RouterOptionsBuilder[T]) WithDeleteByName(serveDeleteByName bool) *Rout
 	return b
 }
 
+func (b *RouterOptionsBuilder[T]) WithServeRestore(serveRestore bool) *RouterOptionsBuilder[T] {
+	b.options = append(b.options, func(opts *routerOptions[T]) {
+		opts.serveRestore = serveRestore
+	})
+	return b
+}
+
 func (b *RouterOptionsBuilder[T]) WithNameQuery(nameQueryParam string) *RouterOptionsBuilder[T] {
 	b.options = append(b.options, func(opts *routerOptions[T]) {
 		opts.nameQueryParam = nameQueryParam
@@ -1,8 +1,26 @@
 package handlers
 
+import (
+	"config-service/db"
+	"config-service/utils/consts"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// reservedQueryParams are query params of the generic handlers, they are not used as scope query params
+var reservedQueryParams = []string{consts.LimitParam, consts.SkipParam, consts.CursorParam, consts.SortParam, consts.FieldsParam, consts.FilterParam, consts.SearchParam, consts.DeletedParam}
+
+// DefaultFilterFields are the fields that can be used in filter expressions of the default query config
+var DefaultFilterFields = []string{consts.NameField, consts.GUIDField, consts.UpdatedTimeField, consts.AttributesField}
+
 type QueryParamsConfig struct {
 	Params2Query   map[string]QueryConfig
 	DefaultContext string
+	FilterFields   []string //fields (and their nested fields) allowed in the filter query param, filter is not supported if empty
 }
 
 type QueryConfig struct {
@@ -14,6 +32,7 @@ type QueryConfig struct {
 func DefaultQueryConfig() *QueryParamsConfig {
 	return &QueryParamsConfig{
 		DefaultContext: "attributes",
+		FilterFields:   DefaultFilterFields,
 		Params2Query: map[string]QueryConfig{
 			"attributes": {
 				FieldName:   "attributes",
@@ -35,5 +54,96 @@ func FlatQueryConfig() *QueryParamsConfig {
 			},
 		},
 		DefaultContext: "",
+		FilterFields:   DefaultFilterFields,
+	}
+}
+
+// filter query config - for routers without scope params, only the filter query param is served
+func FilterQueryConfig() *QueryParamsConfig {
+	return &QueryParamsConfig{
+		Params2Query: map[string]QueryConfig{},
+		FilterFields: DefaultFilterFields,
+	}
+}
+
+// query param operators, e.g. attributes.cluster[ne]=x
+const (
+	OperatorNotEqual = "ne"
+	OperatorRegex    = "regex"
+	OperatorExists   = "exists"
+	OperatorGreater  = "gt"
+	OperatorLower    = "lt"
+	OperatorIn       = "in"
+)
+
+// maxRegexLength bounds the patterns of the regex operator
+const maxRegexLength = 128
+
+var queryOperatorRegex = regexp.MustCompile(`^(.+)\[([a-z]+)\]$`)
+
+// splitQueryOperator returns the param name without the operator suffix and the operator, the operator is empty if the param has no suffix
+func splitQueryOperator(paramKey string) (string, string) {
+	if match := queryOperatorRegex.FindStringSubmatch(paramKey); match != nil {
+		return match[1], match[2]
+	}
+	return paramKey, ""
+}
+
+// operatorCondition returns the filter of the operator on the key values
+func operatorCondition(key, operator string, values []string) (bson.D, error) {
+	single := func() (string, error) {
+		if len(values) > 1 {
+			return "", fmt.Errorf("operator %s accepts a single value", operator)
+		}
+		return values[0], nil
+	}
+	filterBuilder := db.NewFilterBuilder()
+	switch operator {
+	case OperatorNotEqual:
+		if len(values) > 1 {
+			return filterBuilder.WithNotIn(key, values).Get(), nil
+		}
+		return filterBuilder.WithNotEqual(key, values[0]).Get(), nil
+	case OperatorIn:
+		in := []string{}
+		for _, v := range values {
+			in = append(in, strings.Split(v, ",")...)
+		}
+		return filterBuilder.WithIn(key, in).Get(), nil
+	case OperatorRegex:
+		pattern, err := single()
+		if err != nil {
+			return nil, err
+		}
+		if len(pattern) > maxRegexLength {
+			return nil, fmt.Errorf("regex is longer than %d characters", maxRegexLength)
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid regex %s", pattern)
+		}
+		return filterBuilder.WithRegex(key, pattern).Get(), nil
+	case OperatorExists:
+		value, err := single()
+		if err != nil {
+			return nil, err
+		}
+		exists, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("operator %s value must be true or false", operator)
+		}
+		return filterBuilder.WithExists(key, exists).Get(), nil
+	case OperatorGreater:
+		value, err := single()
+		if err != nil {
+			return nil, err
+		}
+		return filterBuilder.WithGreaterThan(key, db.NormalizeTime(value)).Get(), nil
+	case OperatorLower:
+		value, err := single()
+		if err != nil {
+			return nil, err
+		}
+		return filterBuilder.WithLowerThan(key, db.NormalizeTime(value)).Get(), nil
 	}
+	return nil, fmt.Errorf("unsupported operator %s", operator)
 }
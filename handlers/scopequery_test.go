@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestSplitQueryOperator(t *testing.T) {
+	tests := []struct {
+		paramKey, wantKey, wantOperator string
+	}{
+		{"attributes.cluster", "attributes.cluster", ""},
+		{"attributes.cluster[ne]", "attributes.cluster", "ne"},
+		{"cluster[regex]", "cluster", "regex"},
+		{"cluster[]", "cluster[]", ""},
+	}
+	for _, tt := range tests {
+		key, operator := splitQueryOperator(tt.paramKey)
+		if key != tt.wantKey || operator != tt.wantOperator {
+			t.Errorf("splitQueryOperator(%s) = %s, %s, want %s, %s", tt.paramKey, key, operator, tt.wantKey, tt.wantOperator)
+		}
+	}
+}
+
+func TestOperatorCondition(t *testing.T) {
+	tests := []struct {
+		name     string
+		operator string
+		values   []string
+		want     bson.D
+		wantErr  bool
+	}{
+		{name: "ne", operator: OperatorNotEqual, values: []string{"x"}, want: bson.D{{Key: "k", Value: bson.D{{Key: "$ne", Value: "x"}}}}},
+		{name: "ne multiple", operator: OperatorNotEqual, values: []string{"x", "y"}, want: bson.D{{Key: "k", Value: bson.D{{Key: "$nin", Value: []string{"x", "y"}}}}}},
+		{name: "in", operator: OperatorIn, values: []string{"x,y", "z"}, want: bson.D{{Key: "k", Value: bson.D{{Key: "$in", Value: []string{"x", "y", "z"}}}}}},
+		{name: "regex", operator: OperatorRegex, values: []string{"^kube-"}, want: bson.D{{Key: "k", Value: bson.D{{Key: "$regex", Value: "^kube-"}}}}},
+		{name: "bad regex", operator: OperatorRegex, values: []string{"("}, wantErr: true},
+		{name: "long regex", operator: OperatorRegex, values: []string{strings.Repeat("a", maxRegexLength+1)}, wantErr: true},
+		{name: "exists", operator: OperatorExists, values: []string{"false"}, want: bson.D{{Key: "k", Value: bson.D{{Key: "$exists", Value: false}}}}},
+		{name: "bad exists", operator: OperatorExists, values: []string{"maybe"}, wantErr: true},
+		{name: "gt date", operator: OperatorGreater, values: []string{"2023-01-02"}, want: bson.D{{Key: "k", Value: bson.D{{Key: "$gt", Value: "2023-01-02T00:00:00Z"}}}}},
+		{name: "lt time", operator: OperatorLower, values: []string{"2023-01-02T03:00:00+02:00"}, want: bson.D{{Key: "k", Value: bson.D{{Key: "$lt", Value: "2023-01-02T01:00:00Z"}}}}},
+		{name: "gt multiple", operator: OperatorGreater, values: []string{"a", "b"}, wantErr: true},
+		{name: "unsupported", operator: "gte", values: []string{"a"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := operatorCondition("k", tt.operator, tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("operatorCondition() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("operatorCondition() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
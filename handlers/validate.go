@@ -62,10 +62,14 @@ func ValidateUniqueValues[T types.DocContent](uniqueKeyValues ...UniqueKeyValueI
 			keys2Values[key] = values
 		}
 
-		if existingDocs, err := db.FindForCustomer[T](c, filter, projection.Get()); err != nil {
+		existingDocs, err := db.FindForCustomer[T](c, filter, projection.Get())
+		if err != nil {
 			ResponseInternalServerError(c, "failed to read documents", err)
 			return nil, false
-		} else if len(existingDocs) > 0 {
+		}
+		//documents of the same bulk request that were already validated
+		existingDocs = append(existingDocs, GetBulkValidDocs[T](c)...)
+		if len(existingDocs) > 0 {
 			key2ExistingValues := map[string][]string{}
 			for _, uniqueKeyValue := range uniqueKeyValues {
 				key, _, valueGetter := uniqueKeyValue()
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"config-service/db"
+	"config-service/types"
+	"config-service/utils/consts"
+	"config-service/utils/log"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// interval of the comments sent to keep idle watch connections open
+const watchKeepAliveInterval = 30 * time.Second
+
+// WatchEventData is the data of the server-sent events of document changes, the event name is the change type
+type WatchEventData[T types.DocContent] struct {
+	GUID     string `json:"guid"`
+	Document T      `json:"document"` //the document after the change, or before a delete
+}
+
+// HandleWatch - stream the changes of the customer documents as server-sent events
+// clients resume after the last received event with the Last-Event-ID header
+func HandleWatch[T types.DocContent](c *gin.Context) {
+	defer log.LogNTraceEnterExit("HandleWatch", c)()
+	events, err := db.WatchForCustomer(c, c.GetHeader(consts.LastEventIDHeader))
+	if errors.Is(err, db.ErrInvalidResumeToken) {
+		ResponseBadRequest(c, consts.LastEventIDHeader+" is invalid or expired")
+		return
+	} else if err != nil {
+		ResponseInternalServerError(c, "failed to watch documents", err)
+		return
+	}
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+	keepAlive := time.NewTicker(watchKeepAliveInterval)
+	defer keepAlive.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			var doc T
+			if err := bson.Unmarshal(event.Document, &doc); err != nil {
+				log.LogNTraceError(fmt.Sprintf("failed to decode changed document %s", event.GUID), err, c)
+				return true
+			}
+			c.Render(-1, sse.Event{Id: event.ID, Event: event.Type, Data: WatchEventData[T]{GUID: event.GUID, Document: doc}})
+			return true
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ":\n\n")
+			return err == nil
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"bufio"
+	"config-service/db"
+	"config-service/types"
+	"config-service/utils/consts"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/armosec/armoapi-go/armotypes"
+	"github.com/gin-gonic/gin"
+)
+
+func TestHandleWatch(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.ContextWithFallback = true
+	router.GET("/clusters/watch", func(c *gin.Context) {
+		c.Set(consts.Collection, consts.ClustersCollection)
+		c.Set(consts.CustomerGUID, c.Query("customer"))
+		c.Next()
+	}, HandleWatch[*types.Cluster])
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watch := func(customer, lastEventID string) *http.Response {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/clusters/watch?customer="+customer, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lastEventID != "" {
+			req.Header.Set(consts.LastEventIDHeader, lastEventID)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	publish := func(customer, eventType, name string) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Set(consts.Collection, consts.ClustersCollection)
+		c.Set(consts.CustomerGUID, customer)
+		if err := db.PublishChange(c, eventType, &types.Cluster{PortalBase: armotypes.PortalBase{GUID: name + "-guid", Name: name}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	readEvent := func(reader *bufio.Reader) string {
+		event := []string{}
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			if line = strings.TrimSuffix(line, "\n"); line == "" {
+				return strings.Join(event, "\n")
+			}
+			event = append(event, line)
+		}
+	}
+
+	res := watch("watch-customer", "")
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("watch response status = %d content type = %s", res.StatusCode, res.Header.Get("Content-Type"))
+	}
+	publish("other-customer", db.ChangeEventCreate, "other")
+	publish("watch-customer", db.ChangeEventCreate, "first")
+	publish("watch-customer", db.ChangeEventDelete, "second")
+	reader := bufio.NewReader(res.Body)
+	first := readEvent(reader)
+	if !strings.Contains(first, "event:create") || !strings.Contains(first, `"guid":"first-guid"`) || !strings.Contains(first, `"name":"first"`) {
+		t.Errorf("first event = %s", first)
+	}
+	second := readEvent(reader)
+	if !strings.Contains(second, "event:delete") || !strings.Contains(second, `"guid":"second-guid"`) {
+		t.Errorf("second event = %s", second)
+	}
+
+	//resume after the first event
+	firstID := strings.TrimPrefix(strings.Split(first, "\n")[0], "id:")
+	resumed := watch("watch-customer", firstID)
+	defer resumed.Body.Close()
+	if resumed := readEvent(bufio.NewReader(resumed.Body)); resumed != second {
+		t.Errorf("resumed event = %s, want %s", resumed, second)
+	}
+
+	if res := watch("watch-customer", "not-an-id"); res.StatusCode != http.StatusBadRequest {
+		t.Errorf("watch with invalid %s status = %d, want %d", consts.LastEventIDHeader, res.StatusCode, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"config-service/db"
+	"config-service/types"
+	"config-service/utils/consts"
+	"config-service/utils/log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleGetWebhookDeliveries - get a page of the delivery log of the webhook by id in path, latest first
+// the status query param filters the deliveries by status (e.g. deadLetter)
+func HandleGetWebhookDeliveries(c *gin.Context) {
+	defer log.LogNTraceEnterExit("HandleGetWebhookDeliveries", c)()
+	guid := c.Param(consts.GUIDField)
+	if guid == "" {
+		ResponseMissingGUID(c)
+		return
+	}
+	pagination, ok := readSkipPagination(c)
+	if !ok {
+		return
+	}
+	if webhook, err := db.GetDocByGUID[*types.Webhook](c, guid); err != nil {
+		ResponseInternalServerError(c, "failed to read webhook", err)
+		return
+	} else if webhook == nil {
+		ResponseDocumentNotFound(c)
+		return
+	}
+	if page, err := db.FindWebhookDeliveries(c, guid, c.Query(consts.StatusParam), pagination); err != nil {
+		ResponseInternalServerError(c, "failed to read webhook deliveries", err)
+	} else {
+		c.JSON(http.StatusOK, page)
+	}
+}
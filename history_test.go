@@ -0,0 +1,80 @@
+package main
+
+import (
+	"config-service/db"
+	"config-service/handlers"
+	"config-service/types"
+	"config-service/utils/consts"
+	"fmt"
+	"net/http"
+
+	"github.com/armosec/armoapi-go/armotypes"
+)
+
+func (suite *MainTestSuite) TestHistory() {
+	suite.login("history-customer")
+	cluster := &types.Cluster{PortalBase: armotypes.PortalBase{Name: "history-cluster", Attributes: map[string]interface{}{"env": "dev"}}}
+	original := testPostDoc(suite, consts.ClusterPath, cluster, newClusterCompareFilter)
+	path := consts.ClusterPath + "/" + original.GUID
+	historyPath := path + "/history"
+
+	//revision 1 by PUT and revision 2 by PATCH
+	updated := testGetDoc(suite, path, original, newClusterCompareFilter)
+	updated.Attributes["env"] = "prod"
+	w := suite.doRequest(http.MethodPut, consts.ClusterPath, updated)
+	suite.Equal(http.StatusOK, w.Code, w.Body.String())
+	suite.requestHeaders = map[string]string{"Content-Type": handlers.MergePatchContentType}
+	w = suite.doRequest(http.MethodPatch, path, map[string]interface{}{"attributes": map[string]interface{}{"env": "staging"}})
+	suite.requestHeaders = nil
+	suite.Equal(http.StatusOK, w.Code, w.Body.String())
+	updated.Attributes["env"] = "staging"
+	current := testGetDoc(suite, path, updated, newClusterCompareFilter)
+
+	getHistory := func() []db.HistoryRecord[*types.Cluster] {
+		w := suite.doRequest(http.MethodGet, historyPath, nil)
+		suite.Equal(http.StatusOK, w.Code, w.Body.String())
+		records, err := decodeResponseArray[db.HistoryRecord[*types.Cluster]](w)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+		return records
+	}
+	records := getHistory()
+	suite.Len(records, 2)
+	for i, record := range records {
+		suite.Equal(int64(1-i), record.Revision)
+		suite.Equal(db.HistoryOperationUpdate, record.Operation)
+		suite.Equal(original.GUID, record.GUID)
+	}
+	suite.Equal("prod", records[0].Document.Attributes["env"])
+
+	//get revisions
+	testGetDoc(suite, historyPath+"/0", original, newClusterCompareFilter)
+	testGetDoc(suite, historyPath+"/2", current, newClusterCompareFilter)
+	testBadRequest(suite, http.MethodGet, historyPath+"/3", errorDocumentNotFound, nil, http.StatusNotFound)
+	testBadRequest(suite, http.MethodGet, historyPath+"/latest", `{"error":"revision must be a non negative number"}`, nil, http.StatusBadRequest)
+
+	//rollback
+	w = suite.doRequest(http.MethodPost, path+"/rollback/0", nil)
+	suite.Equal(http.StatusOK, w.Code, w.Body.String())
+	testGetDoc(suite, path, original, newClusterCompareFilter)
+	testBadRequest(suite, http.MethodPost, path+"/rollback/7", errorDocumentNotFound, nil, http.StatusNotFound)
+	records = getHistory()
+	suite.Len(records, 3)
+	suite.Equal("staging", records[0].Document.Attributes["env"])
+
+	//delete
+	testDeleteDocByGUID(suite, consts.ClusterPath, original, newClusterCompareFilter)
+	records = getHistory()
+	suite.Len(records, 4)
+	suite.Equal(db.HistoryOperationDelete, records[0].Operation)
+	suite.Equal(int64(3), records[0].Revision)
+	testBadRequest(suite, http.MethodPost, path+"/rollback/1", errorDocumentNotFound, nil, http.StatusNotFound)
+
+	//other customers history
+	suite.login("history-other-customer")
+	w = suite.doRequest(http.MethodGet, historyPath, nil)
+	suite.Equal(http.StatusOK, w.Code)
+	suite.Equal("[]", w.Body.String())
+	testBadRequest(suite, http.MethodGet, fmt.Sprintf("%s/%d", historyPath, 0), errorDocumentNotFound, nil, http.StatusNotFound)
+}
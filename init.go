@@ -7,6 +7,8 @@ import (
 	"config-service/db"
 	"config-service/db/mongo"
 	"config-service/utils"
+	"config-service/utils/auth"
+	"config-service/utils/webhook"
 	"context"
 	"log"
 	"os"
@@ -26,6 +28,7 @@ import (
 
 var zapLogger *zap.Logger
 var zapInfoLevelLogger *zap.Logger
+var jwtValidator *auth.JWTValidator
 
 func initialize() (shutdown func()) {
 	conf := utils.GetConfig()
@@ -33,13 +36,35 @@ func initialize() (shutdown func()) {
 	initLogger(conf.LoggerConfig)
 	//init tracer
 	tracer := initTracer(conf.Telemetry)
+	//init bearer token authentication
+	initAuth(conf.Auth)
 	//connect db
 	mongo.MustConnect(conf.Mongo)
 	//init db library
 	db.Init()
+	//init sessions store
+	if err := auth.InitSessionStore(context.Background()); err != nil {
+		zapLogger.Fatal("failed to initialize sessions store", zap.Error(err))
+	}
+	//init api keys store
+	if err := auth.InitAPIKeyStore(context.Background()); err != nil {
+		zapLogger.Fatal("failed to initialize api keys store", zap.Error(err))
+	}
+	//start deleted documents purge job
+	stopPurgeJob := func() {}
+	if !conf.SoftDelete.DisablePurgeJob {
+		stopPurgeJob = db.StartPurgeJob(conf.SoftDelete.GetRetention(), conf.SoftDelete.GetPurgeInterval())
+	}
+	//start webhooks dispatcher
+	stopWebhooks := func() {}
+	if !conf.Webhooks.Disabled {
+		stopWebhooks = webhook.Start(conf.Webhooks)
+	}
 
 	//shutdown function
 	shutdown = func() {
+		stopPurgeJob()
+		stopWebhooks()
 		mongo.Disconnect()
 		if err := tracer.Shutdown(context.Background()); err != nil {
 			log.Printf("Error shutting down tracer provider: %v", err)
@@ -89,6 +114,17 @@ func newZapConf(lvl zap.AtomicLevel, config utils.LoggerConfig) zap.Config {
 	return zapConf
 }
 
+// initAuth initializes the JWT validator when a key set is configured
+func initAuth(config utils.AuthConfig) {
+	if !config.JWT.Enabled() {
+		return
+	}
+	var err error
+	if jwtValidator, err = auth.NewJWTValidator(config.JWT); err != nil {
+		zapLogger.Fatal("failed to initialize jwt validator", zap.Error(err))
+	}
+}
+
 // initTracer used to initialize tracer
 func initTracer(config utils.TelemetryConfig) *sdktrace.TracerProvider {
 	serviceName := "config-service"
@@ -4,6 +4,8 @@ import (
 	"config-service/routes/login"
 	"config-service/routes/prob"
 	"config-service/routes/v1/admin"
+	"config-service/routes/v1/api_key"
+	"config-service/routes/v1/audit"
 	"config-service/routes/v1/cluster"
 	"config-service/routes/v1/customer"
 	"config-service/routes/v1/customer_config"
@@ -12,6 +14,7 @@ import (
 	"config-service/routes/v1/registry_cron_job"
 	"config-service/routes/v1/repository"
 	"config-service/routes/v1/vulnerability_exception"
+	"config-service/routes/v1/webhook"
 	"config-service/utils"
 	"context"
 	"log"
@@ -58,14 +61,19 @@ func setupRouter() *gin.Engine {
 	//Public routes
 
 	//login routes
-	login.AddRoutes(router)
-	//public (not authenticate routes
-	customer.AddPublicRoutes(router)
+	if !utils.GetConfig().Auth.DisableCookieLogin {
+		login.AddRoutes(router, authenticateLogin)
+	}
+	//tenant creation with a provisioning token or admin credentials
+	customer.AddTenantRoutes(router, authenticateTenantCreation)
 
 	//auth middleware
 	router.Use(authenticate)
 
 	//add protected routes
+	if !utils.GetConfig().Auth.DisableCookieLogin {
+		login.AddLogoutRoutes(router)
+	}
 	admin.AddRoutes(router)
 	cluster.AddRoutes(router)
 	posture_exception.AddRoutes(router)
@@ -75,6 +83,9 @@ func setupRouter() *gin.Engine {
 	framework.AddRoutes(router)
 	repository.AddRoutes(router)
 	registry_cron_job.AddRoutes(router)
+	api_key.AddRoutes(router)
+	audit.AddRoutes(router)
+	webhook.AddRoutes(router)
 
 	return router
 }
@@ -1,7 +1,15 @@
 package main
 
 import (
+	"config-service/db"
+	"config-service/handlers"
+	"config-service/types"
+	"config-service/utils"
+	"config-service/utils/auth"
 	"config-service/utils/consts"
+	"config-service/utils/log"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
@@ -14,39 +22,73 @@ import (
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-	"golang.org/x/exp/slices"
 )
 
 //////////////////////////////////////////middleware handlers//////////////////////////////////////////
 
 // authenticate middleware for request authentication
 func authenticate(c *gin.Context) {
-	cookieVal, err := c.Cookie(consts.CustomerGUID)
-	customerValues := strings.Split(cookieVal, ";")
-	customerGuid := customerValues[0]
-	if err != nil || customerGuid == "" {
-
-		customerGuid := c.Query(consts.CustomerGUID)
-		if customerGuid == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+	if !setAuthenticationContext(c) {
+		return
+	}
+	c.Next()
+}
+
+// authenticateTenantCreation middleware allows tenant creation with a provisioning token or with admin credentials
+func authenticateTenantCreation(c *gin.Context) {
+	if token := c.GetHeader(consts.ProvisioningTokenHeader); token != "" {
+		claims, err := auth.ParseProvisioningToken(token, auth.GetSigningKey())
+		if err != nil {
+			abortUnauthorized(c, err)
 			return
 		}
+		c.Set(consts.ProvisioningTokenID, claims.TokenID)
+		c.Next()
+		return
 	}
-	c.Set(consts.CustomerGUID, customerGuid)
-	if len(customerValues) > 1 && slices.Contains(customerValues[1:], consts.AdminAccess) {
-		c.Set(consts.AdminAccess, true)
+	if !setAuthenticationContext(c) {
+		return
+	}
+	if !c.GetBool(consts.AdminAccess) {
+		handlers.ResponseForbidden(c, "tenant creation requires a provisioning token or admin credentials")
+		return
 	}
 	c.Next()
 }
 
+// authenticateLogin middleware verifies the bearer JWT of login requests, sessions are created only for verified tokens
+func authenticateLogin(c *gin.Context) {
+	token := bearerToken(c)
+	if token == "" {
+		abortUnauthorized(c, errNoCredentials)
+		return
+	}
+	if jwtValidator == nil {
+		abortUnauthorized(c, fmt.Errorf("%w: bearer authentication is not enabled", auth.ErrInvalidToken))
+		return
+	}
+	claims, err := jwtValidator.Validate(token)
+	if err != nil {
+		abortUnauthorized(c, err)
+		return
+	}
+	c.Set(consts.LoginClaims, claims)
+	c.Next()
+}
+
 // traceAttributesNHeader middleware adds tracing header in response and request attributes in span
+// the attributes are set after the request is served since the customer is set by the authentication middleware
 func traceAttributesNHeader(c *gin.Context) {
 	otel.GetTextMapPropagator().Inject(c.Request.Context(), propagation.HeaderCarrier(c.Writer.Header()))
-	if trace.SpanFromContext(c.Request.Context()).SpanContext().IsValid() {
-		trace.SpanFromContext(c.Request.Context()).SetAttributes(attribute.String(consts.CustomerGUID, c.GetString(consts.CustomerGUID)))
-	}
 
 	c.Next()
+
+	if span := trace.SpanFromContext(c.Request.Context()); span.SpanContext().IsValid() {
+		span.SetAttributes(attribute.String(consts.CustomerGUID, c.GetString(consts.CustomerGUID)))
+		if impersonatedBy := c.GetString(consts.ImpersonatedBy); impersonatedBy != "" {
+			span.SetAttributes(attribute.String(consts.ImpersonatedBy, impersonatedBy))
+		}
+	}
 }
 
 // requestLogger middleware adds a logger with request attributes to the context
@@ -72,6 +114,114 @@ func requestSummary() func(c *gin.Context) {
 
 /////////////////////////////////////helper functions/////////////////////////////////////
 
+var errNoCredentials = errors.New("no credentials")
+
+// setAuthenticationContext authenticates the request and sets the customer and roles in the context
+// on failure the request is aborted and false is returned
+func setAuthenticationContext(c *gin.Context) bool {
+	claims, err := authenticateRequest(c)
+	if err != nil {
+		abortUnauthorized(c, err)
+		return false
+	}
+	c.Set(consts.CustomerGUID, claims.CustomerGUID)
+	roles := auth.ResolveRoles(claims)
+	c.Set(consts.Roles, roles)
+	if auth.Authorize(roles, c.Request.Method, consts.RouteGroupAdmin) {
+		c.Set(consts.AdminAccess, true)
+	}
+	//admins can act as another customer, the admin identity is kept for logging and tracing
+	if actAs := c.GetHeader(consts.ActAsCustomerHeader); actAs != "" {
+		if !c.GetBool(consts.AdminAccess) {
+			handlers.ResponseForbidden(c, "only admins can act as another customer")
+			return false
+		}
+		c.Set(consts.CustomerGUID, actAs)
+		c.Set(consts.ImpersonatedBy, claims.CustomerGUID)
+		c.Set(consts.ReqLogger, log.GetLogger(c).With(zap.String(consts.CustomerGUID, actAs), zap.String(consts.ImpersonatedBy, claims.CustomerGUID)))
+		log.LogNTrace("admin is acting as customer", c)
+	}
+	//api keys are limited to their scopes
+	if iScopes, ok := c.Get(consts.APIKeyScopes); ok {
+		if scopes, _ := iScopes.([]types.APIKeyScope); !auth.ScopesAllow(scopes, c.Request.Method, c.Request.URL.Path) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden - api key scopes do not allow this request"})
+			return false
+		}
+	}
+	return true
+}
+
+// abortUnauthorized aborts the request with 401 for authentication errors and 500 for other errors
+func abortUnauthorized(c *gin.Context, err error) {
+	if err == errNoCredentials {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+	} else if auth.IsAuthenticationError(err) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized - " + err.Error()})
+	} else {
+		handlers.ResponseInternalServerError(c, "failed to authenticate request", err)
+	}
+}
+
+// authenticateRequest returns the session claims of a bearer JWT, an API key or, when cookie login is enabled, of the session cookie
+func authenticateRequest(c *gin.Context) (*auth.SessionClaims, error) {
+	if token := bearerToken(c); token != "" {
+		if jwtValidator == nil {
+			return nil, fmt.Errorf("%w: bearer authentication is not enabled", auth.ErrInvalidToken)
+		}
+		return jwtValidator.Validate(token)
+	}
+	if key := c.GetHeader(consts.APIKeyHeader); key != "" {
+		return authenticateAPIKey(c, key)
+	}
+	if !utils.GetConfig().Auth.DisableCookieLogin {
+		if token, err := c.Cookie(consts.CustomerGUID); err == nil && token != "" {
+			return authenticateSession(c, token)
+		}
+	}
+	return nil, errNoCredentials
+}
+
+// authenticateSession verifies the session token and checks that the session was not revoked
+func authenticateSession(c *gin.Context, token string) (*auth.SessionClaims, error) {
+	claims, err := auth.ParseSessionToken(token, auth.GetSigningKey())
+	if err != nil {
+		return nil, err
+	}
+	if err := auth.ValidateSession(c, claims); err != nil {
+		return nil, err
+	}
+	c.Set(consts.SessionID, claims.SessionID)
+	return claims, nil
+}
+
+// authenticateAPIKey looks up the API key by its hash, on success the key scopes are set in the context
+func authenticateAPIKey(c *gin.Context, key string) (*auth.SessionClaims, error) {
+	apiKey, owners, err := db.GetDocWithOwnersFromCollection[types.APIKey](c, consts.APIKeyCollection,
+		db.NewFilterBuilder().WithValue(consts.KeyHashField, auth.HashAPIKey(key)))
+	if err != nil {
+		return nil, err
+	}
+	if apiKey == nil || len(owners) == 0 || owners[0] == "" {
+		return nil, auth.ErrInvalidToken
+	}
+	if expiration := apiKey.GetExpirationTime(); expiration != nil && expiration.Before(time.Now()) {
+		return nil, auth.ErrExpiredToken
+	}
+	c.Set(consts.APIKeyScopes, apiKey.Scopes)
+	return &auth.SessionClaims{CustomerGUID: owners[0]}, nil
+}
+
+// bearerToken returns the token from the Authorization header if the bearer scheme is used
+func bearerToken(c *gin.Context) string {
+	header := c.GetHeader("Authorization")
+	if len(header) > len(bearerScheme) && strings.EqualFold(header[:len(bearerScheme)], bearerScheme) {
+		return strings.TrimSpace(header[len(bearerScheme):])
+	}
+	return ""
+}
+
+const bearerScheme = "Bearer "
+
 // telemetryLogFields returns telemetry and customer id fields for  logging
 func telemetryLogFields(c *gin.Context) []zapcore.Field {
 	fields := []zapcore.Field{}
@@ -79,6 +229,10 @@ func telemetryLogFields(c *gin.Context) []zapcore.Field {
 	if customerGUID := c.GetString(consts.CustomerGUID); customerGUID != "" {
 		fields = append(fields, zap.String(consts.CustomerGUID, customerGUID))
 	}
+	// log the admin acting as the customer
+	if impersonatedBy := c.GetString(consts.ImpersonatedBy); impersonatedBy != "" {
+		fields = append(fields, zap.String(consts.ImpersonatedBy, impersonatedBy))
+	}
 	// log trace and span ID
 	if trace.SpanFromContext(c.Request.Context()).SpanContext().IsValid() {
 		fields = append(fields, zap.String("trace_id", trace.SpanFromContext(c.Request.Context()).SpanContext().TraceID().String()))
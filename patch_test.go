@@ -0,0 +1,106 @@
+package main
+
+import (
+	"config-service/handlers"
+	"config-service/types"
+	"config-service/utils/consts"
+	"net/http"
+
+	"github.com/armosec/armoapi-go/armotypes"
+)
+
+func (suite *MainTestSuite) TestMergePatch() {
+	cluster := &types.Cluster{PortalBase: armotypes.PortalBase{Name: "merge-patch-cluster", Attributes: map[string]interface{}{"env": "dev", "team": "a"}}}
+	cluster = testPostDoc(suite, consts.ClusterPath, cluster, newClusterCompareFilter)
+	path := consts.ClusterPath + "/" + cluster.GUID
+
+	suite.requestHeaders = map[string]string{"Content-Type": handlers.MergePatchContentType}
+	patch := map[string]interface{}{
+		"name":       "renamed-cluster", //read only, ignored
+		"attributes": map[string]interface{}{"env": "prod", "team": nil},
+	}
+	w := suite.doRequest(http.MethodPatch, path, patch)
+	suite.Equal(http.StatusOK, w.Code)
+	docs, err := decodeResponseArray[*types.Cluster](w)
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	suite.Len(docs, 2)
+	suite.Equal(cluster.Attributes, docs[0].Attributes)
+	suite.Equal(cluster.Name, docs[1].Name)
+	suite.Equal(map[string]interface{}{"env": "prod", consts.ShortNameAttribute: cluster.Attributes[consts.ShortNameAttribute]}, docs[1].Attributes)
+	suite.NotEqual(docs[0].UpdatedTime, docs[1].UpdatedTime)
+
+	//null out all attributes but the alias that is kept by the put validators
+	w = suite.doRequest(http.MethodPatch, path, map[string]interface{}{"attributes": nil})
+	suite.Equal(http.StatusOK, w.Code)
+	docs, err = decodeResponseArray[*types.Cluster](w)
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	suite.Equal(map[string]interface{}{consts.ShortNameAttribute: cluster.Attributes[consts.ShortNameAttribute]}, docs[1].Attributes)
+
+	testBadRequest(suite, http.MethodPatch, path, `{"error":"merge patch must be a json object"}`, []string{"attributes"}, http.StatusBadRequest)
+	testBadRequest(suite, http.MethodPatch, consts.ClusterPath+"/not-exist", errorDocumentNotFound, patch, http.StatusNotFound)
+	suite.requestHeaders = nil
+	testBadRequest(suite, http.MethodPatch, path, `{"error":"unsupported content type, supported types are: application/merge-patch+json,application/json-patch+json"}`, patch, http.StatusUnsupportedMediaType)
+
+	testDeleteDocByGUID(suite, consts.ClusterPath, docs[1], newClusterCompareFilter)
+}
+
+func (suite *MainTestSuite) TestJSONPatch() {
+	policies, _ := loadJson[*types.PostureExceptionPolicy](posturePoliciesJson)
+	policy := policies[0]
+	policy.Name = "json-patch-policy"
+	policy.Attributes = map[string]interface{}{"env": "dev", "team": "a"}
+	policy = testPostDoc(suite, consts.PostureExceptionPolicyPath, policy, commonCmpFilter)
+	path := consts.PostureExceptionPolicyPath + "/" + policy.GUID
+	resourcesCount := len(policy.Resources)
+
+	patchPolicy := func(patch []map[string]interface{}, expectedCode int) *types.PostureExceptionPolicy {
+		w := suite.doRequest(http.MethodPatch, path, patch)
+		suite.Equal(expectedCode, w.Code, w.Body.String())
+		if expectedCode != http.StatusOK {
+			return nil
+		}
+		docs, err := decodeResponseArray[*types.PostureExceptionPolicy](w)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+		suite.Len(docs, 2)
+		return docs[1]
+	}
+	suite.requestHeaders = map[string]string{"Content-Type": handlers.JSONPatchContentType}
+	//translated to update operators
+	designator := map[string]interface{}{"designatorType": "Attributes", "attributes": map[string]interface{}{"cluster": "patched-cluster"}}
+	patched := patchPolicy([]map[string]interface{}{
+		{"op": "test", "path": "/name", "value": "json-patch-policy"},
+		{"op": "add", "path": "/resources/-", "value": designator},
+		{"op": "replace", "path": "/attributes/env", "value": "prod"},
+		{"op": "remove", "path": "/attributes/team"},
+	}, http.StatusOK)
+	suite.Len(patched.Resources, resourcesCount+1)
+	suite.Equal("patched-cluster", patched.Resources[resourcesCount].Attributes["cluster"])
+	suite.Equal(map[string]interface{}{"env": "prod"}, patched.Attributes)
+	//read-modify-write
+	patched = patchPolicy([]map[string]interface{}{
+		{"op": "test", "path": "/attributes", "value": map[string]interface{}{"env": "prod"}},
+		{"op": "remove", "path": "/resources/0"},
+		{"op": "move", "from": "/attributes/env", "path": "/attributes/stage"},
+	}, http.StatusOK)
+	suite.Len(patched.Resources, resourcesCount)
+	suite.Equal("patched-cluster", patched.Resources[resourcesCount-1].Attributes["cluster"])
+	suite.Equal(map[string]interface{}{"stage": "prod"}, patched.Attributes)
+	//failed test
+	patchPolicy([]map[string]interface{}{
+		{"op": "test", "path": "/attributes/stage", "value": "dev"},
+		{"op": "replace", "path": "/attributes/stage", "value": "qa"},
+	}, http.StatusConflict)
+	//bad patches
+	patchPolicy([]map[string]interface{}{{"op": "remove", "path": "/attributes/missing"}}, http.StatusBadRequest)
+	patchPolicy([]map[string]interface{}{{"op": "add", "path": "/resources/100", "value": designator}}, http.StatusBadRequest)
+	patchPolicy([]map[string]interface{}{{"op": "merge", "path": "/attributes", "value": designator}}, http.StatusBadRequest)
+	suite.requestHeaders = nil
+
+	testDeleteDocByGUID(suite, consts.PostureExceptionPolicyPath, patched, commonCmpFilter)
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"config-service/db"
+	"config-service/types"
+	"config-service/utils/consts"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/armosec/armoapi-go/armotypes"
+)
+
+func (suite *MainTestSuite) TestPagination() {
+	suite.login("pagination-customer-guid")
+	clusters := []*types.Cluster{}
+	for i := 0; i < 5; i++ {
+		clusters = append(clusters, &types.Cluster{PortalBase: armotypes.PortalBase{
+			Name:       fmt.Sprintf("page-cluster-%d", i),
+			Attributes: map[string]interface{}{"group": fmt.Sprintf("%d", i%2)},
+		}})
+	}
+	clusters = testBulkPostDocs(suite, consts.ClusterPath, clusters, newClusterCompareFilter)
+
+	getPage := func(query string) *db.AggResult[*types.Cluster] {
+		w := suite.doRequest(http.MethodGet, consts.ClusterPath+"?"+query, nil)
+		suite.Equal(http.StatusOK, w.Code)
+		page, err := decodeResponse[*db.AggResult[*types.Cluster]](w)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+		return page
+	}
+
+	//without pagination params all the documents are returned as an array
+	testGetDocs(suite, consts.ClusterPath, clusters, newClusterCompareFilter)
+
+	//skip pagination
+	page := getPage("limit=2")
+	suite.Equal(db.Metadata{Total: 5, Limit: 2, NextSkip: 2, NextCursor: page.Metadata.NextCursor}, page.Metadata)
+	suite.Len(page.Results, 2)
+	page = getPage("limit=2&skip=4")
+	suite.Equal(db.Metadata{Total: 5, Limit: 2}, page.Metadata)
+	suite.Len(page.Results, 1)
+
+	//cursor pagination reads all the documents once
+	seen := map[string]bool{}
+	cursor := ""
+	for pages := 0; pages < 3; pages++ {
+		page = getPage("limit=2&cursor=" + cursor)
+		for _, cluster := range page.Results {
+			suite.False(seen[cluster.GUID], "cluster returned twice")
+			seen[cluster.GUID] = true
+		}
+		cursor = page.Metadata.NextCursor
+		if cursor == "" {
+			break
+		}
+	}
+	suite.Len(seen, 5)
+
+	//pagination of scope queries
+	page = getPage("limit=2&group=1")
+	suite.Equal(2, page.Metadata.Total)
+	suite.Len(page.Results, 2)
+	suite.Empty(page.Metadata.NextCursor)
+
+	//bad params
+	testBadRequest(suite, http.MethodGet, consts.ClusterPath+"?limit=-1", `{"error":"limit must be a positive number"}`, nil, http.StatusBadRequest)
+	testBadRequest(suite, http.MethodGet, consts.ClusterPath+"?skip=1&cursor=abc", `{"error":"skip and cursor cannot be used together"}`, nil, http.StatusBadRequest)
+	testBadRequest(suite, http.MethodGet, consts.ClusterPath+"?cursor=***", `{"error":"invalid cursor"}`, nil, http.StatusBadRequest)
+
+	for _, cluster := range clusters {
+		testDeleteDocByGUID(suite, consts.ClusterPath, cluster, newClusterCompareFilter)
+	}
+	//restore login
+	suite.login(defaultUserGUID)
+}
+
+func (suite *MainTestSuite) TestSort() {
+	suite.login("sort-customer-guid")
+	clusters := []*types.Cluster{}
+	for _, name := range []string{"b-cluster", "c-cluster", "a-cluster"} {
+		clusters = append(clusters, &types.Cluster{PortalBase: armotypes.PortalBase{Name: name, Attributes: map[string]interface{}{"group": "sorted"}}})
+	}
+	clusters = testBulkPostDocs(suite, consts.ClusterPath, clusters, newClusterCompareFilter)
+
+	getNames := func(query string) []string {
+		w := suite.doRequest(http.MethodGet, consts.ClusterPath+"?"+query, nil)
+		suite.Equal(http.StatusOK, w.Code)
+		docs, err := decodeResponseArray[*types.Cluster](w)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+		names := []string{}
+		for _, doc := range docs {
+			names = append(names, doc.Name)
+		}
+		return names
+	}
+	suite.Equal([]string{"a-cluster", "b-cluster", "c-cluster"}, getNames("sort=name"))
+	suite.Equal([]string{"c-cluster", "b-cluster", "a-cluster"}, getNames("sort=-name"))
+	//sort scope queries
+	suite.Equal([]string{"c-cluster", "b-cluster", "a-cluster"}, getNames("sort=-name&group=sorted"))
+	//sort pages
+	w := suite.doRequest(http.MethodGet, consts.ClusterPath+"?sort=name&limit=2&skip=1", nil)
+	suite.Equal(http.StatusOK, w.Code)
+	page, err := decodeResponse[*db.AggResult[*types.Cluster]](w)
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	suite.Equal("b-cluster", page.Results[0].Name)
+	suite.Equal("c-cluster", page.Results[1].Name)
+	suite.Empty(page.Metadata.NextCursor)
+
+	//bad sort params
+	testBadRequest(suite, http.MethodGet, consts.ClusterPath+"?sort=attributes.alias", `{"error":"sort by attributes.alias is not supported, sortable fields are: name,guid,updatedTime"}`, nil, http.StatusBadRequest)
+	testBadRequest(suite, http.MethodGet, consts.ClusterPath+"?sort=name&cursor=abc", `{"error":"invalid cursor: cursor cannot be used with sort"}`, nil, http.StatusBadRequest)
+	testBadRequest(suite, http.MethodGet, consts.APIKeyPath+"?sort=name", `{"error":"sort is not supported"}`, nil, http.StatusBadRequest)
+
+	for _, cluster := range clusters {
+		testDeleteDocByGUID(suite, consts.ClusterPath, cluster, newClusterCompareFilter)
+	}
+	//restore login
+	suite.login(defaultUserGUID)
+}
+
+func (suite *MainTestSuite) TestFields() {
+	suite.login("fields-customer-guid")
+	clusters := []*types.Cluster{
+		{PortalBase: armotypes.PortalBase{Name: "fields-cluster", Attributes: map[string]interface{}{"alias": "fc", "group": "fields"}}},
+	}
+	clusters = testBulkPostDocs(suite, consts.ClusterPath, clusters, newClusterCompareFilter)
+	cluster := clusters[0]
+
+	checkFields := func(doc *types.Cluster) {
+		suite.Equal(cluster.Name, doc.Name)
+		suite.Equal(cluster.GUID, doc.GUID)
+		suite.Equal(map[string]interface{}{"alias": "fc"}, doc.Attributes)
+		suite.Empty(doc.UpdatedTime)
+	}
+	//get by guid
+	w := suite.doRequest(http.MethodGet, consts.ClusterPath+"/"+cluster.GUID+"?fields=name,guid,attributes.alias", nil)
+	suite.Equal(http.StatusOK, w.Code)
+	doc, err := decodeResponse[*types.Cluster](w)
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	checkFields(doc)
+	//get all and scope queries
+	for _, query := range []string{"?fields=name,guid,attributes.alias", "?group=fields&fields=name,guid,attributes.alias"} {
+		w = suite.doRequest(http.MethodGet, consts.ClusterPath+query, nil)
+		suite.Equal(http.StatusOK, w.Code)
+		docs, err := decodeResponseArray[*types.Cluster](w)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+		suite.Len(docs, 1)
+		checkFields(docs[0])
+	}
+	//pages
+	w = suite.doRequest(http.MethodGet, consts.ClusterPath+"?fields=name,guid,attributes.alias&limit=1", nil)
+	suite.Equal(http.StatusOK, w.Code)
+	page, err := decodeResponse[*db.AggResult[*types.Cluster]](w)
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	suite.Len(page.Results, 1)
+	checkFields(page.Results[0])
+
+	testBadRequest(suite, http.MethodGet, consts.ClusterPath+"?fields=$where", `{"error":"invalid field $where"}`, nil, http.StatusBadRequest)
+	testBadRequest(suite, http.MethodGet, consts.ClusterPath+"/"+cluster.GUID+"?fields=attributes,attributes.alias", `{"error":"fields attributes and attributes.alias overlap"}`, nil, http.StatusBadRequest)
+
+	testDeleteDocByGUID(suite, consts.ClusterPath, cluster, newClusterCompareFilter)
+	//restore login
+	suite.login(defaultUserGUID)
+}
+
+func (suite *MainTestSuite) TestScopeQueryOperators() {
+	suite.login("operators-customer-guid")
+	clusters := []*types.Cluster{
+		{PortalBase: armotypes.PortalBase{Name: "kube-cluster", Attributes: map[string]interface{}{"namespace": "kube-system", "reported": "2023-01-01T00:00:00Z", "alias": "kc"}}},
+		{PortalBase: armotypes.PortalBase{Name: "prod-cluster", Attributes: map[string]interface{}{"namespace": "prod", "reported": "2023-02-01T00:00:00Z"}}},
+		{PortalBase: armotypes.PortalBase{Name: "dev-cluster", Attributes: map[string]interface{}{"namespace": "dev", "reported": "2023-03-01T00:00:00Z"}}},
+	}
+	clusters = testBulkPostDocs(suite, consts.ClusterPath, clusters, newClusterCompareFilter)
+
+	getNames := func(query string) []string {
+		w := suite.doRequest(http.MethodGet, consts.ClusterPath+"?sort=name&"+query, nil)
+		suite.Equal(http.StatusOK, w.Code)
+		docs, err := decodeResponseArray[*types.Cluster](w)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+		names := []string{}
+		for _, doc := range docs {
+			names = append(names, doc.Name)
+		}
+		return names
+	}
+	suite.Equal([]string{"dev-cluster", "prod-cluster"}, getNames("namespace[ne]=kube-system"))
+	suite.Equal([]string{"dev-cluster"}, getNames("namespace[ne]=kube-system&namespace[ne]=prod"))
+	suite.Equal([]string{"kube-cluster", "prod-cluster"}, getNames("namespace[in]=kube-system,prod"))
+	suite.Equal([]string{"dev-cluster", "kube-cluster"}, getNames("attributes.namespace[regex]=^(kube|dev)"))
+	suite.Equal([]string{"kube-cluster"}, getNames("alias[exists]=true"))
+	suite.Equal([]string{"dev-cluster", "prod-cluster"}, getNames("alias[exists]=false"))
+	suite.Equal([]string{"dev-cluster", "prod-cluster"}, getNames("reported[gt]=2023-01-15"))
+	//range on the same key
+	suite.Equal([]string{"prod-cluster"}, getNames("reported[gt]=2023-01-15&reported[lt]=2023-02-15T00:00:00Z"))
+	//operators with exact values
+	suite.Equal([]string{"prod-cluster"}, getNames("namespace=prod&reported[gt]=2023-01-15"))
+
+	testBadRequest(suite, http.MethodGet, consts.ClusterPath+"?namespace[eq]=prod", `{"error":"unsupported operator eq"}`, nil, http.StatusBadRequest)
+	testBadRequest(suite, http.MethodGet, consts.ClusterPath+"?namespace[regex]=(", `{"error":"invalid regex ("}`, nil, http.StatusBadRequest)
+	testBadRequest(suite, http.MethodGet, consts.ClusterPath+"?namespace[regex]="+strings.Repeat("a", 129), `{"error":"regex is longer than 128 characters"}`, nil, http.StatusBadRequest)
+	testBadRequest(suite, http.MethodGet, consts.ClusterPath+"?alias[exists]=maybe", `{"error":"operator exists value must be true or false"}`, nil, http.StatusBadRequest)
+
+	for _, cluster := range clusters {
+		testDeleteDocByGUID(suite, consts.ClusterPath, cluster, newClusterCompareFilter)
+	}
+	//restore login
+	suite.login(defaultUserGUID)
+}
+
+func (suite *MainTestSuite) TestFilterExpression() {
+	suite.login("filter-customer-guid")
+	clusters := []*types.Cluster{
+		{PortalBase: armotypes.PortalBase{Name: "prod-eu", Attributes: map[string]interface{}{"env": "production", "team": "sec"}}},
+		{PortalBase: armotypes.PortalBase{Name: "prod-us", Attributes: map[string]interface{}{"env": "staging", "team": "sec"}}},
+		{PortalBase: armotypes.PortalBase{Name: "prod-asia", Attributes: map[string]interface{}{"env": "production", "team": "dev"}}},
+		{PortalBase: armotypes.PortalBase{Name: "dev", Attributes: map[string]interface{}{"env": "staging", "team": "dev"}}},
+	}
+	clusters = testBulkPostDocs(suite, consts.ClusterPath, clusters, newClusterCompareFilter)
+
+	getNames := func(query string) []string {
+		w := suite.doRequest(http.MethodGet, consts.ClusterPath+"?sort=name&"+query, nil)
+		suite.Equal(http.StatusOK, w.Code)
+		docs, err := decodeResponseArray[*types.Cluster](w)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+		names := []string{}
+		for _, doc := range docs {
+			names = append(names, doc.Name)
+		}
+		return names
+	}
+	filter := func(expression string) string {
+		return "filter=" + url.QueryEscape(expression)
+	}
+	suite.Equal([]string{"prod-asia", "prod-us"}, getNames(filter("name==prod* and (attributes.env==staging or attributes.team!=sec)")))
+	suite.Equal([]string{"dev", "prod-eu"}, getNames(filter("not (name==prod* and attributes.team==dev) and name!=prod-us")))
+	suite.Equal([]string{"dev", "prod-us"}, getNames(filter("attributes.env=in=(staging,qa)")))
+	//filter with scope params
+	suite.Equal([]string{"prod-us"}, getNames(filter("name==prod*")+"&env=staging"))
+
+	testBadRequest(suite, http.MethodGet, consts.ClusterPath+"?"+filter("customers==x"), `{"error":"invalid filter: filter by customers is not supported"}`, nil, http.StatusBadRequest)
+	testBadRequest(suite, http.MethodGet, consts.ClusterPath+"?"+filter("name==a and"), `{"error":"invalid filter: unexpected end of expression"}`, nil, http.StatusBadRequest)
+
+	for _, cluster := range clusters {
+		testDeleteDocByGUID(suite, consts.ClusterPath, cluster, newClusterCompareFilter)
+	}
+
+	//routers without scope params serve the filter param
+	repositories, _ := loadJson[*types.Repository](repositoriesJson)
+	repositories = testBulkPostDocs(suite, consts.RepositoryPath, repositories[:2], repoCompareFilter)
+	w := suite.doRequest(http.MethodGet, consts.RepositoryPath+"?"+filter("name=="+repositories[1].Name), nil)
+	suite.Equal(http.StatusOK, w.Code, w.Body.String())
+	found, err := decodeResponseArray[*types.Repository](w)
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	suite.Len(found, 1)
+	suite.Equal(repositories[1].GUID, found[0].GUID)
+	testBadRequest(suite, http.MethodGet, consts.RepositoryPath+"?"+filter("owner==x"), `{"error":"invalid filter: filter by owner is not supported"}`, nil, http.StatusBadRequest)
+	for _, repository := range repositories {
+		testDeleteDocByGUID(suite, consts.RepositoryPath, repository, repoCompareFilter)
+	}
+	//restore login
+	suite.login(defaultUserGUID)
+}
+
+func (suite *MainTestSuite) TestTextSearch() {
+	suite.login("text-search-customer-guid")
+	policies, _ := loadJson[*types.PostureExceptionPolicy](posturePoliciesJson)
+	policies = policies[:3]
+	policies[0].Name = "nginx exception"
+	policies[1].Name = "web exception"
+	policies[1].Resources = []armotypes.PortalDesignator{{DesignatorType: armotypes.DesignatorAttributes, Attributes: map[string]string{"name": "nginx-deployment"}}}
+	policies[2].Name = "redis exception"
+	policies = testBulkPostDocs(suite, consts.PostureExceptionPolicyPath, policies, commonCmpFilter)
+
+	getNames := func(query string) []string {
+		w := suite.doRequest(http.MethodGet, consts.PostureExceptionPolicyPath+"?"+query, nil)
+		suite.Equal(http.StatusOK, w.Code)
+		docs, err := decodeResponseArray[*types.PostureExceptionPolicy](w)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+		names := []string{}
+		for _, doc := range docs {
+			names = append(names, doc.Name)
+		}
+		return names
+	}
+	//name matches are ranked first
+	suite.Equal([]string{"nginx exception", "web exception"}, getNames("q=nginx"))
+	suite.Equal([]string{"redis exception"}, getNames("q=redis"))
+	suite.Equal([]string{"web exception", "nginx exception"}, getNames("q=nginx&sort=-name"))
+	//search with scope params and filters
+	suite.Equal([]string{"web exception"}, getNames("q=nginx&scope.name=nginx-deployment"))
+	suite.Equal([]string{"nginx exception"}, getNames("q=nginx&"+"filter="+url.QueryEscape("name==nginx*")))
+	//search pages
+	w := suite.doRequest(http.MethodGet, consts.PostureExceptionPolicyPath+"?q=nginx&limit=1", nil)
+	suite.Equal(http.StatusOK, w.Code)
+	page, err := decodeResponse[*db.AggResult[*types.PostureExceptionPolicy]](w)
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	suite.Equal(2, page.Metadata.Total)
+	suite.Equal("nginx exception", page.Results[0].Name)
+	suite.Equal(1, page.Metadata.NextSkip)
+
+	testBadRequest(suite, http.MethodGet, consts.ClusterPath+"?q=nginx", `{"error":"text search is not supported"}`, nil, http.StatusBadRequest)
+
+	for _, policy := range policies {
+		testDeleteDocByGUID(suite, consts.PostureExceptionPolicyPath, policy, commonCmpFilter)
+	}
+	//restore login
+	suite.login(defaultUserGUID)
+}
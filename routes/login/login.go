@@ -2,40 +2,63 @@ package login
 
 import (
 	"config-service/handlers"
+	"config-service/utils"
+	"config-service/utils/auth"
 	"config-service/utils/consts"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AddRoutes(g *gin.Engine) {
+// AddRoutes adds the login route, authMiddleware must verify the request credentials and set their claims in the context
+func AddRoutes(g *gin.Engine, authMiddleware gin.HandlerFunc) {
 	login := g.Group("/login")
+	login.Use(handlers.AuditMiddleware)
+	login.Use(authMiddleware)
 
 	//login routes
 	login.POST("", func(c *gin.Context) {
-		loginDetails := struct {
-			CustomerGUID string                 `json:"customerGUID" binding:"required"`
-			Attributes   map[string]interface{} `json:"attributes,omitempty"`
-		}{
-			CustomerGUID: "",
+		verified, ok := c.Get(consts.LoginClaims)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
 		}
-
-		if err := c.ShouldBindJSON(&loginDetails); err != nil {
-			handlers.ResponseFailedToBindJson(c, err)
+		//the customer, admin access and roles are taken from the verified credentials and never from the request body
+		verifiedClaims := verified.(*auth.SessionClaims)
+		ttl := utils.GetConfig().Auth.GetSessionTTL()
+		claims := auth.NewSessionClaims(verifiedClaims.CustomerGUID, verifiedClaims.AdminAccess, ttl, verifiedClaims.Roles...)
+		if err := auth.CreateSession(c, &claims); err != nil {
+			handlers.ResponseInternalServerError(c, "failed to create session", err)
 			return
 		}
-		if loginDetails.CustomerGUID == "" {
-			handlers.ResponseBadRequest(c, "customerGUID is required")
+		token, err := auth.SignSessionToken(claims, auth.GetSigningKey())
+		if err != nil {
+			handlers.ResponseInternalServerError(c, "failed to sign session token", err)
 			return
 		}
-		cookieValue := loginDetails.CustomerGUID
-		//check if admin access required
-		if loginDetails.Attributes != nil {
-			if admin, ok := loginDetails.Attributes["admin"]; ok && admin == true {
-				cookieValue += ";" + consts.AdminAccess
-			}
+		c.SetCookie(consts.CustomerGUID, token, int(ttl.Seconds()), "/", "", true, true)
+		//the login request is not authenticated with a session, record the logged in customer as the actor
+		c.Set(consts.CustomerGUID, claims.CustomerGUID)
+		c.Set(consts.AdminAccess, claims.AdminAccess)
+		handlers.AddAuditTargets(c, claims.SessionID)
+		c.JSON(http.StatusOK, nil)
+	})
+}
+
+// AddLogoutRoutes adds the logout route, it must be added after the authentication middleware
+func AddLogoutRoutes(g *gin.Engine) {
+	g.POST("/logout", handlers.AuditMiddleware, func(c *gin.Context) {
+		sessionID := c.GetString(consts.SessionID)
+		if sessionID == "" {
+			handlers.ResponseBadRequest(c, "request is not authenticated with a login session")
+			return
+		}
+		if err := auth.RevokeSession(c, sessionID); err != nil {
+			handlers.ResponseInternalServerError(c, "failed to revoke session", err)
+			return
 		}
-		c.SetCookie(consts.CustomerGUID, cookieValue, 2*60*60*24, "/", "", false, true)
+		c.SetCookie(consts.CustomerGUID, "", -1, "/", "", true, true)
+		handlers.AddAuditTargets(c, sessionID)
 		c.JSON(http.StatusOK, nil)
 	})
 }
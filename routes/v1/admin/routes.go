@@ -5,6 +5,7 @@ import (
 	"config-service/handlers"
 	"config-service/types"
 	"config-service/utils"
+	"config-service/utils/auth"
 	"config-service/utils/consts"
 	"config-service/utils/log"
 	"fmt"
@@ -13,31 +14,97 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/exp/slices"
 )
 
 func AddRoutes(g *gin.Engine) {
 	admin := g.Group(consts.AdminPath)
 
-	//add middleware to check if user is admin
-	adminUsers := utils.GetConfig().AdminUsers
-	adminAuthMiddleware := func(c *gin.Context) {
-		//check if admin access granted by auth middleware or if user is in the configuration admin users list
-		if c.GetBool(consts.AdminAccess) {
-			c.Next()
-		} else if slices.Contains(adminUsers, c.GetString(consts.CustomerGUID)) {
-			c.Next()
-		} else {
-			//not admin
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized - not an admin user"})
-		}
-	}
-
-	admin.Use(adminAuthMiddleware)
+	//only roles permitted on the admin route group can use admin APIs
+	admin.Use(handlers.AuthorizationMiddleware(consts.RouteGroupAdmin))
+	admin.Use(handlers.AuditMiddleware)
 
 	admin.GET("/activeCustomers", getActiveCustomers)
 	//add delete customers data route
 	admin.DELETE("/customers", deleteAllCustomerData)
+	//add revoke customers sessions route
+	admin.DELETE("/sessions", revokeCustomersSessions)
+	//add issue tenant provisioning token route
+	admin.POST("/provisioningTokens", issueProvisioningToken)
+	//add purge deleted documents route
+	admin.DELETE("/deletedDocuments", purgeDeletedDocuments)
+}
+
+func purgeDeletedDocuments(c *gin.Context) {
+	defer log.LogNTraceEnterExit("purgeDeletedDocuments", c)()
+	retention := utils.GetConfig().SoftDelete.GetRetention()
+	if retentionStr := c.Query(consts.RetentionParam); retentionStr != "" {
+		var err error
+		if retention, err = time.ParseDuration(retentionStr); err != nil || retention < 0 {
+			handlers.ResponseBadRequest(c, consts.RetentionParam+" must be a non negative duration (e.g. 720h)")
+			return
+		}
+	}
+	purged, err := db.AdminPurgeDeletedDocs(c, time.Now().Add(-retention))
+	if err != nil {
+		handlers.ResponseInternalServerError(c, fmt.Sprintf("purged: %d, errors: %v", purged, err), err)
+		return
+	}
+	log.LogNTrace(fmt.Sprintf("purgeDeletedDocuments %d documents deleted more than %s ago purged by admin %s", purged, retention, c.GetString(consts.CustomerGUID)), c)
+	c.JSON(http.StatusOK, gin.H{"purged": purged})
+}
+
+func issueProvisioningToken(c *gin.Context) {
+	defer log.LogNTraceEnterExit("issueProvisioningToken", c)()
+	request := struct {
+		CustomerGUID   string                 `json:"customerGUID,omitempty"`
+		Attributes     map[string]interface{} `json:"attributes,omitempty"`
+		ExpirationTime string                 `json:"expirationTime,omitempty"`
+	}{}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		handlers.ResponseFailedToBindJson(c, err)
+		return
+	}
+	expiration := time.Now().UTC().Add(auth.DefaultProvisioningTokenTTL)
+	if request.ExpirationTime != "" {
+		var err error
+		if expiration, err = time.Parse(time.RFC3339, request.ExpirationTime); err != nil {
+			handlers.ResponseBadRequest(c, "expirationTime must be in RFC3339 format")
+			return
+		} else if expiration.Before(time.Now()) {
+			handlers.ResponseBadRequest(c, "expirationTime must be in the future")
+			return
+		}
+	}
+	token := &auth.ProvisioningToken{
+		CustomerGUID:   request.CustomerGUID,
+		Attributes:     request.Attributes,
+		IssuedBy:       c.GetString(consts.CustomerGUID),
+		ExpirationTime: expiration.UTC(),
+	}
+	if err := auth.IssueProvisioningToken(c, token); err != nil {
+		handlers.ResponseInternalServerError(c, "failed to issue provisioning token", err)
+		return
+	}
+	log.LogNTrace(fmt.Sprintf("issueProvisioningToken token %s issued by admin %s", token.ID, token.IssuedBy), c)
+	handlers.AddAuditTargets(c, token.ID)
+	c.JSON(http.StatusCreated, token)
+}
+
+func revokeCustomersSessions(c *gin.Context) {
+	defer log.LogNTraceEnterExit("revokeCustomersSessions", c)()
+	customersGUIDs := c.QueryArray(consts.CustomersParam)
+	if len(customersGUIDs) == 0 {
+		handlers.ResponseMissingQueryParam(c, consts.CustomersParam)
+		return
+	}
+	revoked, err := auth.RevokeCustomersSessions(c, customersGUIDs...)
+	if err != nil {
+		handlers.ResponseInternalServerError(c, "failed to revoke sessions", err)
+		return
+	}
+	log.LogNTrace(fmt.Sprintf("revokeCustomersSessions %d sessions of %d users revoked by admin %s", revoked, len(customersGUIDs), c.GetString(consts.CustomerGUID)), c)
+	handlers.AddAuditTargets(c, customersGUIDs...)
+	c.JSON(http.StatusOK, gin.H{"revoked": revoked})
 }
 
 func deleteAllCustomerData(c *gin.Context) {
@@ -53,6 +120,7 @@ func deleteAllCustomerData(c *gin.Context) {
 		return
 	}
 	log.LogNTrace(fmt.Sprintf("deleteAllCustomerData completed successfully. %d documents of %d users deleted by admin %s ", deleted, len(customersGUIDs), c.GetString(consts.CustomerGUID)), c)
+	handlers.AddAuditTargets(c, customersGUIDs...)
 	c.JSON(http.StatusOK, gin.H{"deleted": deleted})
 
 }
@@ -0,0 +1,70 @@
+package api_key
+
+import (
+	"config-service/handlers"
+	"config-service/types"
+	"config-service/utils/auth"
+	"config-service/utils/consts"
+	"config-service/utils/log"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AddRoutes(g *gin.Engine) {
+	handlers.AddRoutes(g, handlers.NewRouterOptionsBuilder[*types.APIKey]().
+		WithPath(consts.APIKeyPath).
+		WithDBCollection(consts.APIKeyCollection).
+		WithRouteGroup(consts.RouteGroupAccess).
+		WithMiddlewares(denyAPIKeyAuthentication).
+		WithServePut(false).                    //keys cannot be modified, revoke and create a new one instead
+		WithPostValidators(validatePostAPIKey). //generate the key and store its hash
+		WithValidatePostUniqueName(true).
+		Get()...)
+}
+
+// denyAPIKeyAuthentication aborts requests authenticated with an api key, api keys cannot be used to manage api keys
+func denyAPIKeyAuthentication(c *gin.Context) {
+	if _, ok := c.Get(consts.APIKeyScopes); ok {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden - api keys cannot be managed with an api key"})
+		return
+	}
+	c.Next()
+}
+
+// validatePostAPIKey validates the key scopes and expiration and generates the key, only the key hash is stored
+func validatePostAPIKey(c *gin.Context, docs []*types.APIKey) ([]*types.APIKey, bool) {
+	defer log.LogNTraceEnterExit("validatePostAPIKey", c)()
+	for i := range docs {
+		if len(docs[i].Scopes) == 0 {
+			handlers.ResponseMissingKey(c, "scopes")
+			return nil, false
+		}
+		for _, scope := range docs[i].Scopes {
+			if !auth.ValidScope(scope) {
+				handlers.ResponseBadRequest(c, fmt.Sprintf("invalid scope path: %s access: %s, access must be %s or %s", scope.Path, scope.Access, auth.ScopeAccessRead, auth.ScopeAccessWrite))
+				return nil, false
+			}
+		}
+		if docs[i].ExpirationTime != "" {
+			if expiration := docs[i].GetExpirationTime(); expiration == nil {
+				handlers.ResponseBadRequest(c, "expirationTime must be in RFC3339 format")
+				return nil, false
+			} else if expiration.Before(time.Now()) {
+				handlers.ResponseBadRequest(c, "expirationTime must be in the future")
+				return nil, false
+			}
+		}
+		key, hash, prefix, err := auth.GenerateAPIKey()
+		if err != nil {
+			handlers.ResponseInternalServerError(c, "failed to generate api key", err)
+			return nil, false
+		}
+		docs[i].Key = key
+		docs[i].KeyHash = hash
+		docs[i].KeyPrefix = prefix
+	}
+	return docs, true
+}
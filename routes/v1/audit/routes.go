@@ -0,0 +1,20 @@
+package audit
+
+import (
+	"config-service/db"
+	"config-service/handlers"
+	"config-service/utils/consts"
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AddRoutes(g *gin.Engine) {
+	if err := db.EnsureAuditIndexes(context.Background()); err != nil {
+		panic(err)
+	}
+	audit := g.Group(consts.AuditPath)
+	//the audit log is read only, records are added by the audit middleware of the other routes
+	audit.Use(handlers.AuthorizationMiddleware(consts.RouteGroupAccess))
+	audit.GET("", handlers.HandleGetAuditRecords)
+}
@@ -15,6 +15,9 @@ func AddRoutes(g *gin.Engine) {
 		WithValidatePostUniqueName(true).
 		WithValidatePutGUID(true).
 		WithDeleteByName(false).
+		WithServeRestore(true).
 		WithUniqueShortName(handlers.NameValueGetter[*types.Cluster]).
+		WithQueryConfig(handlers.DefaultQueryConfig()).
+		WithSortFields(handlers.DefaultSortFields...).
 		Get()...)
 }
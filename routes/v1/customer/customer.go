@@ -2,28 +2,38 @@ package customer
 
 import (
 	"config-service/db"
+	"config-service/db/mongo"
 	"config-service/handlers"
 	"config-service/types"
+	"config-service/utils/auth"
 	"config-service/utils/consts"
 	"config-service/utils/log"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
+	uuid "github.com/satori/go.uuid"
 )
 
-func AddPublicRoutes(g *gin.Engine) {
+// AddTenantRoutes adds the tenant creation route, authMiddleware must allow only provisioning tokens or admin credentials
+func AddTenantRoutes(g *gin.Engine, authMiddleware gin.HandlerFunc) {
 	tenant := g.Group(consts.TenantPath)
+	tenant.Use(authMiddleware)
 	tenant.Use(handlers.DBContextMiddleware(consts.CustomersCollection))
+	tenant.Use(handlers.AuditMiddleware)
 	tenant.POST("", postCustomerTenant)
 }
 
 func AddRoutes(g *gin.Engine) {
 	customer := g.Group(consts.CustomerPath)
+	customer.Use(handlers.AuthorizationMiddleware(consts.RouteGroupConfig))
 	customer.Use(handlers.DBContextMiddleware(consts.CustomersCollection))
+	customer.Use(handlers.AuditMiddleware)
 	customer.GET("", getCustomer)
-	customer.DELETE("", deleteCustomer)
+	//deleting the customer deletes the documents of all the route groups including the api keys, webhooks and audit log
+	customer.DELETE("", handlers.AuthorizationMiddleware(consts.RouteGroupConfig, consts.RouteGroupSecurity, consts.RouteGroupAccess), deleteCustomer)
 	customer.PUT("", handlers.HandlePutDocWithValidation(customerPutMiddleware)...)
 
 	//add customer's inner files routes
@@ -77,6 +87,7 @@ func deleteCustomer(c *gin.Context) {
 		handlers.ResponseInternalServerError(c, fmt.Sprintf("failed to delete customer docs. %d docs deleted", deletedCount), err)
 		return
 	} else {
+		handlers.AddAuditTargets(c, c.GetString(consts.CustomerGUID))
 		c.JSON(http.StatusOK, gin.H{"deleted": deletedCount})
 	}
 }
@@ -92,6 +103,32 @@ func postCustomerTenant(c *gin.Context) {
 		handlers.ResponseMissingGUID(c)
 		return
 	}
+	//single use provisioning token, released if the tenant is not created
+	tokenID := c.GetString(consts.ProvisioningTokenID)
+	if tokenID != "" {
+		token, err := auth.ConsumeProvisioningToken(c, tokenID, customer.GUID)
+		if auth.IsAuthenticationError(err) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized - provisioning token is used, expired or issued for another customer"})
+			return
+		} else if err != nil {
+			handlers.ResponseInternalServerError(c, "failed to consume provisioning token", err)
+			return
+		}
+		//preset attributes override the request attributes
+		for key, value := range token.Attributes {
+			if customer.Attributes == nil {
+				customer.Attributes = map[string]interface{}{}
+			}
+			customer.Attributes[key] = value
+		}
+		defer func() {
+			if c.Writer.Status() != http.StatusCreated {
+				if err := auth.ReleaseProvisioningToken(c, tokenID); err != nil {
+					log.LogNTraceError("failed to release provisioning token", err, c)
+				}
+			}
+		}()
+	}
 	customer.InitNew()
 	dbDoc := types.Document[*types.Customer]{
 		ID:        customer.GUID,
@@ -99,5 +136,31 @@ func postCustomerTenant(c *gin.Context) {
 		Customers: []string{customer.GUID},
 	}
 	handlers.PostDBDocumentHandler(c, dbDoc)
+	if c.Writer.Status() == http.StatusCreated {
+		auditTenantCreation(c, customer.GUID, tokenID)
+	}
+}
 
+// tenantAudit records the creation of a tenant, it is not owned by the tenant so it is kept when the tenant data is deleted
+type tenantAudit struct {
+	ID                  string    `bson:"_id"`
+	CustomerGUID        string    `bson:"customerGUID"`
+	CreationTime        time.Time `bson:"creationTime"`
+	CreatedBy           string    `bson:"createdBy,omitempty"`           //admin customer GUID, empty when a provisioning token was used
+	ProvisioningTokenID string    `bson:"provisioningTokenID,omitempty"` //provisioning token used to create the tenant
+	ClientIP            string    `bson:"clientIP"`
+}
+
+func auditTenantCreation(c *gin.Context, customerGUID, tokenID string) {
+	audit := tenantAudit{
+		ID:                  uuid.NewV4().String(),
+		CustomerGUID:        customerGUID,
+		CreationTime:        time.Now().UTC(),
+		CreatedBy:           c.GetString(consts.CustomerGUID),
+		ProvisioningTokenID: tokenID,
+		ClientIP:            c.ClientIP(),
+	}
+	if _, err := mongo.GetWriteCollection(consts.TenantsAuditCollection).InsertOne(c, audit); err != nil {
+		log.LogNTraceError(fmt.Sprintf("failed to audit creation of tenant %s", customerGUID), err, c)
+	}
 }
@@ -38,11 +38,11 @@ func getCustomerConfigByNameHandler(c *gin.Context) bool {
 
 	//case default config is requested - return it
 	if configName == consts.GlobalConfigName {
-		c.JSON(http.StatusOK, defaultConfig)
+		handlers.ResponseJSONWithETag(c, http.StatusOK, defaultConfig)
 		return true
 	}
 	//try and get config by name from db
-	doc, err := db.GetDocByName[types.CustomerConfig](c, configName)
+	doc, version, err := db.FindOneForCustomerWithVersion[types.CustomerConfig](c, db.NewFilterBuilder().WithName(configName), nil)
 	if err != nil {
 		handlers.ResponseInternalServerError(c, "failed to get document by name", err)
 		return true
@@ -52,7 +52,9 @@ func getCustomerConfigByNameHandler(c *gin.Context) bool {
 			handlers.ResponseDocumentNotFound(c)
 			return true
 		} else {
-			c.JSON(http.StatusOK, doc)
+			if !handlers.SetDocETag(c, version) {
+				c.JSON(http.StatusOK, doc)
+			}
 			return true
 		}
 	}
@@ -63,12 +65,12 @@ func getCustomerConfigByNameHandler(c *gin.Context) bool {
 				handlers.ResponseInternalServerError(c, "failed to merge configuration", err)
 				return true
 			} else {
-				c.JSON(http.StatusOK, doc)
+				handlers.ResponseJSONWithETag(c, http.StatusOK, doc)
 				return true
 			}
 		} else {
 			//case customer config is requested but not exists - return default config
-			c.JSON(http.StatusOK, defaultConfig)
+			handlers.ResponseJSONWithETag(c, http.StatusOK, defaultConfig)
 			return true
 		}
 	}
@@ -89,7 +91,7 @@ func getCustomerConfigByNameHandler(c *gin.Context) bool {
 		handlers.ResponseInternalServerError(c, "failed to merge configuration", err)
 		return true
 	}
-	c.JSON(http.StatusOK, doc)
+	handlers.ResponseJSONWithETag(c, http.StatusOK, doc)
 	return true
 }
 
@@ -8,11 +8,18 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// fields searched by the q query param
+var textSearchFields = []string{consts.NameField, "description", "controlsIDs"}
+
 func AddRoutes(g *gin.Engine) {
 	handlers.AddRoutes(g, handlers.NewRouterOptionsBuilder[*types.Framework]().
 		WithPath(consts.FrameworkPath).
 		WithDBCollection(consts.FrameworkCollection).
 		WithNameQuery(consts.FrameworkNameParam).
 		WithDeleteByName(true).
+		WithServeRestore(true).
+		WithRouteGroup(consts.RouteGroupSecurity).
+		WithSortFields(handlers.DefaultSortFields...).
+		WithTextSearch(textSearchFields...).
 		Get()...)
 }
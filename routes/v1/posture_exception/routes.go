@@ -8,6 +8,13 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// fields searched by the q query param
+var textSearchFields = []string{
+	consts.NameField,
+	"posturePolicies.frameworkName", "posturePolicies.controlName", "posturePolicies.controlID",
+	"resources.attributes.cluster", "resources.attributes.namespace", "resources.attributes.kind", "resources.attributes.name",
+}
+
 func AddRoutes(g *gin.Engine) {
 	queryParamsConfig := handlers.DefaultQueryConfig()
 	queryParamsConfig.Params2Query["scope"] = handlers.QueryConfig{
@@ -27,5 +34,5 @@ func AddRoutes(g *gin.Engine) {
 	}
 	handlers.AddPolicyRoutes[*types.PostureExceptionPolicy](g,
 		consts.PostureExceptionPolicyPath,
-		consts.PostureExceptionPolicyCollection, queryParamsConfig)
+		consts.PostureExceptionPolicyCollection, queryParamsConfig, textSearchFields...)
 }
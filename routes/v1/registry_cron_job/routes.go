@@ -15,7 +15,9 @@ func AddRoutes(g *gin.Engine) {
 		WithValidatePostUniqueName(true).
 		WithValidatePutGUID(true).
 		WithDeleteByName(true).
+		WithServeRestore(true).
 		WithNameQuery(consts.NameField).
 		WithQueryConfig(handlers.FlatQueryConfig()).
+		WithSortFields(handlers.DefaultSortFields...).
 		Get()...)
 }
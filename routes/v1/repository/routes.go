@@ -8,6 +8,9 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// fields searched by the q query param
+var textSearchFields = []string{consts.NameField, "provider", "owner", "repoName", "branchName"}
+
 func AddRoutes(g *gin.Engine) {
 	//getter for short name base value for repo
 	repoValueGetter := func(doc *types.Repository) string {
@@ -20,6 +23,9 @@ func AddRoutes(g *gin.Engine) {
 		WithValidatePostUniqueName(true).
 		WithValidatePutGUID(true).
 		WithDeleteByName(false).
+		WithServeRestore(true).
 		WithUniqueShortName(repoValueGetter).
+		WithSortFields(handlers.DefaultSortFields...).
+		WithTextSearch(textSearchFields...).
 		Get()...)
 }
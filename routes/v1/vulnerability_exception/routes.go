@@ -8,6 +8,13 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// fields searched by the q query param
+var textSearchFields = []string{
+	consts.NameField,
+	"vulnerabilities.name",
+	"designators.attributes.cluster", "designators.attributes.namespace", "designators.attributes.kind", "designators.attributes.name", "designators.attributes.containerName",
+}
+
 func AddRoutes(g *gin.Engine) {
 	queryParamsConfig := handlers.DefaultQueryConfig()
 	queryParamsConfig.DefaultContext = "designators"
@@ -29,5 +36,5 @@ func AddRoutes(g *gin.Engine) {
 
 	handlers.AddPolicyRoutes[*types.VulnerabilityExceptionPolicy](g,
 		consts.VulnerabilityExceptionPolicyPath,
-		consts.VulnerabilityExceptionPolicyCollection, queryParamsConfig)
+		consts.VulnerabilityExceptionPolicyCollection, queryParamsConfig, textSearchFields...)
 }
@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"config-service/db"
+	"config-service/handlers"
+	"config-service/types"
+	"config-service/utils/consts"
+	"config-service/utils/log"
+	"config-service/utils/webhook"
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/exp/slices"
+)
+
+var eventTypes = []string{db.ChangeEventCreate, db.ChangeEventUpdate, db.ChangeEventDelete}
+
+func AddRoutes(g *gin.Engine) {
+	if err := db.EnsureWebhookDeliveriesIndex(context.Background()); err != nil {
+		panic(err)
+	}
+	webhookRouter := handlers.AddRoutes(g, handlers.NewRouterOptionsBuilder[*types.Webhook]().
+		WithPath(consts.WebhookPath).
+		WithDBCollection(consts.WebhookCollection).
+		WithRouteGroup(consts.RouteGroupAccess).
+		WithPostValidators(validatePostWebhook). //validate the subscription and generate its secret
+		WithPutValidators(validatePutWebhook).
+		WithValidatePostUniqueName(true).
+		Get()...)
+	//add webhook delivery log route
+	webhookRouter.GET("/:"+consts.GUIDField+"/deliveries", handlers.HandleGetWebhookDeliveries)
+}
+
+// validatePostWebhook validates the webhooks and sets their signing key, a secret is generated when not provided
+func validatePostWebhook(c *gin.Context, docs []*types.Webhook) ([]*types.Webhook, bool) {
+	defer log.LogNTraceEnterExit("validatePostWebhook", c)()
+	for i := range docs {
+		if docs[i].URL == "" {
+			handlers.ResponseMissingKey(c, "url")
+			return nil, false
+		}
+		if !validateWebhook(c, docs[i]) {
+			return nil, false
+		}
+		if docs[i].Secret == "" {
+			secret, err := webhook.GenerateSecret()
+			if err != nil {
+				handlers.ResponseInternalServerError(c, "failed to generate webhook secret", err)
+				return nil, false
+			}
+			docs[i].Secret = secret
+		}
+		docs[i].SigningKey = docs[i].Secret
+	}
+	return docs, true
+}
+
+// validatePutWebhook validates the updated webhook fields, the secret cannot be updated
+func validatePutWebhook(c *gin.Context, docs []*types.Webhook) ([]*types.Webhook, bool) {
+	defer log.LogNTraceEnterExit("validatePutWebhook", c)()
+	for i := range docs {
+		if !validateWebhook(c, docs[i]) {
+			return nil, false
+		}
+		docs[i].Secret = ""
+	}
+	return docs, true
+}
+
+func validateWebhook(c *gin.Context, doc *types.Webhook) bool {
+	if doc.URL != "" {
+		if u, err := url.Parse(doc.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			handlers.ResponseBadRequest(c, "url must be an absolute http or https url")
+			return false
+		}
+	}
+	for _, eventType := range doc.EventTypes {
+		if !slices.Contains(eventTypes, eventType) {
+			handlers.ResponseBadRequest(c, fmt.Sprintf("invalid event type %s, event types must be %v", eventType, eventTypes))
+			return false
+		}
+	}
+	return true
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"config-service/db"
 	"config-service/types"
 	"config-service/utils"
 	"config-service/utils/consts"
@@ -357,8 +358,8 @@ func (suite *MainTestSuite) TestCustomer() {
 
 	//create compare options
 
-	//create customer is public so - remove auth cookie
-	suite.authCookie = ""
+	//creating a customer requires admin credentials or a provisioning token
+	suite.loginAsAdmin("admin-guid")
 	//post new customer
 	newCustomer := testPostDoc(suite, "/customer_tenant", customer, customerCompareFilter)
 	//check creation time
@@ -383,6 +384,7 @@ func (suite *MainTestSuite) TestCustomer() {
 	newCustomer.LicenseType = "partial"
 	testPutPartialDoc(suite, "/customer", oldCustomer, partialCustomer, newCustomer, customerCompareFilter)
 	//test post with existing guid - expect error 400
+	suite.loginAsAdmin("admin-guid")
 	testBadRequest(suite, http.MethodPost, "/customer_tenant", errorGUIDExists, customer, http.StatusBadRequest)
 	//test post customer without GUID
 	customer.GUID = ""
@@ -565,8 +567,8 @@ func (suite *MainTestSuite) TestCustomerNotificationConfig() {
 		LicenseType:        "kubescape",
 		InitialLicenseType: "kubescape",
 	}
-	//create customer is public so - remove auth cookie
-	suite.authCookie = ""
+	//creating a customer requires admin credentials or a provisioning token
+	suite.loginAsAdmin("admin-guid")
 	//post new customer
 	testCustomer := testPostDoc(suite, "/customer_tenant", customer, customerCompareFilter)
 	suite.Nil(testCustomer.NotificationsConfig)
@@ -605,6 +607,15 @@ func (suite *MainTestSuite) TestCustomerNotificationConfig() {
 	res, err := decodeResponse[map[string]int](w)
 	suite.NoError(err)
 	suite.Equal(1, res["added"])
+	//the change is audited
+	w = suite.doRequest(http.MethodGet, consts.AuditPath+"?"+consts.TargetGUIDParam+"="+testCustomerGUID+"&"+consts.LimitParam+"=1", nil)
+	suite.Equal(http.StatusOK, w.Code, w.Body.String())
+	audit, err := decodeResponse[*db.AggResult[db.AuditRecord]](w)
+	suite.NoError(err)
+	suite.Len(audit.Results, 1)
+	suite.Equal(unsubscribePath, audit.Results[0].Path)
+	suite.Len(audit.Results[0].Changes, 1)
+	suite.NotEmpty(audit.Results[0].Changes[0].Diff)
 	//send the same element should update noting
 	w = suite.doRequest(http.MethodPut, unsubscribePath, notify)
 	suite.Equal(http.StatusOK, w.Code)
@@ -718,8 +729,8 @@ func (suite *MainTestSuite) TestCustomerState() {
 		LicenseType:        "kubescape",
 		InitialLicenseType: "kubescape",
 	}
-	//create customer is public so - remove auth cookie
-	suite.authCookie = ""
+	//creating a customer requires admin credentials or a provisioning token
+	suite.loginAsAdmin("admin-guid")
 	//post new customer
 	testCustomer := testPostDoc(suite, "/customer_tenant", customer, customerCompareFilter)
 	suite.Nil(testCustomer.State)
@@ -800,8 +811,8 @@ func (suite *MainTestSuite) TestActiveSubscription() {
 		LicenseType:        "kubescape",
 		InitialLicenseType: "kubescape",
 	}
-	//create customer is public so - remove auth cookie
-	suite.authCookie = ""
+	//creating a customer requires admin credentials or a provisioning token
+	suite.loginAsAdmin("admin-guid")
 	//post new customer
 	testCustomer := testPostDoc(suite, "/customer_tenant", customer, customerCompareFilter)
 	suite.Nil(testCustomer.ActiveSubscription)
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"config-service/db"
+	"config-service/db/mongo"
+	"config-service/types"
+	"config-service/utils/consts"
+	"context"
+	"net/http"
+
+	"github.com/armosec/armoapi-go/armotypes"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func (suite *MainTestSuite) TestSoftDelete() {
+	const customerGUID = "soft-delete-customer"
+	suite.login(customerGUID)
+	cluster := &types.Cluster{PortalBase: armotypes.PortalBase{Name: "soft-delete-cluster", Attributes: map[string]interface{}{"env": "dev"}}}
+	cluster = testPostDoc(suite, consts.ClusterPath, cluster, newClusterCompareFilter)
+	path := consts.ClusterPath + "/" + cluster.GUID
+	trashPath := consts.ClusterPath + "?" + consts.DeletedParam + "=true"
+
+	//deleted documents are hidden and listed in the trash
+	testDeleteDocByGUID(suite, consts.ClusterPath, cluster, newClusterCompareFilter)
+	testBadRequest(suite, http.MethodGet, path, errorDocumentNotFound, nil, http.StatusNotFound)
+	testGetDocs(suite, trashPath, []*types.Cluster{cluster}, newClusterCompareFilter)
+	testBadRequest(suite, http.MethodGet, consts.ClusterPath+"?"+consts.DeletedParam+"=maybe", `{"error":"deleted must be true or false"}`, nil, http.StatusBadRequest)
+
+	//restore
+	w := suite.doRequest(http.MethodPost, path+"/restore", nil)
+	suite.Equal(http.StatusOK, w.Code, w.Body.String())
+	testGetDoc(suite, path, cluster, newClusterCompareFilter)
+	testGetDocs(suite, trashPath, []*types.Cluster{})
+	testBadRequest(suite, http.MethodPost, path+"/restore", errorDocumentNotFound, nil, http.StatusNotFound)
+
+	//a deleted document is not restored when its name was taken
+	testDeleteDocByGUID(suite, consts.ClusterPath, cluster, newClusterCompareFilter)
+	sameName := testPostDoc(suite, consts.ClusterPath, &types.Cluster{PortalBase: armotypes.PortalBase{Name: cluster.Name}}, newClusterCompareFilter)
+	testBadRequest(suite, http.MethodPost, path+"/restore", errorNameExist(cluster.Name), nil, http.StatusBadRequest)
+	testDeleteDocByGUID(suite, consts.ClusterPath, sameName, newClusterCompareFilter)
+
+	//the trash is filtered and paginated like other lists
+	other := testPostDoc(suite, consts.ClusterPath, &types.Cluster{PortalBase: armotypes.PortalBase{Name: "soft-delete-other-cluster"}}, newClusterCompareFilter)
+	testDeleteDocByGUID(suite, consts.ClusterPath, other, newClusterCompareFilter)
+	testGetDocs(suite, trashPath+"&"+consts.FilterParam+"=name=="+other.Name, []*types.Cluster{other}, newClusterCompareFilter)
+	w = suite.doRequest(http.MethodGet, trashPath+"&"+consts.LimitParam+"=1", nil)
+	suite.Equal(http.StatusOK, w.Code, w.Body.String())
+	page, err := decodeResponse[*db.AggResult[*types.Cluster]](w)
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	//the cluster, the cluster that took its name and the other cluster
+	suite.Equal(3, page.Metadata.Total)
+	suite.Len(page.Results, 1)
+	suite.Equal(other.GUID, page.Results[0].GUID, "recently deleted first")
+
+	//restored documents are validated like new documents, a short name is generated for a cluster deleted without one
+	if _, err := mongo.GetWriteCollection(consts.ClustersCollection).UpdateOne(context.Background(),
+		bson.M{consts.GUIDField: other.GUID}, bson.M{"$unset": bson.M{"attributes." + consts.ShortNameAttribute: ""}}); err != nil {
+		suite.FailNow(err.Error())
+	}
+	w = suite.doRequest(http.MethodPost, consts.ClusterPath+"/"+other.GUID+"/restore", nil)
+	suite.Equal(http.StatusOK, w.Code, w.Body.String())
+	restored, err := decodeResponse[*types.Cluster](w)
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	suite.NotEmpty(restored.Attributes[consts.ShortNameAttribute])
+	testGetDoc(suite, consts.ClusterPath+"/"+other.GUID, restored, newClusterCompareFilter)
+	testDeleteDocByGUID(suite, consts.ClusterPath, restored, newClusterCompareFilter)
+
+	//other customers trash
+	suite.login("soft-delete-other-customer")
+	testGetDocs(suite, trashPath, []*types.Cluster{})
+	testBadRequest(suite, http.MethodPost, path+"/restore", errorDocumentNotFound, nil, http.StatusNotFound)
+
+	//purge
+	suite.loginAsAdmin("soft-delete-admin")
+	testBadRequest(suite, http.MethodDelete, consts.AdminPath+"/deletedDocuments?"+consts.RetentionParam+"=week", `{"error":"retention must be a non negative duration (e.g. 720h)"}`, nil, http.StatusBadRequest)
+	w = suite.doRequest(http.MethodDelete, consts.AdminPath+"/deletedDocuments?"+consts.RetentionParam+"=0s", nil)
+	suite.Equal(http.StatusOK, w.Code, w.Body.String())
+	purged, err := decodeResponse[*struct {
+		Purged int64 `json:"purged"`
+	}](w)
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	suite.GreaterOrEqual(purged.Purged, int64(2))
+	suite.login(customerGUID)
+	testGetDocs(suite, trashPath, []*types.Cluster{})
+}
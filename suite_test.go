@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"config-service/db/mongo"
 	"config-service/types"
+	"config-service/utils/auth"
 	"config-service/utils/consts"
 	"context"
 	_ "embed"
@@ -48,6 +49,7 @@ type MainTestSuite struct {
 	shutdownFunc     func()
 	authCookie       string
 	authCustomerGUID string
+	requestHeaders   map[string]string
 }
 
 func (suite *MainTestSuite) SetupSuite() {
@@ -87,36 +89,31 @@ func (suite *MainTestSuite) SetupTest() {
 	suite.login(defaultUserGUID)
 }
 
+// login creates a session of the customer, the login route creates sessions only for verified bearer tokens
 func (suite *MainTestSuite) login(customerGUID string) {
-	loginDetails := struct {
-		CustomerGUID string `json:"customerGUID"`
-	}{
-		CustomerGUID: customerGUID,
-	}
-	w := suite.doRequest(http.MethodPost, "/login", loginDetails)
-	if w.Code != http.StatusOK {
-		suite.FailNow("failed to login")
-	}
-	suite.authCookie = w.Header().Get("Set-Cookie")
-	suite.authCustomerGUID = customerGUID
+	suite.loginWithClaims(auth.NewSessionClaims(customerGUID, false, time.Hour))
 }
 
+// loginAsAdmin creates an admin session
 func (suite *MainTestSuite) loginAsAdmin(customerGUID string) {
-	loginDetails := struct {
-		CustomerGUID string                 `json:"customerGUID"`
-		Attributes   map[string]interface{} `json:"attributes"`
-	}{
-		CustomerGUID: customerGUID,
-		Attributes: map[string]interface{}{
-			"admin": true,
-		},
+	suite.loginWithClaims(auth.NewSessionClaims(customerGUID, true, time.Hour))
+}
+
+// loginWithRoles creates a session with the given roles
+func (suite *MainTestSuite) loginWithRoles(customerGUID string, roles ...string) {
+	suite.loginWithClaims(auth.NewSessionClaims(customerGUID, false, time.Hour, roles...))
+}
+
+func (suite *MainTestSuite) loginWithClaims(claims auth.SessionClaims) {
+	if err := auth.CreateSession(context.Background(), &claims); err != nil {
+		suite.FailNow("failed to create session", err.Error())
 	}
-	w := suite.doRequest(http.MethodPost, "/login", loginDetails)
-	if w.Code != http.StatusOK {
-		suite.FailNow("failed to login")
+	token, err := auth.SignSessionToken(claims, auth.GetSigningKey())
+	if err != nil {
+		suite.FailNow("failed to sign session token", err.Error())
 	}
-	suite.authCookie = w.Header().Get("Set-Cookie")
-	suite.authCustomerGUID = customerGUID
+	suite.authCookie = consts.CustomerGUID + "=" + token
+	suite.authCustomerGUID = claims.CustomerGUID
 }
 
 func (suite *MainTestSuite) TearDownSuite() {
@@ -145,6 +142,9 @@ func (suite *MainTestSuite) doRequest(method, path string, body interface{}) *ht
 	if suite.authCookie != "" {
 		req.Header.Set("Cookie", suite.authCookie)
 	}
+	for key, value := range suite.requestHeaders {
+		req.Header.Set(key, value)
+	}
 	suite.router.ServeHTTP(w, req)
 
 	return w
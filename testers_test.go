@@ -200,11 +200,12 @@ func testGetDeleteByNameAndQuery[T types.DocContent](suite *MainTestSuite, baseP
 
 const (
 	//error messages
-	errorMissingName      = `{"error":"name is required"}`
-	errorMissingGUID      = `{"error":"guid is required"}`
-	errorGUIDExists       = `{"error":"guid already exists"}`
-	errorDocumentNotFound = `{"error":"document not found"}`
-	errorNotAdminUser     = `{"error":"Unauthorized - not an admin user"}`
+	errorMissingName        = `{"error":"name is required"}`
+	errorMissingGUID        = `{"error":"guid is required"}`
+	errorGUIDExists         = `{"error":"guid already exists"}`
+	errorDocumentNotFound   = `{"error":"document not found"}`
+	errorPreconditionFailed = `{"error":"document was modified, read it and try again"}`
+	errorNotAdminUser       = `{"error":"Forbidden - DELETE is not permitted on admin routes"}`
 )
 
 func errorBadTimeParam(paramName string) string {
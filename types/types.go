@@ -31,10 +31,15 @@ func NewDocument[T DocContent](content T, customerGUID string) Document[T] {
 	return doc
 }
 
+// Redactor is implemented by documents with secrets, the redacted copy is sent outside of the service responses (e.g. to webhooks)
+type Redactor interface {
+	Redacted() interface{}
+}
+
 // Doc Content interface for data types embedded in DB documents
 type DocContent interface {
 	*CustomerConfig | *Cluster | *PostureExceptionPolicy | *VulnerabilityExceptionPolicy | *Customer |
-		*Framework | *Repository | *RegistryCronJob
+		*Framework | *Repository | *RegistryCronJob | *APIKey | *Webhook
 	InitNew()
 	GetReadOnlyFields() []string
 	//default implementation exist in portal base
@@ -273,9 +278,103 @@ func (r *RegistryCronJob) GetCreationTime() *time.Time {
 	return &creationTime
 }
 
+// APIKey is a long lived customer credential, only the key hash is stored
+type APIKey struct {
+	armotypes.PortalBase `json:",inline" bson:"inline"`
+	Key                  string        `json:"key,omitempty" bson:"-"` //plain key, returned only on creation
+	KeyHash              string        `json:"-" bson:"keyHash"`
+	KeyPrefix            string        `json:"keyPrefix" bson:"keyPrefix"` //first characters of the key for identification
+	Scopes               []APIKeyScope `json:"scopes" bson:"scopes"`
+	CreationTime         string        `json:"creationTime" bson:"creationTime"`
+	ExpirationTime       string        `json:"expirationTime,omitempty" bson:"expirationTime,omitempty"`
+}
+
+// APIKeyScope grants read or write access to a path
+type APIKeyScope struct {
+	Path   string `json:"path" bson:"path"`     //route path (e.g. "/cluster") or "*" for all paths
+	Access string `json:"access" bson:"access"` //"read" or "write", write access includes read access
+}
+
+func (*APIKey) GetReadOnlyFields() []string {
+	return apiKeyReadOnlyFields
+}
+
+func (k *APIKey) InitNew() {
+	k.CreationTime = time.Now().UTC().Format(time.RFC3339)
+}
+
+func (k *APIKey) GetCreationTime() *time.Time {
+	if k.CreationTime == "" {
+		return nil
+	}
+	creationTime, err := time.Parse(time.RFC3339, k.CreationTime)
+	if err != nil {
+		return nil
+	}
+	return &creationTime
+}
+
+// Redacted returns a copy of the key without the plain key
+func (k *APIKey) Redacted() interface{} {
+	redacted := *k
+	redacted.Key = ""
+	return &redacted
+}
+
+func (k *APIKey) GetExpirationTime() *time.Time {
+	if k.ExpirationTime == "" {
+		return nil
+	}
+	expirationTime, err := time.Parse(time.RFC3339, k.ExpirationTime)
+	if err != nil {
+		return nil
+	}
+	return &expirationTime
+}
+
+// Webhook is a customer subscription to documents changes, the changes are posted to the URL signed with the webhook secret
+type Webhook struct {
+	armotypes.PortalBase `json:",inline" bson:"inline"`
+	URL                  string   `json:"url" bson:"url"`
+	EventTypes           []string `json:"eventTypes,omitempty" bson:"eventTypes,omitempty"`   //create, update or delete, all events when empty
+	Collections          []string `json:"collections,omitempty" bson:"collections,omitempty"` //changed documents collections (e.g. "clusters"), all collections when empty
+	Secret               string   `json:"secret,omitempty" bson:"-"`                          //payloads signing secret, returned only on creation
+	SigningKey           string   `json:"-" bson:"signingKey"`
+	CreationTime         string   `json:"creationTime" bson:"creationTime"`
+}
+
+// Redacted returns a copy of the webhook without its secret
+func (w *Webhook) Redacted() interface{} {
+	redacted := *w
+	redacted.Secret = ""
+	redacted.SigningKey = ""
+	return &redacted
+}
+
+func (*Webhook) GetReadOnlyFields() []string {
+	return webhookReadOnlyFields
+}
+
+func (w *Webhook) InitNew() {
+	w.CreationTime = time.Now().UTC().Format(time.RFC3339)
+}
+
+func (w *Webhook) GetCreationTime() *time.Time {
+	if w.CreationTime == "" {
+		return nil
+	}
+	creationTime, err := time.Parse(time.RFC3339, w.CreationTime)
+	if err != nil {
+		return nil
+	}
+	return &creationTime
+}
+
 var commonReadOnlyFields = []string{consts.IdField, consts.NameField, consts.GUIDField}
 var clusterReadOnlyFields = append([]string{"subscription_date"}, commonReadOnlyFields...)
 var exceptionPolicyReadOnlyFields = append([]string{"creationTime"}, commonReadOnlyFields...)
 var customerConfigReadOnlyFields = append([]string{"creationTime"}, commonReadOnlyFields...)
 var repositoryReadOnlyFields = append([]string{"creationDate", "provider", "owner", "repoName", "branchName"}, commonReadOnlyFields...)
 var croneJobReadOnlyFields = append([]string{"creationTime", "clusterName", "registryName"}, commonReadOnlyFields...)
+var apiKeyReadOnlyFields = append([]string{"creationTime", "keyHash", "keyPrefix"}, commonReadOnlyFields...)
+var webhookReadOnlyFields = append([]string{"creationTime", "signingKey"}, commonReadOnlyFields...)
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"config-service/types"
+	"config-service/utils/consts"
+	"net/http"
+)
+
+func (suite *MainTestSuite) TestUpsert() {
+	policies, _ := loadJson[*types.PostureExceptionPolicy](posturePoliciesJson)
+	policy := policies[0]
+	policy.GUID = ""
+	policy.Name = ""
+	path := consts.PostureExceptionPolicyPath + "?upsert=true&name=upsert-policy"
+
+	//create
+	w := suite.doRequest(http.MethodPut, path, policy)
+	suite.Equal(http.StatusCreated, w.Code, w.Body.String())
+	created, err := decodeResponse[*types.PostureExceptionPolicy](w)
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	suite.NotEmpty(created.GUID)
+	suite.Equal("upsert-policy", created.Name)
+
+	//update
+	policy.Attributes = map[string]interface{}{"upsert": "updated"}
+	w = suite.doRequest(http.MethodPut, path, policy)
+	suite.Equal(http.StatusOK, w.Code, w.Body.String())
+	docs, err := decodeResponseArray[*types.PostureExceptionPolicy](w)
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	suite.Len(docs, 2)
+	suite.Equal(created.GUID, docs[1].GUID)
+	suite.Equal(policy.Attributes, docs[1].Attributes)
+
+	//the name can be in the body
+	policy.Name = "upsert-policy"
+	policy.Attributes = map[string]interface{}{"upsert": "updated-again"}
+	w = suite.doRequest(http.MethodPut, consts.PostureExceptionPolicyPath+"?upsert=true", policy)
+	suite.Equal(http.StatusOK, w.Code, w.Body.String())
+
+	testBadRequest(suite, http.MethodPut, consts.PostureExceptionPolicyPath+"?upsert=true&name=other-name", `{"error":"name in body does not match the name query param"}`, policy, http.StatusBadRequest)
+	policy.Name = ""
+	testBadRequest(suite, http.MethodPut, consts.PostureExceptionPolicyPath+"?upsert=true", errorMissingName, policy, http.StatusBadRequest)
+	testBadRequest(suite, http.MethodPut, consts.PostureExceptionPolicyPath+"?upsert=maybe", `{"error":"upsert must be true or false"}`, policy, http.StatusBadRequest)
+	//without upsert the guid is required
+	testBadRequest(suite, http.MethodPut, consts.PostureExceptionPolicyPath+"?name=upsert-policy", errorMissingGUID, policy, http.StatusBadRequest)
+
+	testDeleteDocByGUID(suite, consts.PostureExceptionPolicyPath, docs[1], commonCmpFilter)
+}
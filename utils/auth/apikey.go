@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"config-service/db/mongo"
+	"config-service/types"
+	"config-service/utils/consts"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	mongoDB "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	apiKeyPrefix       = "cs_"
+	apiKeyPrefixLength = len(apiKeyPrefix) + 8
+
+	ScopeAccessRead  = "read"
+	ScopeAccessWrite = "write"
+	ScopeAllPaths    = "*"
+)
+
+// GenerateAPIKey returns a new random API key, its hash for storage and its display prefix
+func GenerateAPIKey() (key, hash, prefix string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+	key = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return key, HashAPIKey(key), key[:apiKeyPrefixLength], nil
+}
+
+// InitAPIKeyStore creates the unique index of the keys hashes, keys are looked up by their hash on every api key request
+func InitAPIKeyStore(c context.Context) error {
+	_, err := mongo.GetWriteCollection(consts.APIKeyCollection).Indexes().CreateOne(c, mongoDB.IndexModel{
+		Keys:    bson.D{{Key: consts.KeyHashField, Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// HashAPIKey returns the hex encoded sha256 of the key
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidScope returns true if the scope has a path and a known access level
+func ValidScope(scope types.APIKeyScope) bool {
+	return scope.Path != "" && (scope.Access == ScopeAccessRead || scope.Access == ScopeAccessWrite)
+}
+
+// ScopesAllow returns true if one of the scopes grants access to the request method and path
+func ScopesAllow(scopes []types.APIKeyScope, method, path string) bool {
+	for _, scope := range scopes {
+		if !scopePathMatch(scope.Path, path) {
+			continue
+		}
+		if scope.Access == ScopeAccessWrite || isReadMethod(method) {
+			return true
+		}
+	}
+	return false
+}
+
+func scopePathMatch(scopePath, path string) bool {
+	if scopePath == ScopeAllPaths {
+		return true
+	}
+	scopePath = strings.TrimSuffix(scopePath, "/")
+	return path == scopePath || strings.HasPrefix(path, scopePath+"/")
+}
+
+func isReadMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
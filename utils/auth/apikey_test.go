@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"config-service/types"
+	"net/http"
+	"testing"
+)
+
+func TestScopesAllow(t *testing.T) {
+	scopes := []types.APIKeyScope{
+		{Path: "/cluster", Access: ScopeAccessWrite},
+		{Path: "/v1_registry_cron_job/", Access: ScopeAccessRead},
+	}
+	tests := []struct {
+		method string
+		path   string
+		want   bool
+	}{
+		{method: http.MethodGet, path: "/cluster", want: true},
+		{method: http.MethodPost, path: "/cluster", want: true},
+		{method: http.MethodDelete, path: "/cluster/some-guid", want: true},
+		{method: http.MethodGet, path: "/clusters", want: false},
+		{method: http.MethodGet, path: "/v1_registry_cron_job", want: true},
+		{method: http.MethodGet, path: "/v1_registry_cron_job/some-guid", want: true},
+		{method: http.MethodPut, path: "/v1_registry_cron_job", want: false},
+		{method: http.MethodGet, path: "/v1_posture_exception_policy", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+tt.path, func(t *testing.T) {
+			if got := ScopesAllow(scopes, tt.method, tt.path); got != tt.want {
+				t.Errorf("ScopesAllow(%s %s) = %v, want %v", tt.method, tt.path, got, tt.want)
+			}
+		})
+	}
+	if !ScopesAllow([]types.APIKeyScope{{Path: ScopeAllPaths, Access: ScopeAccessRead}}, http.MethodGet, "/v1_opa_framework") {
+		t.Errorf("ScopesAllow() all paths scope should allow any path")
+	}
+}
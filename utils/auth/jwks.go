@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// minimal interval between key set reloads when an unknown key id is requested
+const keySetMinRefreshInterval = time.Minute
+
+// jsonWebKey is a public key in JWK format (RFC 7517)
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	//RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	//EC keys
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// KeySet holds the public keys of a JSON Web Key Set loaded from a url or a local file
+type KeySet struct {
+	url         string
+	file        string
+	client      *http.Client
+	keys        map[string]crypto.PublicKey
+	lastRefresh time.Time
+	mutex       sync.RWMutex
+}
+
+func NewKeySet(url, file string) (*KeySet, error) {
+	if url == "" && file == "" {
+		return nil, fmt.Errorf("key set url or file must be set")
+	}
+	k := &KeySet{
+		url:    url,
+		file:   file,
+		client: &http.Client{Timeout: 10 * time.Second},
+		keys:   map[string]crypto.PublicKey{},
+	}
+	if err := k.refresh(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// GetKey returns the public key with the given key id, the key set is reloaded if the key is unknown
+func (k *KeySet) GetKey(kid string) (crypto.PublicKey, error) {
+	k.mutex.RLock()
+	key, ok := k.keys[kid]
+	lastRefresh := k.lastRefresh
+	k.mutex.RUnlock()
+	if ok {
+		return key, nil
+	}
+	if time.Since(lastRefresh) > keySetMinRefreshInterval {
+		if err := k.refresh(); err != nil {
+			zap.L().Error("failed to refresh key set", zap.Error(err))
+		}
+		k.mutex.RLock()
+		key, ok = k.keys[kid]
+		k.mutex.RUnlock()
+		if ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("key %s not found in key set", kid)
+}
+
+func (k *KeySet) refresh() error {
+	data, err := k.load()
+	if err != nil {
+		return err
+	}
+	var set jsonWebKeySet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("failed to decode key set: %w", err)
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			zap.L().Warn("skipping invalid key in key set", zap.String("kid", jwk.Kid), zap.Error(err))
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.keys = keys
+	k.lastRefresh = time.Now()
+	return nil
+}
+
+func (k *KeySet) load() ([]byte, error) {
+	if k.url == "" {
+		return os.ReadFile(k.file)
+	}
+	res, err := k.client.Get(k.url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get key set from %s status: %d", k.url, res.StatusCode)
+	}
+	return io.ReadAll(res.Body)
+}
+
+func (j *jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := decodeBigInt(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(j.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", j.Crv)
+		}
+		x, err := decodeBigInt(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", j.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, fmt.Errorf("missing key parameter")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
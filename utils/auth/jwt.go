@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"config-service/utils"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var supportedSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// JWTValidator validates bearer JWTs against a JSON Web Key Set and maps their claims to session claims
+type JWTValidator struct {
+	config utils.JWTConfig
+	keySet *KeySet
+	parser *jwt.Parser
+}
+
+func NewJWTValidator(config utils.JWTConfig) (*JWTValidator, error) {
+	keySet, err := NewKeySet(config.JWKSURL, config.JWKSFile)
+	if err != nil {
+		return nil, err
+	}
+	if config.CustomerGUIDClaim == "" {
+		config.CustomerGUIDClaim = utils.DefaultCustomerGUIDClaim
+	}
+	if config.AdminClaim == "" {
+		config.AdminClaim = utils.DefaultAdminClaim
+	}
+	if config.RolesClaim == "" {
+		config.RolesClaim = utils.DefaultRolesClaim
+	}
+	return &JWTValidator{
+		config: config,
+		keySet: keySet,
+		parser: jwt.NewParser(jwt.WithValidMethods(supportedSigningMethods)),
+	}, nil
+}
+
+// Validate verifies the token signature, expiration, issuer and audience and returns the mapped session claims
+func (v *JWTValidator) Validate(tokenString string) (*SessionClaims, error) {
+	claims := jwt.MapClaims{}
+	if _, err := v.parser.ParseWithClaims(tokenString, claims, v.keyFunc); err != nil {
+		if validationErr, ok := err.(*jwt.ValidationError); ok && validationErr.Errors&jwt.ValidationErrorExpired != 0 {
+			return nil, ErrExpiredToken
+		}
+		return nil, fmt.Errorf("%w: %s", ErrInvalidToken, err.Error())
+	}
+	if _, ok := claims["exp"]; !ok {
+		return nil, fmt.Errorf("%w: missing exp claim", ErrInvalidToken)
+	}
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, ErrExpiredToken
+	}
+	if v.config.Issuer != "" && !claims.VerifyIssuer(v.config.Issuer, true) {
+		return nil, fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	}
+	if v.config.Audience != "" && !claims.VerifyAudience(v.config.Audience, true) {
+		return nil, fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+	}
+	customerGUID, _ := claims[v.config.CustomerGUIDClaim].(string)
+	if customerGUID == "" {
+		return nil, fmt.Errorf("%w: missing %s claim", ErrInvalidToken, v.config.CustomerGUIDClaim)
+	}
+	sessionClaims := &SessionClaims{
+		CustomerGUID: customerGUID,
+		AdminAccess:  v.isAdmin(claims[v.config.AdminClaim]),
+		Roles:        stringsClaim(claims[v.config.RolesClaim]),
+	}
+	if exp, ok := claims["exp"].(float64); ok {
+		sessionClaims.ExpiresAt = int64(exp)
+	}
+	if iat, ok := claims["iat"].(float64); ok {
+		sessionClaims.IssuedAt = int64(iat)
+	}
+	return sessionClaims, nil
+}
+
+func (v *JWTValidator) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	return v.keySet.GetKey(kid)
+}
+
+// isAdmin checks the admin claim value, if adminClaimValue is configured the claim must equal or contain it otherwise it must be true
+func (v *JWTValidator) isAdmin(claim interface{}) bool {
+	if v.config.AdminClaimValue == "" {
+		admin, _ := claim.(bool)
+		return admin
+	}
+	switch value := claim.(type) {
+	case string:
+		return value == v.config.AdminClaimValue
+	case []interface{}:
+		for _, item := range value {
+			if s, ok := item.(string); ok && s == v.config.AdminClaimValue {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// stringsClaim returns a string or array of strings claim as a slice
+func stringsClaim(claim interface{}) []string {
+	switch value := claim.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		values := []string{}
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"config-service/utils"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestJWTValidator(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//write local key set with the public key
+	keySet := jsonWebKeySet{Keys: []jsonWebKey{{
+		Kty: "RSA",
+		Kid: "test-kid",
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+	keySetBytes, _ := json.Marshal(keySet)
+	keySetFile := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(keySetFile, keySetBytes, 0600); err != nil {
+		t.Fatal(err)
+	}
+	validator, err := NewJWTValidator(utils.JWTConfig{
+		JWKSFile:          keySetFile,
+		Issuer:            "https://issuer.test",
+		Audience:          "config-service",
+		CustomerGUIDClaim: "tenant",
+		AdminClaim:        "roles",
+		AdminClaimValue:   "admin",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(signingKey *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(signingKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	validClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":    "https://issuer.test",
+			"aud":    "config-service",
+			"exp":    time.Now().Add(time.Hour).Unix(),
+			"tenant": "customer1",
+			"roles":  []string{"viewer", "admin"},
+		}
+	}
+	withClaim := func(key string, value interface{}) jwt.MapClaims {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = value
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name      string
+		token     string
+		wantErr   error
+		wantAdmin bool
+	}{
+		{name: "valid admin", token: sign(key, "test-kid", validClaims()), wantAdmin: true},
+		{name: "valid not admin", token: sign(key, "test-kid", withClaim("roles", []string{"viewer"}))},
+		{name: "expired", token: sign(key, "test-kid", withClaim("exp", time.Now().Add(-time.Minute).Unix())), wantErr: ErrExpiredToken},
+		{name: "missing expiration", token: sign(key, "test-kid", withClaim("exp", nil)), wantErr: ErrInvalidToken},
+		{name: "wrong issuer", token: sign(key, "test-kid", withClaim("iss", "https://other.test")), wantErr: ErrInvalidToken},
+		{name: "wrong audience", token: sign(key, "test-kid", withClaim("aud", "other-service")), wantErr: ErrInvalidToken},
+		{name: "missing customer claim", token: sign(key, "test-kid", withClaim("tenant", nil)), wantErr: ErrInvalidToken},
+		{name: "unknown key", token: sign(otherKey, "other-kid", validClaims()), wantErr: ErrInvalidToken},
+		{name: "wrong signature", token: sign(otherKey, "test-kid", validClaims()), wantErr: ErrInvalidToken},
+		{name: "not a jwt", token: "some-token", wantErr: ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := validator.Validate(tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if claims.CustomerGUID != "customer1" || claims.AdminAccess != tt.wantAdmin {
+				t.Errorf("Validate() unexpected claims %+v", claims)
+			}
+		})
+	}
+}
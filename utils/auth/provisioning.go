@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"config-service/db"
+	"config-service/db/mongo"
+	"config-service/utils/consts"
+	"context"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	mongoDB "go.mongodb.org/mongo-driver/mongo"
+)
+
+const DefaultProvisioningTokenTTL = 24 * time.Hour
+
+// ProvisioningClaims are the claims carried by a signed provisioning token
+type ProvisioningClaims struct {
+	TokenType string `json:"typ"`
+	TokenID   string `json:"jti"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// ProvisioningToken is the record of an issued provisioning token, each token can create a single tenant
+type ProvisioningToken struct {
+	ID             string                 `json:"guid" bson:"_id"`
+	Token          string                 `json:"token,omitempty" bson:"-"`                         //the signed token, returned only when issued
+	CustomerGUID   string                 `json:"customerGUID,omitempty" bson:"customerGUID"`       //when set, the token can only create the tenant with this GUID
+	Attributes     map[string]interface{} `json:"attributes,omitempty" bson:"attributes,omitempty"` //preset attributes of the created tenant
+	IssuedBy       string                 `json:"issuedBy" bson:"issuedBy"`
+	CreationTime   time.Time              `json:"creationTime" bson:"creationTime"`
+	ExpirationTime time.Time              `json:"expirationTime" bson:"expirationTime"`
+	UsedBy         string                 `json:"usedBy,omitempty" bson:"usedBy,omitempty"` //GUID of the tenant created with the token
+}
+
+// IssueProvisioningToken stores the token record and sets its id and signed token
+func IssueProvisioningToken(c context.Context, record *ProvisioningToken) error {
+	record.ID = uuid.NewV4().String()
+	record.CreationTime = time.Now().UTC()
+	token, err := SignProvisioningToken(ProvisioningClaims{TokenID: record.ID, ExpiresAt: record.ExpirationTime.Unix()}, GetSigningKey())
+	if err != nil {
+		return err
+	}
+	if _, err := mongo.GetWriteCollection(consts.ProvisioningTokensCollection).InsertOne(c, record); err != nil {
+		return err
+	}
+	record.Token = token
+	return nil
+}
+
+// SignProvisioningToken encodes the claims as a provisioning token and signs them with HMAC-SHA256
+func SignProvisioningToken(claims ProvisioningClaims, key []byte) (string, error) {
+	claims.TokenType = TokenTypeProvisioning
+	return signClaims(claims, key)
+}
+
+// ParseProvisioningToken verifies the token signature and expiration and returns its claims
+func ParseProvisioningToken(token string, key []byte) (*ProvisioningClaims, error) {
+	claims := &ProvisioningClaims{}
+	if err := parseClaims(token, key, claims); err != nil || claims.TokenType != TokenTypeProvisioning || claims.TokenID == "" {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().UTC().Unix() >= claims.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+	return claims, nil
+}
+
+// ConsumeProvisioningToken marks the token as used by the customer and returns its record
+// returns ErrInvalidToken if the token was already used, expired or issued for another customer
+func ConsumeProvisioningToken(c context.Context, tokenID, customerGUID string) (*ProvisioningToken, error) {
+	filter := db.NewFilterBuilder().
+		WithID(tokenID).
+		WithExists(consts.UsedByField, false).
+		WithIn(consts.CustomerGUIDField, []string{"", customerGUID}).
+		WithValue(consts.ExpirationTimeField, bson.D{{Key: "$gt", Value: time.Now().UTC()}})
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: consts.UsedByField, Value: customerGUID}}}}
+	record := &ProvisioningToken{}
+	if err := mongo.GetWriteCollection(consts.ProvisioningTokensCollection).FindOneAndUpdate(c, filter.Get(), update).Decode(record); err == mongoDB.ErrNoDocuments {
+		return nil, ErrInvalidToken
+	} else if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// ReleaseProvisioningToken makes a consumed token usable again, used when the tenant creation failed
+func ReleaseProvisioningToken(c context.Context, tokenID string) error {
+	update := bson.D{{Key: "$unset", Value: bson.D{{Key: consts.UsedByField, Value: ""}}}}
+	_, err := mongo.GetWriteCollection(consts.ProvisioningTokensCollection).UpdateOne(c, db.NewFilterBuilder().WithID(tokenID).Get(), update)
+	return err
+}
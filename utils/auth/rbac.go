@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"config-service/utils"
+	"config-service/utils/consts"
+	"net/http"
+	"sync"
+
+	"golang.org/x/exp/slices"
+)
+
+const (
+	RoleViewer        = "viewer"
+	RoleEditor        = "editor"
+	RoleSecurityAdmin = "security-admin"
+	RolePlatformAdmin = "platform-admin"
+
+	allValues = "*"
+)
+
+var readMethods = []string{http.MethodGet, http.MethodHead, http.MethodOptions}
+var writeMethods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// defaultPolicy is used when no policy is configured
+var defaultPolicy = map[string][]utils.Permission{
+	RoleViewer: {
+		{Methods: readMethods, RouteGroups: []string{consts.RouteGroupConfig, consts.RouteGroupSecurity}},
+	},
+	RoleEditor: {
+		{Methods: readMethods, RouteGroups: []string{consts.RouteGroupConfig, consts.RouteGroupSecurity}},
+		{Methods: writeMethods, RouteGroups: []string{consts.RouteGroupConfig}},
+	},
+	RoleSecurityAdmin: {
+		{Methods: readMethods, RouteGroups: []string{consts.RouteGroupConfig, consts.RouteGroupSecurity, consts.RouteGroupAccess}},
+		{Methods: writeMethods, RouteGroups: []string{consts.RouteGroupSecurity, consts.RouteGroupAccess}},
+	},
+	RolePlatformAdmin: {
+		{Methods: []string{allValues}, RouteGroups: []string{allValues}},
+	},
+}
+
+// defaultRoles are given to sessions without roles when no default roles are configured, they grant full access to the customer's tenant
+var defaultRoles = []string{RoleEditor, RoleSecurityAdmin}
+
+var policy map[string][]utils.Permission
+var policyOnce sync.Once
+
+func getPolicy() map[string][]utils.Permission {
+	policyOnce.Do(func() {
+		if policy = utils.GetConfig().Auth.RBAC.Policy; len(policy) == 0 {
+			policy = defaultPolicy
+		}
+	})
+	return policy
+}
+
+// ResolveRoles returns the session roles, sessions without roles get the default roles
+// admin sessions and configured admin users get the platform admin role
+func ResolveRoles(claims *SessionClaims) []string {
+	roles := claims.Roles
+	if len(roles) == 0 {
+		if roles = utils.GetConfig().Auth.RBAC.DefaultRoles; len(roles) == 0 {
+			roles = defaultRoles
+		}
+	}
+	roles = append([]string{}, roles...)
+	if (claims.AdminAccess || slices.Contains(utils.GetConfig().AdminUsers, claims.CustomerGUID)) && !slices.Contains(roles, RolePlatformAdmin) {
+		roles = append(roles, RolePlatformAdmin)
+	}
+	return roles
+}
+
+// Authorize returns true if one of the roles permits the method on the route group
+func Authorize(roles []string, method, routeGroup string) bool {
+	p := getPolicy()
+	for _, role := range roles {
+		for _, permission := range p[role] {
+			if matchAny(permission.Methods, method) && matchAny(permission.RouteGroups, routeGroup) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchAny(values []string, value string) bool {
+	return slices.Contains(values, allValues) || slices.Contains(values, value)
+}
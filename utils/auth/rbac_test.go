@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"config-service/utils/consts"
+	"net/http"
+	"os"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestMain(m *testing.M) {
+	//roles and policy defaults are read from the configuration
+	os.Setenv("CONFIG_PATH", "../../config.json")
+	os.Exit(m.Run())
+}
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		roles      []string
+		method     string
+		routeGroup string
+		want       bool
+	}{
+		{roles: []string{RoleViewer}, method: http.MethodGet, routeGroup: consts.RouteGroupConfig, want: true},
+		{roles: []string{RoleViewer}, method: http.MethodGet, routeGroup: consts.RouteGroupSecurity, want: true},
+		{roles: []string{RoleViewer}, method: http.MethodPost, routeGroup: consts.RouteGroupConfig, want: false},
+		{roles: []string{RoleViewer}, method: http.MethodGet, routeGroup: consts.RouteGroupAccess, want: false},
+		{roles: []string{RoleEditor}, method: http.MethodPut, routeGroup: consts.RouteGroupConfig, want: true},
+		{roles: []string{RoleEditor}, method: http.MethodDelete, routeGroup: consts.RouteGroupSecurity, want: false},
+		{roles: []string{RoleSecurityAdmin}, method: http.MethodPost, routeGroup: consts.RouteGroupSecurity, want: true},
+		{roles: []string{RoleSecurityAdmin}, method: http.MethodPost, routeGroup: consts.RouteGroupAccess, want: true},
+		{roles: []string{RoleSecurityAdmin}, method: http.MethodPost, routeGroup: consts.RouteGroupConfig, want: false},
+		{roles: []string{RoleEditor, RoleSecurityAdmin}, method: http.MethodGet, routeGroup: consts.RouteGroupAdmin, want: false},
+		{roles: []string{RolePlatformAdmin}, method: http.MethodDelete, routeGroup: consts.RouteGroupAdmin, want: true},
+		{roles: []string{"unknown"}, method: http.MethodGet, routeGroup: consts.RouteGroupConfig, want: false},
+		{roles: nil, method: http.MethodGet, routeGroup: consts.RouteGroupConfig, want: false},
+	}
+	for _, tt := range tests {
+		if got := Authorize(tt.roles, tt.method, tt.routeGroup); got != tt.want {
+			t.Errorf("Authorize(%v, %s, %s) = %v, want %v", tt.roles, tt.method, tt.routeGroup, got, tt.want)
+		}
+	}
+}
+
+func TestResolveRoles(t *testing.T) {
+	roles := ResolveRoles(&SessionClaims{CustomerGUID: "customer1"})
+	if !slices.Equal(roles, defaultRoles) {
+		t.Errorf("ResolveRoles() without roles = %v, want default roles %v", roles, defaultRoles)
+	}
+	roles = ResolveRoles(&SessionClaims{CustomerGUID: "customer1", Roles: []string{RoleViewer}})
+	if !slices.Equal(roles, []string{RoleViewer}) {
+		t.Errorf("ResolveRoles() = %v, want [%s]", roles, RoleViewer)
+	}
+	roles = ResolveRoles(&SessionClaims{CustomerGUID: "customer1", AdminAccess: true, Roles: []string{RoleViewer}})
+	if !slices.Equal(roles, []string{RoleViewer, RolePlatformAdmin}) {
+		t.Errorf("ResolveRoles() with admin access = %v, want [%s %s]", roles, RoleViewer, RolePlatformAdmin)
+	}
+}
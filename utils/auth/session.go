@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"config-service/db"
+	"config-service/db/mongo"
+	"config-service/utils"
+	"config-service/utils/consts"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	mongoDB "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrRevokedSession = errors.New("session revoked")
+
+// session is the server side record of a login session, a session token is valid only while its record exists
+// sessions are not owned by the customer (no customers field) so they are not counted as customer documents
+type session struct {
+	ID             string    `bson:"_id"`
+	CustomerGUID   string    `bson:"customerGUID"`
+	CreationTime   time.Time `bson:"creationTime"`
+	ExpirationTime time.Time `bson:"expirationTime"`
+}
+
+// cachedSession is a session found in the store, trusted until validUntil
+type cachedSession struct {
+	customerGUID string
+	validUntil   time.Time
+}
+
+var sessionsCache = sync.Map{}
+var lastCacheSweep = time.Now()
+var cacheSweepMutex sync.Mutex
+
+// InitSessionStore creates the index that removes expired sessions from the store
+func InitSessionStore(c context.Context) error {
+	_, err := mongo.GetWriteCollection(consts.SessionsCollection).Indexes().CreateOne(c, mongoDB.IndexModel{
+		Keys:    bson.D{{Key: consts.ExpirationTimeField, Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// CreateSession stores a new session for the claims and sets the claims session id
+func CreateSession(c context.Context, claims *SessionClaims) error {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	claims.SessionID = hex.EncodeToString(id)
+	_, err := mongo.GetWriteCollection(consts.SessionsCollection).InsertOne(c, session{
+		ID:             claims.SessionID,
+		CustomerGUID:   claims.CustomerGUID,
+		CreationTime:   time.Unix(claims.IssuedAt, 0).UTC(),
+		ExpirationTime: time.Unix(claims.ExpiresAt, 0).UTC(),
+	})
+	return err
+}
+
+// ValidateSession returns ErrRevokedSession if the claims session is not in the store
+// found sessions are cached for the configured session cache TTL
+func ValidateSession(c context.Context, claims *SessionClaims) error {
+	if claims.SessionID == "" {
+		return ErrInvalidToken
+	}
+	if i, ok := sessionsCache.Load(claims.SessionID); ok {
+		if cached := i.(cachedSession); time.Now().Before(cached.validUntil) {
+			return nil
+		}
+		sessionsCache.Delete(claims.SessionID)
+	}
+	filter := db.NewFilterBuilder().WithID(claims.SessionID).WithValue(consts.CustomerGUIDField, claims.CustomerGUID)
+	if err := mongo.GetReadCollection(consts.SessionsCollection).FindOne(c, filter.Get()).Err(); err == mongoDB.ErrNoDocuments {
+		return ErrRevokedSession
+	} else if err != nil {
+		return err
+	}
+	cacheTTL := utils.GetConfig().Auth.GetSessionCacheTTL()
+	sessionsCache.Store(claims.SessionID, cachedSession{
+		customerGUID: claims.CustomerGUID,
+		validUntil:   time.Now().Add(cacheTTL),
+	})
+	sweepSessionsCache(cacheTTL)
+	return nil
+}
+
+// sweepSessionsCache removes expired entries of sessions that are no longer used, at most once per cache TTL
+func sweepSessionsCache(cacheTTL time.Duration) {
+	cacheSweepMutex.Lock()
+	defer cacheSweepMutex.Unlock()
+	if time.Since(lastCacheSweep) < cacheTTL {
+		return
+	}
+	lastCacheSweep = time.Now()
+	sessionsCache.Range(func(key, value interface{}) bool {
+		if time.Now().After(value.(cachedSession).validUntil) {
+			sessionsCache.Delete(key)
+		}
+		return true
+	})
+}
+
+// RevokeSession removes the session from the store
+func RevokeSession(c context.Context, sessionID string) error {
+	sessionsCache.Delete(sessionID)
+	_, err := mongo.GetWriteCollection(consts.SessionsCollection).DeleteOne(c, db.NewFilterBuilder().WithID(sessionID).Get())
+	return err
+}
+
+// RevokeCustomersSessions removes all the sessions of the customers from the store
+// other instances may accept the revoked sessions until their cache entries expire
+func RevokeCustomersSessions(c context.Context, customerGUIDs ...string) (revoked int64, err error) {
+	sessionsCache.Range(func(key, value interface{}) bool {
+		for _, customerGUID := range customerGUIDs {
+			if value.(cachedSession).customerGUID == customerGUID {
+				sessionsCache.Delete(key)
+			}
+		}
+		return true
+	})
+	res, err := mongo.GetWriteCollection(consts.SessionsCollection).DeleteMany(c, db.NewFilterBuilder().WithIn(consts.CustomerGUIDField, customerGUIDs).Get())
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSessionToken(t *testing.T) {
+	key := []byte("test-signing-key")
+	validToken, _ := SignSessionToken(NewSessionClaims("customer1", true, time.Hour), key)
+	expiredToken, _ := SignSessionToken(NewSessionClaims("customer1", false, -time.Minute), key)
+	otherKeyToken, _ := SignSessionToken(NewSessionClaims("customer1", false, time.Hour), []byte("other-key"))
+	//replace the payload of a valid token with a forged one
+	forgedPayload := base64.RawURLEncoding.EncodeToString([]byte(`{"customerGUID":"customer2","adminAccess":true,"exp":9999999999}`))
+	forgedToken := forgedPayload + validToken[strings.Index(validToken, "."):]
+	//signed claims of both token types without a token type
+	untypedToken, _ := signClaims(map[string]interface{}{"customerGUID": "customer1", "adminAccess": true, "jti": "token1", "exp": time.Now().Add(time.Hour).Unix()}, key)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "valid", token: validToken, wantErr: nil},
+		{name: "expired", token: expiredToken, wantErr: ErrExpiredToken},
+		{name: "signed with other key", token: otherKeyToken, wantErr: ErrInvalidToken},
+		{name: "forged payload", token: forgedToken, wantErr: ErrInvalidToken},
+		{name: "raw customer guid", token: "customer1;adminAccess", wantErr: ErrInvalidToken},
+		{name: "no token type", token: untypedToken, wantErr: ErrInvalidToken},
+		{name: "empty", token: "", wantErr: ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := ParseSessionToken(tt.token, key)
+			if err != tt.wantErr {
+				t.Fatalf("ParseSessionToken() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (claims.CustomerGUID != "customer1" || !claims.AdminAccess) {
+				t.Errorf("ParseSessionToken() unexpected claims %+v", claims)
+			}
+		})
+	}
+}
+
+func TestParseProvisioningToken(t *testing.T) {
+	key := []byte("test-signing-key")
+	validToken, _ := SignProvisioningToken(ProvisioningClaims{TokenID: "token1", ExpiresAt: time.Now().Add(time.Hour).Unix()}, key)
+	expiredToken, _ := SignProvisioningToken(ProvisioningClaims{TokenID: "token1", ExpiresAt: time.Now().Add(-time.Minute).Unix()}, key)
+	sessionToken, _ := SignSessionToken(NewSessionClaims("customer1", true, time.Hour), key)
+	untypedToken, _ := signClaims(map[string]interface{}{"customerGUID": "customer1", "jti": "token1", "exp": time.Now().Add(time.Hour).Unix()}, key)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "valid", token: validToken, wantErr: nil},
+		{name: "expired", token: expiredToken, wantErr: ErrExpiredToken},
+		{name: "session token", token: sessionToken, wantErr: ErrInvalidToken},
+		{name: "no token type", token: untypedToken, wantErr: ErrInvalidToken},
+		{name: "empty", token: "", wantErr: ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := ParseProvisioningToken(tt.token, key)
+			if err != tt.wantErr {
+				t.Fatalf("ParseProvisioningToken() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && claims.TokenID != "token1" {
+				t.Errorf("ParseProvisioningToken() unexpected claims %+v", claims)
+			}
+		})
+	}
+	//provisioning tokens are not session tokens
+	if _, err := ParseSessionToken(validToken, key); err != ErrInvalidToken {
+		t.Errorf("ParseSessionToken() of provisioning token error = %v, want %v", err, ErrInvalidToken)
+	}
+}
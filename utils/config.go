@@ -4,16 +4,77 @@ import (
 	"fmt"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/tkanos/gonfig"
 )
 
 type Configuration struct {
-	Port         string          `json:"port"`
-	Telemetry    TelemetryConfig `json:"telemetry"`
-	Mongo        MongoConfig     `json:"mongo"`
-	LoggerConfig LoggerConfig    `json:"logger"`
-	AdminUsers   []string        `json:"admins"`
+	Port         string           `json:"port"`
+	Telemetry    TelemetryConfig  `json:"telemetry"`
+	Mongo        MongoConfig      `json:"mongo"`
+	LoggerConfig LoggerConfig     `json:"logger"`
+	AdminUsers   []string         `json:"admins"`
+	Auth         AuthConfig       `json:"auth"`
+	SoftDelete   SoftDeleteConfig `json:"softDelete"`
+	Webhooks     WebhooksConfig   `json:"webhooks"`
+}
+
+type SoftDeleteConfig struct {
+	Retention       string `json:"retention"`       //how long deleted documents are kept before they are purged (e.g. "720h")
+	PurgeInterval   string `json:"purgeInterval"`   //how often the purge job runs (e.g. "1h")
+	DisablePurgeJob bool   `json:"disablePurgeJob"` //when true, deleted documents are purged only by the admin purge API
+}
+
+type WebhooksConfig struct {
+	Disabled             bool   `json:"disabled"`             //when true, documents changes are not posted to webhooks
+	MaxAttempts          int    `json:"maxAttempts"`          //delivery attempts before the delivery is dead lettered, default 5
+	InitialBackoff       string `json:"initialBackoff"`       //wait before the first retry, doubled in every retry (e.g. "1s")
+	MaxBackoff           string `json:"maxBackoff"`           //max wait between retries (e.g. "5m")
+	Timeout              string `json:"timeout"`              //delivery request timeout (e.g. "10s")
+	AllowPrivateNetworks bool   `json:"allowPrivateNetworks"` //when true, webhooks can post to loopback and private addresses, for local development only
+}
+
+type AuthConfig struct {
+	SigningKey         string     `json:"signingKey"`         //HMAC key used to sign session tokens, overridden by the AUTH_SIGNING_KEY environment variable, a random key is used when not set
+	SessionTTL         string     `json:"sessionTTL"`         //session token time to live (e.g. "48h")
+	SessionCacheTTL    string     `json:"sessionCacheTTL"`    //how long a validated session is trusted before it is checked again in the sessions store (e.g. "10s")
+	DisableCookieLogin bool       `json:"disableCookieLogin"` //when true, login route is not served and session cookies are not accepted, login exchanges a verified bearer JWT for a session cookie
+	JWT                JWTConfig  `json:"jwt"`                //bearer JWT authentication, enabled when jwksURL or jwksFile is set
+	RBAC               RBACConfig `json:"rbac"`               //role based access control
+}
+
+type RBACConfig struct {
+	DefaultRoles []string                `json:"defaultRoles"` //roles of sessions without roles, default is full access to the customer's tenant
+	Policy       map[string][]Permission `json:"policy"`       //role to permissions, replaces the default policy when set
+}
+
+// Permission allows HTTP methods on route groups, "*" matches any method or route group
+type Permission struct {
+	Methods     []string `json:"methods"`
+	RouteGroups []string `json:"routeGroups"`
+}
+
+const (
+	DefaultCustomerGUIDClaim = "customerGUID"
+	DefaultAdminClaim        = "adminAccess"
+	DefaultRolesClaim        = "roles"
+)
+
+type JWTConfig struct {
+	JWKSURL           string `json:"jwksURL"`           //url of the JSON Web Key Set used to validate tokens
+	JWKSFile          string `json:"jwksFile"`          //local JSON Web Key Set file, used when jwksURL is not set
+	Issuer            string `json:"issuer"`            //expected iss claim, not validated when empty
+	Audience          string `json:"audience"`          //expected aud claim, not validated when empty
+	CustomerGUIDClaim string `json:"customerGUIDClaim"` //claim holding the customer GUID, default "customerGUID"
+	AdminClaim        string `json:"adminClaim"`        //claim granting admin access, default "adminAccess"
+	AdminClaimValue   string `json:"adminClaimValue"`   //when set, admin access is granted if the admin claim equals or contains this value, otherwise the claim must be true
+	RolesClaim        string `json:"rolesClaim"`        //claim holding the session roles, default "roles"
+}
+
+// Enabled returns true if a key set source is configured
+func (j JWTConfig) Enabled() bool {
+	return j.JWKSURL != "" || j.JWKSFile != ""
 }
 
 type TelemetryConfig struct {
@@ -42,6 +103,10 @@ var globalConfig = Configuration{
 		Port: "27017",
 		DB:   "caportalbe_db",
 	},
+	Auth: AuthConfig{
+		SessionTTL:      "48h",
+		SessionCacheTTL: "10s",
+	},
 }
 var initOnce sync.Once
 
@@ -59,3 +124,67 @@ func GetConfig() Configuration {
 	})
 	return globalConfig
 }
+
+// GetSessionTTL returns the configured session time to live, defaults to 48 hours
+func (a AuthConfig) GetSessionTTL() time.Duration {
+	if ttl, err := time.ParseDuration(a.SessionTTL); err == nil && ttl > 0 {
+		return ttl
+	}
+	return 48 * time.Hour
+}
+
+// GetSessionCacheTTL returns the configured session cache time to live, defaults to 10 seconds
+func (a AuthConfig) GetSessionCacheTTL() time.Duration {
+	if ttl, err := time.ParseDuration(a.SessionCacheTTL); err == nil && ttl > 0 {
+		return ttl
+	}
+	return 10 * time.Second
+}
+
+// GetRetention returns the configured deleted documents retention, defaults to 30 days
+func (s SoftDeleteConfig) GetRetention() time.Duration {
+	if retention, err := time.ParseDuration(s.Retention); err == nil && retention > 0 {
+		return retention
+	}
+	return 30 * 24 * time.Hour
+}
+
+// GetPurgeInterval returns the configured purge job interval, defaults to 1 hour
+func (s SoftDeleteConfig) GetPurgeInterval() time.Duration {
+	if interval, err := time.ParseDuration(s.PurgeInterval); err == nil && interval > 0 {
+		return interval
+	}
+	return time.Hour
+}
+
+// GetMaxAttempts returns the configured webhook delivery attempts, defaults to 5
+func (w WebhooksConfig) GetMaxAttempts() int {
+	if w.MaxAttempts > 0 {
+		return w.MaxAttempts
+	}
+	return 5
+}
+
+// GetInitialBackoff returns the configured wait before the first webhook delivery retry, defaults to 1 second
+func (w WebhooksConfig) GetInitialBackoff() time.Duration {
+	if backoff, err := time.ParseDuration(w.InitialBackoff); err == nil && backoff > 0 {
+		return backoff
+	}
+	return time.Second
+}
+
+// GetMaxBackoff returns the configured max wait between webhook delivery retries, defaults to 5 minutes
+func (w WebhooksConfig) GetMaxBackoff() time.Duration {
+	if backoff, err := time.ParseDuration(w.MaxBackoff); err == nil && backoff > 0 {
+		return backoff
+	}
+	return 5 * time.Minute
+}
+
+// GetTimeout returns the configured webhook delivery request timeout, defaults to 10 seconds
+func (w WebhooksConfig) GetTimeout() time.Duration {
+	if timeout, err := time.ParseDuration(w.Timeout); err == nil && timeout > 0 {
+		return timeout
+	}
+	return 10 * time.Second
+}
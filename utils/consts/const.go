@@ -3,14 +3,28 @@ package consts
 const (
 
 	//Context keys for stored values
-	DocContentKey  = "docContent"           //key for doc content from request body
-	CustomerGUID   = "customerGUID"         //key for customer GUID from request login details
-	Collection     = "collection"           //key for db collection name of the request
-	ReqLogger      = "reqLogger"            //key for request logger
-	AdminAccess    = "adminAccess"          //key for admin access flag
-	BodyDecoder    = "customBodyDecoder"    //key for custom body decoder
-	ResponseSender = "customResponseSender" //key for custom response sender
-	PutDocFields   = "customPutDocFields"   //key for string list of fields name to update in PUT requests, only these fields will be updated
+	DocContentKey       = "docContent"           //key for doc content from request body
+	CustomerGUID        = "customerGUID"         //key for customer GUID from request login details
+	Collection          = "collection"           //key for db collection name of the request
+	ReqLogger           = "reqLogger"            //key for request logger
+	AdminAccess         = "adminAccess"          //key for admin access flag
+	BodyDecoder         = "customBodyDecoder"    //key for custom body decoder
+	ResponseSender      = "customResponseSender" //key for custom response sender
+	PutDocFields        = "customPutDocFields"   //key for string list of fields name to update in PUT requests, only these fields will be updated
+	APIKeyScopes        = "apiKeyScopes"         //key for the scopes of the API key used to authenticate the request
+	Roles               = "roles"                //key for the roles of the authenticated session
+	SessionID           = "sessionID"            //key for the id of the login session used to authenticate the request
+	ProvisioningTokenID = "provisioningTokenID"  //key for the id of the provisioning token used to create a tenant
+	LoginClaims         = "loginClaims"          //key for the verified claims a login session is created for
+	ImpersonatedBy      = "impersonatedBy"       //key for the GUID of the admin acting as the customer of the request
+	SortFields          = "sortFields"           //key for string list of fields that list GET requests can sort by
+	TextSearch          = "textSearch"           //key for text search flag, true when list GET requests can search with the q query param
+	JSONPatchOperations = "jsonPatchOperations"  //key for the json patch operations of PATCH requests that can be translated to an update command
+	ListDeleted         = "listDeleted"          //key for the deleted flag of list GET requests, when true the customer's deleted documents are listed instead of the existing ones
+	ReadRevision        = "readRevision"         //key for the revision of the document patched by PATCH requests, the document is updated only if it is still in this revision
+	BulkItemsResults    = "bulkItemsResults"     //key for the results of the items of bulk requests, the valid items have no result status yet
+	BulkValidDocs       = "bulkValidDocs"        //key for the documents of a bulk request that were validated before the document in validation
+	AuditChanges        = "auditChanges"         //key for the changes of the documents modified by the request, recorded in the audit log
 
 	//PATHS
 	ClusterPath                      = "/cluster"
@@ -26,6 +40,15 @@ const (
 	NotificationConfigPath           = "/v1_notification_config"
 	CustomerStatePath                = "/v1_customer_state"
 	ActiveSubscriptionPath           = "/v1_active_subscription"
+	APIKeyPath                       = "/v1_api_key"
+	AuditPath                        = "/v1_audit"
+	WebhookPath                      = "/v1_webhook"
+
+	//Route groups for role based access control
+	RouteGroupConfig   = "config"   //clusters, customer configurations, repositories and other configuration documents
+	RouteGroupSecurity = "security" //exception policies and frameworks
+	RouteGroupAccess   = "access"   //api keys, webhooks and audit log
+	RouteGroupAdmin    = "admin"    //admin APIs
 
 	//DB collections
 	ClustersCollection                     = "clusters"
@@ -36,19 +59,52 @@ const (
 	FrameworkCollection                    = "v1_opa_frameworks"
 	RepositoryCollection                   = "v1_repositories"
 	RegistryCronJobCollection              = "v1_registry_cron_jobs"
+	APIKeyCollection                       = "v1_api_keys"
+	SessionsCollection                     = "v1_sessions"
+	ProvisioningTokensCollection           = "v1_provisioning_tokens"
+	TenantsAuditCollection                 = "v1_tenants_audit"
+	AuditCollection                        = "v1_audit"
+	WebhookCollection                      = "v1_webhooks"
+	WebhookDeliveriesCollection            = "v1_webhook_deliveries"
 
 	//Common document fields
 	IdField          = "_id"
 	GUIDField        = "guid"
 	NameField        = "name"
 	DeletedField     = "is_deleted"
+	DeletedTimeField = "deletedTime"
 	AttributesField  = "attributes"
 	CustomersField   = "customers"
 	UpdatedTimeField = "updatedTime"
+	TextScoreField   = "textScore"
+	RevisionField    = "revision"
 	//cluster fields
 	ShortNameAttribute = "alias"
 	ShortNameField     = AttributesField + "." + ShortNameAttribute
 
+	//api key fields
+	KeyHashField = "keyHash"
+	//webhook fields
+	SigningKeyField  = "signingKey"
+	EventTypesField  = "eventTypes"
+	CollectionsField = "collections"
+	//session and provisioning token fields
+	ExpirationTimeField = "expirationTime"
+	CustomerGUIDField   = "customerGUID"
+	UsedByField         = "usedBy"
+
+	//Headers
+	APIKeyHeader            = "X-API-Key"
+	ProvisioningTokenHeader = "X-Provisioning-Token"
+	ActAsCustomerHeader     = "X-Act-As-Customer"
+	ETagHeader              = "ETag"
+	IfMatchHeader           = "If-Match"
+	IfNoneMatchHeader       = "If-None-Match"
+	LastEventIDHeader       = "Last-Event-ID"
+	WebhookSignatureHeader  = "X-Webhook-Signature"
+	WebhookEventHeader      = "X-Webhook-Event"
+	WebhookDeliveryHeader   = "X-Webhook-Delivery"
+
 	//Query params
 	ListParam          = "list"
 	PolicyNameParam    = "policyName"
@@ -56,8 +112,22 @@ const (
 	CustomersParam     = "customers"
 	LimitParam         = "limit"
 	SkipParam          = "skip"
+	CursorParam        = "cursor"
+	SortParam          = "sort"
+	FieldsParam        = "fields"
+	FilterParam        = "filter"
+	SearchParam        = "q"
+	ModeParam          = "mode"
+	UpsertParam        = "upsert"
+	NameParam          = "name"
+	DeletedParam       = "deleted"
+	RetentionParam     = "retention"
 	FromDateParam      = "fromDate"
 	ToDateParam        = "toDate"
+	ActorParam         = "actor"
+	CollectionParam    = "collection"
+	TargetGUIDParam    = "targetGUID"
+	StatusParam        = "status"
 
 	//Cached documents keys
 	DefaultCustomerConfigKey = "defaultCustomerConfig"
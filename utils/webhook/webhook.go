@@ -0,0 +1,322 @@
+package webhook
+
+import (
+	"bytes"
+	"config-service/db"
+	"config-service/utils"
+	"config-service/utils/consts"
+	"config-service/utils/log"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"go.uber.org/zap"
+)
+
+// documents changes are queued by the handlers and the dispatcher workers queue their deliveries to the customer webhooks subscribed to them,
+// deliveries are posted by the delivery workers, failed deliveries are retried with exponential backoff and dead lettered when the attempts are exhausted
+
+const (
+	eventsQueueSize     = 1000 //queued events, events are dropped when the queue is full
+	dispatchWorkers     = 4
+	deliveriesQueueSize = 1000 //queued deliveries, dispatch workers wait when the queue is full
+	deliveryWorkers     = 16   //max concurrent deliveries
+	signaturePrefix     = "sha256="
+	maxResponseBodySize = 64 * 1024
+)
+
+// Event is a document change posted to the webhooks subscribed to it
+type Event struct {
+	ID           string      `json:"id"`
+	Type         string      `json:"type"` //create, update or delete
+	Collection   string      `json:"collection"`
+	CustomerGUID string      `json:"customerGUID"`
+	GUID         string      `json:"guid"` //the changed document GUID
+	Time         time.Time   `json:"time"`
+	Document     interface{} `json:"document"` //the document after the change, or before a delete
+}
+
+// NewEvent returns a new event of the change of the customer document
+func NewEvent(eventType, collection, customerGUID, guid string, doc interface{}) Event {
+	return Event{
+		ID:           uuid.NewV4().String(),
+		Type:         eventType,
+		Collection:   collection,
+		CustomerGUID: customerGUID,
+		GUID:         guid,
+		Time:         time.Now().UTC(),
+		Document:     doc,
+	}
+}
+
+// the running dispatcher, nil when webhooks are disabled
+var dispatcher *Dispatcher
+
+type Dispatcher struct {
+	config     utils.WebhooksConfig
+	client     *http.Client
+	events     chan Event
+	deliveries chan delivery
+	ctx        context.Context
+	workers    sync.WaitGroup
+}
+
+// delivery is a queued post of an event payload to a webhook
+type delivery struct {
+	id        string
+	url       string
+	secret    string
+	eventType string
+	payload   []byte
+}
+
+func newDispatcher(ctx context.Context, config utils.WebhooksConfig) *Dispatcher {
+	return &Dispatcher{
+		config:     config,
+		client:     newClient(config.AllowPrivateNetworks),
+		events:     make(chan Event, eventsQueueSize),
+		deliveries: make(chan delivery, deliveriesQueueSize),
+		ctx:        ctx,
+	}
+}
+
+// newClient returns the deliveries http client, redirects are not followed and unless allowPrivateNetworks is set
+// connections are allowed only to public addresses, the address is checked when dialing so it cannot be bypassed by DNS rebinding
+func newClient(allowPrivateNetworks bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !allowPrivateNetworks {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("webhook address %s is not a public address", host)
+			}
+			return nil
+		}
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 nil, //the proxy address would be checked instead of the webhook address
+			DialContext:           dialer.DialContext,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// non public networks that are not covered by the net.IP methods
+var reservedNetworks = []*net.IPNet{
+	{IP: net.IPv4(0, 0, 0, 0), Mask: net.CIDRMask(8, 32)},     //"this" network
+	{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}, //carrier grade NAT
+}
+
+// isPublicIP returns false for loopback, private, link local, multicast, unspecified and reserved addresses
+func isPublicIP(ip net.IP) bool {
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// Start starts posting the dispatched events to the webhooks until the returned stop function is called
+// pending retries are abandoned when the dispatcher is stopped
+func Start(config utils.WebhooksConfig) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := newDispatcher(ctx, config)
+	for i := 0; i < dispatchWorkers; i++ {
+		d.workers.Add(1)
+		go func() {
+			defer d.workers.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case event := <-d.events:
+					d.dispatch(event)
+				}
+			}
+		}()
+	}
+	for i := 0; i < deliveryWorkers; i++ {
+		d.workers.Add(1)
+		go func() {
+			defer d.workers.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case delivery := <-d.deliveries:
+					d.run(delivery)
+				}
+			}
+		}()
+	}
+	dispatcher = d
+	return func() {
+		cancel()
+		d.workers.Wait()
+	}
+}
+
+// Dispatch queues the event to be posted to the customer webhooks subscribed to it
+func Dispatch(c context.Context, event Event) {
+	if dispatcher == nil {
+		return
+	}
+	select {
+	case dispatcher.events <- event:
+	default:
+		log.LogNTraceError(fmt.Sprintf("webhooks events queue is full, event %s of document %s dropped", event.Type, event.GUID), fmt.Errorf("queue is full"), c)
+	}
+}
+
+// Sign returns the signature header value of the payload signed with the secret
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// GenerateSecret returns a new random webhook secret
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// dispatch adds the deliveries of the event to the delivery log and posts them
+func (d *Dispatcher) dispatch(event Event) {
+	webhooks, err := db.FindWebhooksForEvent(d.ctx, event.CustomerGUID, event.Collection, event.Type)
+	if err != nil {
+		zap.L().Error("failed to read webhooks", zap.String("customerGUID", event.CustomerGUID), zap.Error(err))
+		return
+	} else if len(webhooks) == 0 {
+		return
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		zap.L().Error("failed to encode webhook event", zap.String("eventID", event.ID), zap.Error(err))
+		return
+	}
+	for _, webhook := range webhooks {
+		record := db.WebhookDelivery{
+			ID:           uuid.NewV4().String(),
+			Customers:    []string{event.CustomerGUID},
+			WebhookGUID:  webhook.GUID,
+			EventID:      event.ID,
+			EventType:    event.Type,
+			Collection:   event.Collection,
+			DocumentGUID: event.GUID,
+			Status:       db.DeliveryStatusPending,
+			Attempts:     []db.DeliveryAttempt{},
+			CreationTime: time.Now().UTC(),
+		}
+		if err := db.InsertWebhookDelivery(d.ctx, record); err != nil {
+			zap.L().Error("failed to add webhook delivery", zap.String("webhookGUID", webhook.GUID), zap.Error(err))
+			continue
+		}
+		select {
+		case d.deliveries <- delivery{id: record.ID, url: webhook.URL, secret: webhook.SigningKey, eventType: event.Type, payload: payload}:
+		case <-d.ctx.Done():
+			return
+		}
+	}
+}
+
+// run posts the delivery and records its attempts in the delivery log
+func (d *Dispatcher) run(delivery delivery) {
+	d.deliver(delivery.url, delivery.secret, delivery.id, delivery.eventType, delivery.payload, func(attempt db.DeliveryAttempt, status string) {
+		if err := db.AddWebhookDeliveryAttempt(d.ctx, delivery.id, attempt, status, delivery.payload); err != nil {
+			zap.L().Error("failed to log webhook delivery attempt", zap.String("deliveryID", delivery.id), zap.Error(err))
+		}
+	})
+}
+
+// deliver posts the payload until it is accepted or the attempts are exhausted, each attempt is recorded with the delivery status after it
+func (d *Dispatcher) deliver(url, secret, deliveryID, eventType string, payload []byte, record func(attempt db.DeliveryAttempt, status string)) {
+	maxAttempts := d.config.GetMaxAttempts()
+	for i := 1; ; i++ {
+		attempt, delivered := d.post(url, secret, deliveryID, eventType, payload)
+		switch {
+		case delivered:
+			record(attempt, db.DeliveryStatusDelivered)
+			return
+		case i >= maxAttempts:
+			record(attempt, db.DeliveryStatusDeadLetter)
+			return
+		default:
+			record(attempt, db.DeliveryStatusPending)
+		}
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-time.After(d.backoff(i)):
+		}
+	}
+}
+
+// backoff returns the wait after the failed attempt, the initial backoff doubled in every retry up to the max backoff
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	backoff, maxBackoff := d.config.GetInitialBackoff(), d.config.GetMaxBackoff()
+	for i := 1; i < attempt && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+	return backoff
+}
+
+// post sends a delivery request, returns true if the webhook accepted the payload with a 2xx response
+func (d *Dispatcher) post(url, secret, deliveryID, eventType string, payload []byte) (db.DeliveryAttempt, bool) {
+	attempt := db.DeliveryAttempt{Time: time.Now().UTC()}
+	ctx, cancel := context.WithTimeout(d.ctx, d.config.GetTimeout())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt, false
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(consts.WebhookSignatureHeader, Sign(secret, payload))
+	req.Header.Set(consts.WebhookEventHeader, eventType)
+	req.Header.Set(consts.WebhookDeliveryHeader, deliveryID)
+	res, err := d.client.Do(req)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt, false
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, maxResponseBodySize))
+	attempt.StatusCode = res.StatusCode
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		attempt.Error = fmt.Sprintf("unexpected response status %s", res.Status)
+		return attempt, false
+	}
+	return attempt, true
+}
@@ -0,0 +1,143 @@
+package webhook
+
+import (
+	"config-service/db"
+	"config-service/utils"
+	"config-service/utils/consts"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	//echo -n '{"id":"1"}' | openssl dgst -sha256 -hmac secret
+	want := "sha256=6146142a2ce0159e84c0767881e4ec80bc397da62526e7d19f70795eb79460c0"
+	if got := Sign("secret", []byte(`{"id":"1"}`)); got != want {
+		t.Errorf("Sign() = %s, want %s", got, want)
+	}
+	if Sign("secret", []byte("a")) == Sign("other-secret", []byte("a")) {
+		t.Errorf("Sign() is the same for different secrets")
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	payload := []byte(`{"id":"event-id"}`)
+	tests := []struct {
+		name         string
+		failures     int //number of requests the receiver fails before accepting
+		wantStatuses []string
+	}{
+		{name: "delivered", failures: 0, wantStatuses: []string{db.DeliveryStatusDelivered}},
+		{name: "delivered after retries", failures: 2, wantStatuses: []string{db.DeliveryStatusPending, db.DeliveryStatusPending, db.DeliveryStatusDelivered}},
+		{name: "dead letter", failures: 3, wantStatuses: []string{db.DeliveryStatusPending, db.DeliveryStatusPending, db.DeliveryStatusDeadLetter}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				body, _ := io.ReadAll(r.Body)
+				if r.Header.Get(consts.WebhookSignatureHeader) != Sign("secret", body) {
+					t.Errorf("invalid signature %s", r.Header.Get(consts.WebhookSignatureHeader))
+				}
+				if r.Header.Get(consts.WebhookEventHeader) != "update" || r.Header.Get(consts.WebhookDeliveryHeader) != "delivery-id" {
+					t.Errorf("invalid headers %v", r.Header)
+				}
+				if requests <= tt.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			defer receiver.Close()
+			d := newDispatcher(context.Background(), utils.WebhooksConfig{MaxAttempts: 3, InitialBackoff: "1ms", AllowPrivateNetworks: true})
+			statuses := []string{}
+			d.deliver(receiver.URL, "secret", "delivery-id", "update", payload, func(attempt db.DeliveryAttempt, status string) {
+				statuses = append(statuses, status)
+				if status == db.DeliveryStatusDelivered && (attempt.StatusCode != http.StatusOK || attempt.Error != "") {
+					t.Errorf("delivered attempt = %+v", attempt)
+				} else if status != db.DeliveryStatusDelivered && (attempt.StatusCode != http.StatusServiceUnavailable || attempt.Error == "") {
+					t.Errorf("failed attempt = %+v", attempt)
+				}
+			})
+			if len(statuses) != len(tt.wantStatuses) {
+				t.Fatalf("deliver() statuses = %v, want %v", statuses, tt.wantStatuses)
+			}
+			for i := range statuses {
+				if statuses[i] != tt.wantStatuses[i] {
+					t.Errorf("deliver() statuses = %v, want %v", statuses, tt.wantStatuses)
+				}
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := newDispatcher(context.Background(), utils.WebhooksConfig{InitialBackoff: "1s", MaxBackoff: "5s"})
+	for attempt, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if got := d.backoff(attempt + 1); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempt+1, got, want)
+		}
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "8.8.8.8", want: true},
+		{ip: "2001:4860:4860::8888", want: true},
+		{ip: "127.0.0.1", want: false},
+		{ip: "::1", want: false},
+		{ip: "10.1.2.3", want: false},
+		{ip: "172.16.0.1", want: false},
+		{ip: "192.168.1.1", want: false},
+		{ip: "169.254.169.254", want: false},
+		{ip: "100.64.0.1", want: false},
+		{ip: "0.0.0.0", want: false},
+		{ip: "0.1.2.3", want: false},
+		{ip: "fd00::1", want: false},
+		{ip: "fe80::1", want: false},
+		{ip: "::ffff:127.0.0.1", want: false},
+		{ip: "224.0.0.1", want: false},
+	}
+	for _, tt := range tests {
+		if got := isPublicIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("isPublicIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestPostPrivateAddress(t *testing.T) {
+	requests := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/target", http.StatusFound)
+		}
+	}))
+	defer receiver.Close()
+	payload := []byte(`{"id":"event-id"}`)
+
+	//private addresses are rejected when dialing
+	d := newDispatcher(context.Background(), utils.WebhooksConfig{})
+	if attempt, delivered := d.post(receiver.URL, "secret", "delivery-id", "update", payload); delivered || !strings.Contains(attempt.Error, "is not a public address") {
+		t.Errorf("post() to private address = %+v, %v", attempt, delivered)
+	}
+	if requests != 0 {
+		t.Errorf("post() to private address sent %d requests", requests)
+	}
+
+	//redirects are not followed
+	d = newDispatcher(context.Background(), utils.WebhooksConfig{AllowPrivateNetworks: true})
+	if attempt, delivered := d.post(receiver.URL+"/redirect", "secret", "delivery-id", "update", payload); delivered || attempt.StatusCode != http.StatusFound {
+		t.Errorf("post() with redirect = %+v, %v", attempt, delivered)
+	}
+	if requests != 1 {
+		t.Errorf("post() with redirect sent %d requests, want 1", requests)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"config-service/types"
+	"config-service/utils/consts"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/armosec/armoapi-go/armotypes"
+)
+
+func (suite *MainTestSuite) TestWatch() {
+	suite.login("watch-customer")
+	server := httptest.NewServer(suite.router)
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watch := func(lastEventID string) *http.Response {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+consts.ClusterPath+"/watch", nil)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+		req.Header.Set("Cookie", suite.authCookie)
+		if lastEventID != "" {
+			req.Header.Set(consts.LastEventIDHeader, lastEventID)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			suite.FailNow(err.Error())
+		}
+		return res
+	}
+	readEvent := func(reader *bufio.Reader) map[string]string {
+		event := map[string]string{}
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				suite.FailNow(err.Error())
+			}
+			if line = strings.TrimSuffix(line, "\n"); line == "" {
+				return event
+			}
+			if field := strings.SplitN(line, ":", 2); len(field) == 2 && field[0] != "" {
+				event[field[0]] = field[1]
+			}
+		}
+	}
+
+	res := watch("")
+	defer res.Body.Close()
+	suite.Equal(http.StatusOK, res.StatusCode)
+	reader := bufio.NewReader(res.Body)
+
+	//other customers changes are not streamed
+	suite.login("watch-other-customer")
+	testPostDoc(suite, consts.ClusterPath, &types.Cluster{PortalBase: armotypes.PortalBase{Name: "watch-other-cluster"}}, newClusterCompareFilter)
+	suite.login("watch-customer")
+	cluster := testPostDoc(suite, consts.ClusterPath, &types.Cluster{PortalBase: armotypes.PortalBase{Name: "watch-cluster", Attributes: map[string]interface{}{"env": "dev"}}}, newClusterCompareFilter)
+	updated := testGetDoc(suite, consts.ClusterPath+"/"+cluster.GUID, cluster, newClusterCompareFilter)
+	updated.Attributes["env"] = "prod"
+	w := suite.doRequest(http.MethodPut, consts.ClusterPath, updated)
+	suite.Equal(http.StatusOK, w.Code, w.Body.String())
+	testDeleteDocByGUID(suite, consts.ClusterPath, updated, newClusterCompareFilter)
+
+	events := []map[string]string{}
+	for _, eventType := range []string{"create", "update", "delete"} {
+		event := readEvent(reader)
+		suite.Equal(eventType, event["event"])
+		suite.Contains(event["data"], `"guid":"`+cluster.GUID+`"`)
+		events = append(events, event)
+	}
+	suite.Contains(events[1]["data"], `"env":"prod"`)
+
+	//resume after the create event
+	resumed := watch(events[0]["id"])
+	defer resumed.Body.Close()
+	resumedReader := bufio.NewReader(resumed.Body)
+	suite.Equal(events[1], readEvent(resumedReader))
+	suite.Equal(events[2], readEvent(resumedReader))
+
+	suite.requestHeaders = map[string]string{consts.LastEventIDHeader: "not-an-id"}
+	testBadRequest(suite, http.MethodGet, consts.ClusterPath+"/watch", `{"error":"Last-Event-ID is invalid or expired"}`, nil, http.StatusBadRequest)
+	suite.requestHeaders = nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"config-service/db"
+	"config-service/types"
+	"config-service/utils/auth"
+	"config-service/utils/consts"
+	"config-service/utils/webhook"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	"github.com/armosec/armoapi-go/armotypes"
+)
+
+func (suite *MainTestSuite) TestWebhooks() {
+	type received struct {
+		signature string
+		body      []byte
+	}
+	requests := make(chan received, 10)
+	var failing int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{signature: r.Header.Get(consts.WebhookSignatureHeader), body: body}
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer receiver.Close()
+
+	suite.login("webhook-customer")
+	//invalid subscriptions
+	testBadRequest(suite, http.MethodPost, consts.WebhookPath, `{"error":"url must be an absolute http or https url"}`,
+		&types.Webhook{PortalBase: armotypes.PortalBase{Name: "invalid-url"}, URL: "ftp://localhost/hook"}, http.StatusBadRequest)
+	testBadRequest(suite, http.MethodPost, consts.WebhookPath, `{"error":"invalid event type modify, event types must be [create update delete]"}`,
+		&types.Webhook{PortalBase: armotypes.PortalBase{Name: "invalid-event"}, URL: receiver.URL, EventTypes: []string{"modify"}}, http.StatusBadRequest)
+
+	//the secret is returned only on creation
+	w := suite.doRequest(http.MethodPost, consts.WebhookPath, &types.Webhook{
+		PortalBase:  armotypes.PortalBase{Name: "clusters-hook"},
+		URL:         receiver.URL,
+		EventTypes:  []string{db.ChangeEventCreate, db.ChangeEventDelete},
+		Collections: []string{consts.ClustersCollection},
+	})
+	suite.Equal(http.StatusCreated, w.Code, w.Body.String())
+	hook, err := decodeResponse[*types.Webhook](w)
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	suite.NotEmpty(hook.Secret)
+	w = suite.doRequest(http.MethodGet, consts.WebhookPath+"/"+hook.GUID, nil)
+	suite.Equal(http.StatusOK, w.Code, w.Body.String())
+	suite.NotContains(w.Body.String(), hook.Secret)
+
+	receive := func() webhook.Event {
+		select {
+		case request := <-requests:
+			suite.Equal(webhook.Sign(hook.Secret, request.body), request.signature)
+			var event webhook.Event
+			if err := json.Unmarshal(request.body, &event); err != nil {
+				suite.FailNow(err.Error())
+			}
+			return event
+		case <-time.After(5 * time.Second):
+			suite.FailNow("webhook request not received")
+		}
+		return webhook.Event{}
+	}
+
+	//subscribed changes are posted, updates are not subscribed
+	cluster := testPostDoc(suite, consts.ClusterPath, &types.Cluster{PortalBase: armotypes.PortalBase{Name: "webhook-cluster", Attributes: map[string]interface{}{"env": "dev"}}}, newClusterCompareFilter)
+	event := receive()
+	suite.Equal(db.ChangeEventCreate, event.Type)
+	suite.Equal(consts.ClustersCollection, event.Collection)
+	suite.Equal("webhook-customer", event.CustomerGUID)
+	suite.Equal(cluster.GUID, event.GUID)
+	updated := testGetDoc(suite, consts.ClusterPath+"/"+cluster.GUID, cluster, newClusterCompareFilter)
+	updated.Attributes["env"] = "prod"
+	w = suite.doRequest(http.MethodPut, consts.ClusterPath, updated)
+	suite.Equal(http.StatusOK, w.Code, w.Body.String())
+	testDeleteDocByGUID(suite, consts.ClusterPath, updated, newClusterCompareFilter)
+	event = receive()
+	suite.Equal(db.ChangeEventDelete, event.Type)
+	suite.Equal(cluster.GUID, event.GUID)
+
+	getDeliveries := func(status string, count int) *db.AggResult[db.WebhookDelivery] {
+		var page *db.AggResult[db.WebhookDelivery]
+		for i := 0; i < 50; i++ {
+			w := suite.doRequest(http.MethodGet, consts.WebhookPath+"/"+hook.GUID+"/deliveries?"+consts.StatusParam+"="+status, nil)
+			suite.Equal(http.StatusOK, w.Code, w.Body.String())
+			if page, err = decodeResponse[*db.AggResult[db.WebhookDelivery]](w); err != nil {
+				suite.FailNow(err.Error())
+			}
+			if page.Metadata.Total == count {
+				break
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		suite.Equal(count, page.Metadata.Total)
+		return page
+	}
+	delivered := getDeliveries(db.DeliveryStatusDelivered, 2)
+	suite.Equal(db.ChangeEventDelete, delivered.Results[0].EventType)
+	suite.Len(delivered.Results[0].Attempts, 1)
+	suite.Equal(http.StatusOK, delivered.Results[0].Attempts[0].StatusCode)
+
+	//failed deliveries are retried and dead lettered
+	atomic.StoreInt32(&failing, 1)
+	failed := testPostDoc(suite, consts.ClusterPath, &types.Cluster{PortalBase: armotypes.PortalBase{Name: "webhook-failed-cluster"}}, newClusterCompareFilter)
+	for i := 0; i < 3; i++ {
+		suite.Equal(failed.GUID, receive().GUID)
+	}
+	deadLetters := getDeliveries(db.DeliveryStatusDeadLetter, 1)
+	suite.Len(deadLetters.Results[0].Attempts, 3)
+	suite.Equal(http.StatusInternalServerError, deadLetters.Results[0].Attempts[2].StatusCode)
+	suite.Contains(string(deadLetters.Results[0].Payload), failed.GUID)
+	atomic.StoreInt32(&failing, 0)
+
+	//secrets of created api keys and webhooks are not posted
+	w = suite.doRequest(http.MethodPut, consts.WebhookPath, &types.Webhook{
+		PortalBase:  hook.PortalBase,
+		URL:         receiver.URL,
+		EventTypes:  []string{db.ChangeEventCreate},
+		Collections: []string{consts.APIKeyCollection, consts.WebhookCollection},
+	})
+	suite.Equal(http.StatusOK, w.Code, w.Body.String())
+	w = suite.doRequest(http.MethodPost, consts.APIKeyPath, &types.APIKey{PortalBase: armotypes.PortalBase{Name: "webhook-key"}, Scopes: []types.APIKeyScope{{Path: consts.ClusterPath, Access: auth.ScopeAccessRead}}})
+	suite.Equal(http.StatusCreated, w.Code, w.Body.String())
+	key, err := decodeResponse[*types.APIKey](w)
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	event = receive()
+	suite.Equal(key.GUID, event.GUID)
+	suite.Empty(event.Document.(map[string]interface{})["key"])
+	w = suite.doRequest(http.MethodPost, consts.WebhookPath, &types.Webhook{PortalBase: armotypes.PortalBase{Name: "other-hook"}, URL: receiver.URL, Collections: []string{consts.ClustersCollection}})
+	suite.Equal(http.StatusCreated, w.Code, w.Body.String())
+	otherHook, err := decodeResponse[*types.Webhook](w)
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	event = receive()
+	suite.Equal(otherHook.GUID, event.GUID)
+	suite.Empty(event.Document.(map[string]interface{})["secret"])
+
+	//other customers cannot read the delivery log
+	suite.login("webhook-other-customer")
+	testBadRequest(suite, http.MethodGet, consts.WebhookPath+"/"+hook.GUID+"/deliveries", errorDocumentNotFound, nil, http.StatusNotFound)
+}